@@ -2,8 +2,11 @@ package main
 
 import (
 	"drawer-service-backend/internal/config"
+	"drawer-service-backend/internal/contest"
 	"drawer-service-backend/internal/db"
+	"drawer-service-backend/internal/phash"
 	"drawer-service-backend/internal/routes"
+	"drawer-service-backend/internal/textfilter"
 	"errors"
 	"log"
 	"net/http"
@@ -11,6 +14,15 @@ import (
 
 func main() {
 	cfg := config.LoadConfig()
+	if !phash.IsValidMode(cfg.DuplicateMode) {
+		log.Printf("Unknown DUPLICATE_MODE %q, falling back to %s", cfg.DuplicateMode, phash.MODE_FLAG)
+		cfg.DuplicateMode = string(phash.MODE_FLAG)
+	}
+	if !textfilter.IsValidMode(cfg.TextFilterMode) {
+		log.Printf("Unknown TEXT_FILTER_MODE %q, falling back to %s", cfg.TextFilterMode, textfilter.MODE_FLAG)
+		cfg.TextFilterMode = string(textfilter.MODE_FLAG)
+	}
+
 	repo, err := db.InitDB(cfg)
 
 	if err != nil {
@@ -32,6 +44,8 @@ func main() {
 		log.Println("Running in production mode")
 	}
 
+	contest.StartWeeklyTally(repo, cfg)
+
 	// --- Gin Router Setup ---
 	router := routes.InitRouter(cfg, repo)
 
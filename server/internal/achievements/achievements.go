@@ -162,6 +162,7 @@ func (as *AchievementService) UpdateSubmissionAchievements(userId string) error
 	return as.updateUserAchievementsByAchievementField(as.DB, as.Ctx, userId, []string{
 		string(stats.SUBMISSION_STREAK),
 		string(stats.SUBMISSION_TOTAL),
+		string(stats.PALETTE_PURIST_TOTAL),
 	})
 }
 
@@ -186,6 +187,20 @@ func (as *AchievementService) UpdateFriendAchievements(userId string) error {
 	})
 }
 
+/* Check all referral achievements to see if a user has earned any */
+func (as *AchievementService) UpdateReferralAchievements(userId string) error {
+	return as.updateUserAchievementsByAchievementField(as.DB, as.Ctx, userId, []string{
+		string(stats.REFERRAL_TOTAL),
+	})
+}
+
+/* Check all contest achievements to see if a user has earned any */
+func (as *AchievementService) UpdateContestAchievements(userId string) error {
+	return as.updateUserAchievementsByAchievementField(as.DB, as.Ctx, userId, []string{
+		string(stats.CONTEST_WIN_TOTAL),
+	})
+}
+
 func (as *AchievementService) updateUserAchievementsByAchievementField(repo *sql.DB, ctx context.Context, userId string, achievementField []string) error {
 	log.Print("Getting all achievements")
 	achievements, err := queries.GetIncompleteAchievementsByAchievementField(repo, ctx, userId, achievementField)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -22,6 +23,16 @@ type Config struct {
 	AwsSecretKey    string
 	VAPIDPrivateKey string
 	VAPIDPublicKey  string
+	// What to do with near-duplicate submissions, "flag" or "reject"
+	DuplicateMode string
+	// Distinct reports after which content is hidden until a moderator looks
+	// at it, 0 to never hide automatically
+	ReportHideThreshold int
+	// What to do with user text that matches the word list, "off", "flag",
+	// "mask" or "reject"
+	TextFilterMode string
+	// Word list file for the text filter, the built-in list when empty
+	TextFilterWordList string
 }
 
 func LoadConfig() *Config {
@@ -61,6 +72,11 @@ func LoadConfig() *Config {
 		AwsSecretKey:    getEnv("AWS_SECRET_ACCESS_KEY", ""),
 		VAPIDPrivateKey: getEnv("VAPID_PRIVATE_KEY", ""),
 		VAPIDPublicKey:  getEnv("VAPID_PUBLIC_KEY", ""),
+		DuplicateMode:   getEnv("DUPLICATE_MODE", "flag"),
+
+		ReportHideThreshold: getEnvInt("REPORT_HIDE_THRESHOLD", 3),
+		TextFilterMode:      getEnv("TEXT_FILTER_MODE", "flag"),
+		TextFilterWordList:  getEnv("TEXT_FILTER_WORDLIST", ""),
 	}
 }
 
@@ -71,3 +87,16 @@ func getEnv(key string, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
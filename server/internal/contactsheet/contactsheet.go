@@ -0,0 +1,173 @@
+package contactsheet
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/config"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/imaging"
+	"drawer-service-backend/internal/palette"
+	"drawer-service-backend/internal/strokes"
+	"drawer-service-backend/internal/utils"
+	"fmt"
+	"image"
+	"image/color"
+	"sync"
+	"time"
+)
+
+const (
+	Margin      = 16
+	TitleScale  = 3
+	TitleHeight = 40
+
+	// Month sheets show each drawing large enough to make out
+	MonthCellSize = 100
+	MonthCellGap  = 4
+
+	// Year sheets are twelve small calendars, four per row
+	YearCellSize    = 24
+	YearCellGap     = 2
+	YearMonthGap    = 20
+	YearMonthsInRow = 4
+
+	// Concurrent image fetches while building a sheet
+	loadWorkers = 8
+)
+
+var (
+	textColor   = color.NRGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff}
+	mutedColor  = color.NRGBA{R: 0x99, G: 0x99, B: 0x99, A: 0xff}
+	drawnColor  = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	missedColor = color.NRGBA{R: 0xe6, G: 0xe4, B: 0xdc, A: 0xff}
+
+	weekdays = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+)
+
+// RenderMonth lays out a month as a calendar with each day's drawing in its
+// cell. Days without a drawing are left as empty cells.
+func RenderMonth(repo *sql.DB, ctx context.Context, cfg *config.Config, title string, month time.Time, submissions []models.UserPromptSubmission) *image.NRGBA {
+	images := loadImages(repo, ctx, cfg, submissions, MonthCellSize)
+
+	gridWidth := 7*MonthCellSize + 6*MonthCellGap
+	headerHeight := imaging.GlyphHeight*2 + 8
+	width := gridWidth + 2*Margin
+	height := Margin + TitleHeight + headerHeight + monthGridHeight(month, MonthCellSize, MonthCellGap) + Margin
+
+	sheet := newSheet(width, height, title)
+	top := Margin + TitleHeight
+	for i, weekday := range weekdays {
+		x := Margin + i*(MonthCellSize+MonthCellGap)
+		imaging.DrawText(sheet, x+(MonthCellSize-imaging.TextWidth(weekday, 2))/2, top, weekday, mutedColor, 2)
+	}
+
+	drawMonthGrid(sheet, image.Pt(Margin, top+headerHeight), month, MonthCellSize, MonthCellGap, images, true)
+	return sheet
+}
+
+// RenderYear lays out twelve small month calendars for a year.
+func RenderYear(repo *sql.DB, ctx context.Context, cfg *config.Config, title string, year int, submissions []models.UserPromptSubmission) *image.NRGBA {
+	images := loadImages(repo, ctx, cfg, submissions, YearCellSize)
+
+	monthWidth := 7*YearCellSize + 6*YearCellGap
+	monthTitleHeight := imaging.GlyphHeight*2 + 8
+	// Every month gets room for six weeks so the rows line up
+	monthHeight := monthTitleHeight + 6*YearCellSize + 5*YearCellGap
+	rows := 12 / YearMonthsInRow
+
+	width := 2*Margin + YearMonthsInRow*monthWidth + (YearMonthsInRow-1)*YearMonthGap
+	height := Margin + TitleHeight + rows*monthHeight + (rows-1)*YearMonthGap + Margin
+
+	sheet := newSheet(width, height, title)
+	for i := 0; i < 12; i++ {
+		month := time.Date(year, time.Month(i+1), 1, 0, 0, 0, 0, time.UTC)
+		x := Margin + (i%YearMonthsInRow)*(monthWidth+YearMonthGap)
+		y := Margin + TitleHeight + (i/YearMonthsInRow)*(monthHeight+YearMonthGap)
+
+		imaging.DrawText(sheet, x, y, month.Format("January"), textColor, 2)
+		drawMonthGrid(sheet, image.Pt(x, y+monthTitleHeight), month, YearCellSize, YearCellGap, images, false)
+	}
+
+	return sheet
+}
+
+func newSheet(width, height int, title string) *image.NRGBA {
+	sheet := image.NewNRGBA(image.Rect(0, 0, width, height))
+	background, _ := strokes.ParseHexColor(palette.DefaultBackground)
+	imaging.Fill(sheet, background)
+
+	title = imaging.TruncateText(title, width-2*Margin, TitleScale)
+	imaging.DrawText(sheet, Margin, Margin, title, textColor, TitleScale)
+	return sheet
+}
+
+// drawMonthGrid draws one cell per day, weeks starting on Sunday.
+func drawMonthGrid(sheet *image.NRGBA, origin image.Point, month time.Time, cellSize, gap int, images map[string]image.Image, labelDays bool) {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	offset := int(first.Weekday())
+
+	for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+		slot := offset + day.Day() - 1
+		x := origin.X + (slot%7)*(cellSize+gap)
+		y := origin.Y + (slot/7)*(cellSize+gap)
+		cell := image.Rect(x, y, x+cellSize, y+cellSize)
+
+		drawing, drawn := images[utils.GetFormattedDate(day)]
+		if !drawn {
+			imaging.FillRect(sheet, cell, missedColor)
+		} else {
+			imaging.FillRect(sheet, cell, drawnColor)
+			if drawing != nil {
+				imaging.DrawFitted(sheet, cell.Inset(cellSize/20), drawing)
+			}
+		}
+
+		if labelDays {
+			imaging.DrawText(sheet, x+4, y+4, fmt.Sprint(day.Day()), mutedColor, 1)
+		}
+	}
+}
+
+func monthGridHeight(month time.Time, cellSize, gap int) int {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	days := first.AddDate(0, 1, -1).Day()
+	weeks := (int(first.Weekday()) + days + 6) / 7
+	return weeks*cellSize + (weeks-1)*gap
+}
+
+// loadImages fetches each submission's drawing keyed by day. Days that were
+// drawn but whose image couldn't be loaded map to nil so they still show as
+// drawn.
+func loadImages(repo *sql.DB, ctx context.Context, cfg *config.Config, submissions []models.UserPromptSubmission, cellSize int) map[string]image.Image {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		images = make(map[string]image.Image, len(submissions))
+		slots  = make(chan struct{}, loadWorkers)
+	)
+
+	for _, submission := range submissions {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(submission models.UserPromptSubmission) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			var img image.Image
+			if cellSize <= strokes.ThumbnailSize {
+				img = imaging.LoadSubmissionThumbnail(repo, ctx, cfg, submission)
+			} else {
+				img = imaging.LoadSubmission(repo, ctx, cfg, submission)
+			}
+
+			mu.Lock()
+			images[submission.Day] = img
+			mu.Unlock()
+		}(submission)
+	}
+
+	wg.Wait()
+	return images
+}
@@ -0,0 +1,62 @@
+package contest
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/achievements"
+	"drawer-service-backend/internal/config"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/notifications"
+	"log"
+	"time"
+)
+
+// How often the job looks for finished weeks to tally
+const tallyInterval = time.Hour
+
+// StartWeeklyTally tallies the votes of every finished week in the
+// background, once at startup and then every tallyInterval. Weeks that were
+// already tallied are skipped, so missing a run just delays the results.
+func StartWeeklyTally(repo *sql.DB, cfg *config.Config) {
+	go func() {
+		ticker := time.NewTicker(tallyInterval)
+		defer ticker.Stop()
+
+		for {
+			TallyFinishedWeeks(repo, cfg)
+			<-ticker.C
+		}
+	}()
+}
+
+// TallyFinishedWeeks picks the winners of the weeks before the current one
+// that haven't been tallied yet, then awards and notifies the winners
+func TallyFinishedWeeks(repo *sql.DB, cfg *config.Config) {
+	ctx := context.Background()
+	currentWeek, _ := queries.ContestWeek(time.Now())
+
+	weeks, err := queries.GetUntalliedContestWeeks(repo, ctx, currentWeek)
+	if err != nil {
+		log.Printf("Error fetching contest weeks to tally: %v", err)
+		return
+	}
+
+	for _, week := range weeks {
+		winners, err := queries.TallyContestWeek(repo, ctx, week)
+		if err != nil {
+			log.Printf("Error tallying contest week %s: %v", week, err)
+			continue
+		}
+		log.Printf("Tallied contest week %s with %d winners", week, len(winners))
+
+		for _, userID := range winners {
+			achievementService := achievements.NewAchievementService(repo, ctx, userID)
+			if err := achievementService.UpdateContestAchievements(userID); err != nil {
+				log.Printf("Error updating contest achievements for user %s: %v", userID, err)
+			}
+			if err := notifications.NotifyUserOfContestWin(repo, userID, cfg); err != nil {
+				log.Printf("Failed to send contest win notification to %s: %v", userID, err)
+			}
+		}
+	}
+}
@@ -3,13 +3,15 @@ package context
 import (
 	"database/sql"
 	"drawer-service-backend/internal/config"
+	"drawer-service-backend/internal/textfilter"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AppContext struct {
-	DB      *sql.DB
-	Config  *config.Config
+	DB         *sql.DB
+	Config     *config.Config
+	TextFilter *textfilter.Filter
 }
 
 var AppContextKey = "APP_CONTEXT"
@@ -18,4 +20,4 @@ func GetCtx(ctx *gin.Context) *AppContext {
 	appContext := ctx.MustGet(string(AppContextKey)).(*AppContext)
 
 	return appContext
-}
\ No newline at end of file
+}
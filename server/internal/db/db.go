@@ -7,12 +7,16 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	_ "github.com/tursodatabase/libsql-client-go/libsql"
 )
 
+// InitDB opens the database for the environment. SQLite leaves foreign keys
+// unenforced unless each connection turns them on, which ours don't, so
+// queries that delete rows remove their dependent rows themselves.
 func InitDB(cfg *config.Config) (*sql.DB, error) {
 	// For local development, use a local SQLite file
 	// For production, use Turso
@@ -73,6 +77,8 @@ func initProductionDB(cfg *config.Config) (*sql.DB, error) {
 	return db, nil
 }
 
+// initLocalDB opens the dev database. Search needs FTS5, which go-sqlite3
+// only compiles in with the sqlite_fts5 build tag.
 func initLocalDB() (*sql.DB, error) {
 	db, err := sql.Open("sqlite3", "file:drawer.db?cache=shared&_journal=WAL&_timeout=5000")
 
@@ -107,6 +113,9 @@ func initializeDevData(db *sql.DB) error {
 
 	// Execute the schema SQL commands
 	_, err = db.Exec(string(migrationsSchema))
+	if err != nil && strings.Contains(err.Error(), "no such module: fts5") {
+		return fmt.Errorf("failed to execute schema SQL, build with -tags sqlite_fts5 for the search index: %w", err)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to execute schema SQL: %w", err)
 	}
@@ -125,12 +134,25 @@ func initializeDevData(db *sql.DB) error {
 			('achievement4', 'A dedicated citizen', 'Draw doodles for 5 days in a row', '', 'SUBMISSION_STREAK', 5),
 			('achievement5', 'Doodle Kiddie', 'Draw 10 total doodles', '', 'SUBMISSION_TOTAL', 10),
 			('achievement6', 'Doodle Pro', 'Draw 50 total doodles', '', 'SUBMISSION_TOTAL', 50),
-			('achievement7', 'Doodle God', 'Draw 100 total doodles', '', 'SUBMISSION_TOTAL', 100);
+			('achievement7', 'Doodle God', 'Draw 100 total doodles', '', 'SUBMISSION_TOTAL', 100),
+			('achievement8', 'Palette purist', 'Stick to the daily palette 10 times', '', 'PALETTE_PURIST_TOTAL', 10),
+			('achievement9', 'Recruiter', 'Bring 3 friends to the app with an invite link', '', 'REFERRAL_TOTAL', 3),
+			('achievement10', 'Crowd favourite', 'Win the weekly vote among your friends', '', 'CONTEST_WIN_TOTAL', 1);
 
 		-- Insert reward unlocks
 		INSERT OR IGNORE INTO reward_unlocks (id, name, description, created_at, achievement_id)
 		VALUES
-			('CUSTOM_PROFILE_PIC', 'Custom Profile Picture', 'You can now draw your own profile picture', date('now'), 'achievement4');
+			('CUSTOM_PROFILE_PIC', 'Custom Profile Picture', 'You can now draw your own profile picture', date('now'), 'achievement4'),
+			('REACTION_SPARKLES', 'Sparkles Reaction', 'You can now react with sparkles', date('now'), 'achievement5');
+
+		-- Insert reaction catalog
+		INSERT OR IGNORE INTO reaction_types (id, emoji, applies_to, reward_id, sort_order) VALUES
+			('heart', '❤️', 'both', NULL, 1),
+			('cry-laugh', '😂', 'both', NULL, 2),
+			('face-meh', '😐', 'both', NULL, 3),
+			('fire', '🔥', 'both', NULL, 4),
+			('thumbs-up', '👍', 'comment', NULL, 5),
+			('sparkles', '✨', 'both', 'REACTION_SPARKLES', 6);
 
 		-- Insert demo users
 		INSERT OR IGNORE INTO users (id, username, email, role, created_at, avatar_type, avatar_url) VALUES
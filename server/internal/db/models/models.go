@@ -6,10 +6,13 @@ import (
 
 // DailyPrompt represents the data returned for the daily challenge.
 type DailyPrompt struct {
-	Day       string   `json:"day"` // Format: YYYY-MM-DD
-	Colors    []string `json:"colors"`
-	Prompt    string   `json:"prompt"`
-	CreatedBy *User    `json:"createdBy"`
+	Day         string   `json:"day"` // Format: YYYY-MM-DD
+	Colors      []string `json:"colors"`
+	Prompt      string   `json:"prompt"`
+	PaletteMode string   `json:"paletteMode"`
+	CreatedBy   *User    `json:"createdBy"`
+	// Set when this is a group's prompt rather than the global one
+	GroupID string `json:"groupId,omitempty"`
 }
 
 type PromptSuggestion struct {
@@ -28,6 +31,61 @@ type User struct {
 	CreatedAt  time.Time `json:"createdAt"`
 	AvatarType string    `json:"avatarType"`
 	AvatarURL  string    `json:"avatarUrl"`
+	// Set while a moderator has suspended the user
+	SuspendedUntil *time.Time `json:"-"`
+}
+
+// PublicUser is a user as shown to people who aren't their friends, without
+// an email address.
+type PublicUser struct {
+	ID         string    `json:"id"`
+	Username   string    `json:"username"`
+	CreatedAt  time.Time `json:"createdAt"`
+	AvatarType string    `json:"avatarType"`
+	AvatarURL  string    `json:"avatarUrl"`
+}
+
+// UserSearchResult is a user matching a search, with the requester's
+// friendship state with them ("pending", "accepted" or empty).
+type UserSearchResult struct {
+	User       PublicUser `json:"user"`
+	Friendship string     `json:"friendship"`
+}
+
+// FriendSuggestion is a friend of a friend the requester may know.
+type FriendSuggestion struct {
+	User          PublicUser `json:"user"`
+	MutualFriends int        `json:"mutualFriends"`
+}
+
+// InviteCode is a shareable code that makes whoever redeems it friends with
+// its creator.
+type InviteCode struct {
+	Code      string     `json:"code"`
+	URL       string     `json:"url"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	MaxUses   *int       `json:"maxUses"`
+	Uses      int        `json:"uses"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Group is a private circle of users with its own feed and, optionally,
+// its own daily prompts.
+type Group struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	InviteCode  string        `json:"inviteCode"` // Anyone with the code can join
+	Role        string        `json:"role"`       // The requester's role in the group
+	MemberCount int           `json:"memberCount"`
+	Members     []GroupMember `json:"members,omitempty"`
+	CreatedAt   time.Time     `json:"createdAt"`
+}
+
+type GroupMember struct {
+	User     PublicUser `json:"user"`
+	Role     string     `json:"role"`
+	JoinedAt time.Time  `json:"joinedAt"`
 }
 
 // Reaction represents a user's reaction to content
@@ -44,6 +102,21 @@ type ReactionCount struct {
 	Count      int    `json:"count"`
 }
 
+// ReactionType is an entry of the reaction catalog
+type ReactionType struct {
+	ID       string `json:"id"`
+	Emoji    string `json:"emoji"`
+	AssetURL string `json:"assetUrl"`
+	// One of submission, comment or both
+	AppliesTo string `json:"appliesTo"`
+	// Reward that has to be unlocked to use the reaction, if any
+	RewardID  string `json:"rewardId,omitempty"`
+	Active    bool   `json:"active"`
+	SortOrder int    `json:"sortOrder"`
+	// Whether the requesting user may use it
+	Unlocked bool `json:"unlocked"`
+}
+
 // ReactionResponse represents the complete reaction data for content
 type ReactionResponse struct {
 	Reactions []Reaction      `json:"reactions"`
@@ -58,6 +131,23 @@ type Comment struct {
 	CreatedAt time.Time       `json:"createdAt"`
 	Reactions []Reaction      `json:"reactions"`
 	Counts    []ReactionCount `json:"counts"`
+	// Set on replies, which are nested under the comment they answer
+	ParentID   string     `json:"parentId,omitempty"`
+	Replies    []Comment  `json:"replies,omitempty"`
+	ReplyCount int        `json:"replyCount"`
+	EditedAt   *time.Time `json:"editedAt"`
+	// Deleted comments that still have replies stay as empty placeholders
+	Deleted  bool      `json:"deleted"`
+	Mentions []Mention `json:"mentions"`
+}
+
+// Mention is a friend @mentioned in a comment. Start and Length locate it in
+// the comment's text, in UTF-16 code units.
+type Mention struct {
+	UserID   string `json:"userId"`
+	Username string `json:"username"`
+	Start    int    `json:"start"`
+	Length   int    `json:"length"`
 }
 
 // UserPromptSubmission combines the daily prompt details with the user's submitted canvas data.
@@ -72,13 +162,191 @@ type UserPromptSubmission struct {
 	Reactions  []Reaction      `json:"reactions"`
 	Counts     []ReactionCount `json:"counts"`
 	IsFavorite bool            `json:"isFavorite"`
+	HasStrokes bool            `json:"hasStrokes"`
 	CreatedAt  time.Time       `json:"createdAt"`
 	CreatedBy  *User           `json:"createdBy"`
+	// One of private, friends or public
+	Visibility string `json:"visibility"`
+	// Optional text the artist added. AltText describes the drawing for
+	// people using screen readers.
+	Title   string `json:"title"`
+	Caption string `json:"caption"`
+	AltText string `json:"altText"`
+	// Set on drawings of today's prompt until the requester has drawn it too
+	Locked bool `json:"locked"`
+	// Remix lineage, only filled in when fetching a single submission. Kind is
+	// daily or remix, RemixOf is empty once the remixed drawing is deleted.
+	Kind    string              `json:"kind,omitempty"`
+	RemixOf string              `json:"remixOf,omitempty"`
+	Lineage []SubmissionPreview `json:"lineage,omitempty"`
+	Remixes []SubmissionPreview `json:"remixes,omitempty"`
+}
+
+// SubmissionPreview is just enough of a submission to link to it
+type SubmissionPreview struct {
+	ID        string    `json:"id"`
+	User      User      `json:"user"`
+	ImageUrl  string    `json:"imageUrl"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SearchResult is a submission that matched a search through its prompt,
+// its own text or its comments
+type SearchResult struct {
+	Submission SubmissionPreview `json:"submission"`
+	Prompt     string            `json:"prompt"`
+	Title      string            `json:"title"`
+	Caption    string            `json:"caption"`
+	Highlights []SearchHighlight `json:"highlights"`
+}
+
+// SearchHighlight is a snippet of text that matched a search. The snippet is
+// HTML-escaped with the matched terms wrapped in <mark> tags.
+type SearchHighlight struct {
+	// One of prompt, submission or comment
+	Source    string `json:"source"`
+	CommentID string `json:"commentId,omitempty"`
+	Snippet   string `json:"snippet"`
+}
+
+// SearchResponse is a page of search results, best match first. NextOffset
+// is nil on the last page.
+type SearchResponse struct {
+	Results    []SearchResult `json:"results"`
+	NextOffset *int           `json:"nextOffset"`
+}
+
+// ContestWin is a drawing that won the weekly vote of at least one friend
+// circle
+type ContestWin struct {
+	// Monday of the week the votes were cast in
+	Week       string            `json:"week"`
+	Submission SubmissionPreview `json:"submission"`
+	// Votes in the circle the drawing did best in
+	Votes int `json:"votes"`
+	// How many friend circles the drawing won
+	Circles int `json:"circles"`
+}
+
+// ContestVote is the drawing a user voted for on a day
+type ContestVote struct {
+	Day          string    `json:"day"`
+	SubmissionID string    `json:"submissionId"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// Lock turns the submission into a placeholder that shows who drew it but not
+// the drawing or the conversation around it
+func (s *UserPromptSubmission) Lock() {
+	s.Locked = true
+	s.ImageUrl = ""
+	s.HasStrokes = false
+	s.Title = ""
+	s.Caption = ""
+	s.AltText = ""
+	s.Comments = []Comment{}
+	s.Reactions = []Reaction{}
+	s.Counts = []ReactionCount{}
+	s.Lineage = nil
+	s.Remixes = nil
+}
+
+// DuplicatePair is a submission that was flagged as a near-duplicate of an
+// earlier drawing by the same user
+type DuplicatePair struct {
+	Submission UserPromptSubmission `json:"submission"`
+	Original   UserPromptSubmission `json:"original"`
+	Distance   int                  `json:"distance"`
+}
+
+// Report is one user's report of a piece of content
+type Report struct {
+	ID         string     `json:"id"`
+	Reporter   User       `json:"reporter"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"`
+	Action     string     `json:"action,omitempty"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// ReportedContent is an entry of the moderation queue: a reported piece of
+// content with its reports rolled up
+type ReportedContent struct {
+	ContentType   string `json:"contentType"`
+	ContentID     string `json:"contentId"`
+	OwnerID       string `json:"ownerId"`
+	OwnerUsername string `json:"ownerUsername"`
+	// Text of the comment, prompt suggestion or username, or the title,
+	// caption and alt text of a drawing. Empty for content that's since been
+	// deleted.
+	Preview  string `json:"preview"`
+	ImageURL string `json:"imageUrl,omitempty"`
+	Hidden   bool   `json:"hidden"`
+	// Distinct reporters, and how many of them gave each reason
+	ReportCount    int            `json:"reportCount"`
+	Reasons        map[string]int `json:"reasons"`
+	Status         string         `json:"status"`
+	LastReportedAt time.Time      `json:"lastReportedAt"`
+}
+
+// TextFlag is user text the profanity filter let through for an admin to
+// look at
+type TextFlag struct {
+	ContentType string `json:"contentType"`
+	ContentID   string `json:"contentId"`
+	User        User   `json:"user"`
+	Text        string `json:"text"`
+	// Word list entries the text matched
+	Terms     []string  `json:"terms"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Stroke is a single continuous brush movement on the canvas.
+// Points are [x, y, t] triples where t is milliseconds since drawing started.
+type Stroke struct {
+	ColorIndex int      `json:"color"`
+	Brush      string   `json:"brush"`
+	Size       int      `json:"size"`
+	Points     [][3]int `json:"points"`
+}
+
+// StrokeData is the compact stroke log a client can send alongside its PNG.
+type StrokeData struct {
+	Width   int      `json:"width"`
+	Height  int      `json:"height"`
+	Strokes []Stroke `json:"strokes"`
+}
+
+// SubmissionStrokes is the data needed to replay how a submission was drawn.
+type SubmissionStrokes struct {
+	SubmissionID string     `json:"submissionId"`
+	Colors       []string   `json:"colors"`
+	Strokes      StrokeData `json:"strokes"`
+	CreatedAt    time.Time  `json:"createdAt"`
 }
 
 type UserStats struct {
 	TotalDrawings int `json:"totalDrawings"`
-	CurrentStreak int `json:"currentStreak"`
+	// Nil when the user hides their streak from the requester
+	CurrentStreak *int `json:"currentStreak"`
+}
+
+// LeaderboardEntry is a user's place on a friends leaderboard
+type LeaderboardEntry struct {
+	// Users with the same score share a rank
+	Rank  int  `json:"rank"`
+	User  User `json:"user"`
+	Score int  `json:"score"`
+}
+
+// PrivacySettings control what other users can see of a user's profile
+type PrivacySettings struct {
+	// One of everyone, friends or nobody
+	ProfileVisibility string `json:"profileVisibility"`
+	ShowStreak        bool   `json:"showStreak"`
+	ShowAchievements  bool   `json:"showAchievements"`
 }
 
 // GetMeResponse is the structure for the /me endpoint response.
@@ -90,6 +358,14 @@ type GetMeResponse struct {
 	Stats      UserStats               `json:"stats"`
 	Favorites  []*FavoriteSubmission   `json:"favorites"`
 	Invitation *InvitationStatus       `json:"invitation"`
+	// Weeks the user's drawing got the most votes among friends, newest first
+	ContestWins []ContestWin `json:"contestWins"`
+	// Whether the requester has blocked or muted this user
+	Blocked bool `json:"blocked"`
+	Muted   bool `json:"muted"`
+	// Set when the user's privacy settings hide their profile from the
+	// requester, only the user and invitation are filled in then
+	Restricted bool `json:"restricted"`
 }
 
 type FavoriteSubmission struct {
@@ -99,26 +375,52 @@ type FavoriteSubmission struct {
 	OrderNum   int                  `json:"orderNum"`
 }
 
+// FavoriteCollection is a named album of a user's favorites
+type FavoriteCollection struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Set while the collection is shared publicly, only shown to its owner
+	ShareCode *string `json:"shareCode,omitempty"`
+	ShareUrl  *string `json:"shareUrl,omitempty"`
+	// The owner, filled in for shared collections
+	User      *PublicUser      `json:"user,omitempty"`
+	Items     []CollectionItem `json:"items"`
+	CreatedAt time.Time        `json:"createdAt"`
+}
+
+type CollectionItem struct {
+	FavoriteID string            `json:"favoriteId"`
+	Submission SubmissionPreview `json:"submission"`
+	OrderNum   int               `json:"orderNum"`
+}
+
 type ActivityAction string
 
 const (
 	ActivityActionComment  ActivityAction = "comment"
 	ActivityActionReaction ActivityAction = "reaction"
+	ActivityActionMention  ActivityAction = "mention"
+	ActivityActionRemix    ActivityAction = "remix"
 )
 
 type Activity struct {
-	ID         string         `json:"id"`
-	User       User           `json:"user"`
-	Action     ActivityAction `json:"action"`
-	Date       time.Time      `json:"date"`
-	IsRead     bool           `json:"isRead"`
-	Comment    *Comment       `json:"comment,omitempty"`
-	Reaction   *Reaction      `json:"reaction,omitempty"`
-	Submission *struct {
-		ID       string `json:"id"`
-		Prompt   string `json:"prompt"`
-		ImageUrl string `json:"imageUrl"`
-	} `json:"submission,omitempty"`
+	ID         string              `json:"id"`
+	User       User                `json:"user"`
+	Action     ActivityAction      `json:"action"`
+	Date       time.Time           `json:"date"`
+	IsRead     bool                `json:"isRead"`
+	Comment    *Comment            `json:"comment,omitempty"`
+	Reaction   *Reaction           `json:"reaction,omitempty"`
+	Submission *ActivitySubmission `json:"submission,omitempty"`
+}
+
+// ActivitySubmission is the drawing an activity happened on
+type ActivitySubmission struct {
+	ID       string `json:"id"`
+	Prompt   string `json:"prompt"`
+	Title    string `json:"title"`
+	Caption  string `json:"caption"`
+	ImageUrl string `json:"imageUrl"`
 }
 
 type PushSubscription struct {
@@ -133,6 +435,11 @@ const (
 	NotificationTypeFriendSubmission NotificationType = "friend_submission"
 	NotificationTypeReaction         NotificationType = "reaction"
 	NotificationTypeComment          NotificationType = "comment"
+	NotificationTypeReply            NotificationType = "reply"
+	NotificationTypeMention          NotificationType = "mention"
+	NotificationTypeWarning          NotificationType = "warning"
+	NotificationTypeRemix            NotificationType = "remix"
+	NotificationTypeContestWin       NotificationType = "contest_win"
 )
 
 type NotificationData struct {
@@ -143,6 +450,9 @@ type NotificationData struct {
 	UserID   string           `json:"userId,omitempty"`
 	Username string           `json:"username,omitempty"`
 	Action   string           `json:"action,omitempty"`
+	// Set when the notification points at a drawing the recipient can't see
+	// until they've drawn today's prompt
+	Locked bool `json:"locked,omitempty"`
 }
 
 type Invitation struct {
@@ -7,10 +7,11 @@ import (
 	"drawer-service-backend/internal/db/models"
 	"drawer-service-backend/internal/utils"
 	"sort"
+	"strings"
 	"time"
 )
 
-func GetActivityFeed(repo *sql.DB, ctx context.Context, userID string, lastReadID string, cfg *config.Config) ([]models.Activity, error) {
+func GetActivityFeed(repo *sql.DB, ctx context.Context, userID string, lastReadDate time.Time, cfg *config.Config) ([]models.Activity, error) {
 	friendQuery := `
 		SELECT DISTINCT CASE WHEN user1 = ? THEN user2 ELSE user1 END AS friend_id
 		FROM friendships
@@ -29,38 +30,58 @@ func GetActivityFeed(repo *sql.DB, ctx context.Context, userID string, lastReadI
 	}
 	friendIDs[userID] = true // include self for submission ownership
 
-	subQuery := `SELECT id, prompt, user_id FROM user_submissions us JOIN daily_prompts dp ON us.day = dp.day WHERE us.user_id IN (` + placeholders(len(friendIDs)) + `)`
+	subQuery := `SELECT us.id, COALESCE(gp.prompt, dp.prompt), us.title, us.caption, us.user_id FROM user_submissions us JOIN daily_prompts dp ON us.day = dp.day LEFT JOIN group_prompts gp ON gp.group_id = us.group_id AND gp.day = us.day WHERE us.user_id IN (` + placeholders(len(friendIDs)) + `) AND ` + SubmissionVisibleTo("us")
 	subArgs := make([]interface{}, 0, len(friendIDs))
 	for id := range friendIDs {
 		subArgs = append(subArgs, id)
 	}
+	subArgs = append(subArgs, SubmissionVisibleToArgs(userID)...)
 	subRows, err := repo.QueryContext(ctx, subQuery, subArgs...)
 	if err != nil {
 		return nil, err
 	}
 	defer subRows.Close()
 	subMap := map[string]struct {
-		Prompt string
-		UserID string
+		Prompt  string
+		Title   string
+		Caption string
+		UserID  string
 	}{}
 	for subRows.Next() {
-		var id, prompt, subUserID string
-		if err := subRows.Scan(&id, &prompt, &subUserID); err == nil {
+		var id, prompt, title, caption, subUserID string
+		if err := subRows.Scan(&id, &prompt, &title, &caption, &subUserID); err == nil {
 			subMap[id] = struct {
-				Prompt string
-				UserID string
-			}{Prompt: prompt, UserID: subUserID}
+				Prompt  string
+				Title   string
+				Caption string
+				UserID  string
+			}{Prompt: prompt, Title: title, Caption: caption, UserID: subUserID}
 		}
 	}
-	if len(subMap) == 0 {
-		return []models.Activity{}, nil
+
+	activities, err := getMentionActivities(repo, ctx, userID, cfg)
+	if err != nil {
+		return nil, err
+	}
+	mentionedIn := map[string]bool{}
+	for _, activity := range activities {
+		mentionedIn[activity.Comment.ID] = true
 	}
 
+	remixes, err := getRemixActivities(repo, ctx, userID, cfg)
+	if err != nil {
+		return nil, err
+	}
+	activities = append(activities, remixes...)
+
+	// An empty IN list matches nothing, so a user without visible
+	// submissions still gets their mentions
 	commentQuery := `
 		SELECT c.id, c.user_id, u.username, u.email, u.created_at, u.avatar_type, u.avatar_url, c.text, c.created_at, c.submission_id
 		FROM comments c
 		JOIN users u ON c.user_id = u.id
 		WHERE c.submission_id IN (` + placeholders(len(subMap)) + `)
+		AND c.deleted_at IS NULL AND c.hidden_at IS NULL
 		AND c.created_at >= datetime('now', '-7 days')
 		ORDER BY c.created_at DESC
 	`
@@ -74,17 +95,19 @@ func GetActivityFeed(repo *sql.DB, ctx context.Context, userID string, lastReadI
 	}
 	defer commentRows.Close()
 
-	activities := []models.Activity{}
 	for commentRows.Next() {
 		var cID, cUserID, cUsername, cEmail, cAvatarType, cAvatarURL, cText, cSubmissionID string
 		var cUserCreatedAt, cCreatedAt time.Time
-		if err := commentRows.Scan(&cID, &cUserID, &cUsername, &cEmail, &cUserCreatedAt, &cText, &cCreatedAt, &cSubmissionID); err == nil {
+		if err := commentRows.Scan(&cID, &cUserID, &cUsername, &cEmail, &cUserCreatedAt, &cAvatarType, &cAvatarURL, &cText, &cCreatedAt, &cSubmissionID); err == nil {
 			if cUserID == userID {
 				continue
 			} // skip own actions
 			if !friendIDs[cUserID] {
 				continue
 			} // only friends' actions
+			if mentionedIn[cID] {
+				continue
+			} // already listed as a mention
 			info := subMap[cSubmissionID]
 			activities = append(activities, models.Activity{
 				ID:     "comment-" + cID,
@@ -97,13 +120,11 @@ func GetActivityFeed(repo *sql.DB, ctx context.Context, userID string, lastReadI
 					Text:      cText,
 					CreatedAt: cCreatedAt,
 				},
-				Submission: &struct {
-					ID       string `json:"id"`
-					Prompt   string `json:"prompt"`
-					ImageUrl string `json:"imageUrl"`
-				}{
+				Submission: &models.ActivitySubmission{
 					ID:       cSubmissionID,
 					Prompt:   info.Prompt,
+					Title:    info.Title,
+					Caption:  info.Caption,
 					ImageUrl: utils.GetImageUrl(cfg, utils.GetSubmissionFilename(info.UserID, cSubmissionID)),
 				},
 			})
@@ -145,13 +166,11 @@ func GetActivityFeed(repo *sql.DB, ctx context.Context, userID string, lastReadI
 					ReactionID: rReactionID,
 					CreatedAt:  rCreatedAt,
 				},
-				Submission: &struct {
-					ID       string `json:"id"`
-					Prompt   string `json:"prompt"`
-					ImageUrl string `json:"imageUrl"`
-				}{
+				Submission: &models.ActivitySubmission{
 					ID:       rContentID,
 					Prompt:   info.Prompt,
+					Title:    info.Title,
+					Caption:  info.Caption,
 					ImageUrl: utils.GetImageUrl(cfg, utils.GetSubmissionFilename(info.UserID, rContentID)),
 				},
 			})
@@ -165,7 +184,7 @@ func GetActivityFeed(repo *sql.DB, ctx context.Context, userID string, lastReadI
 	}
 
 	for i := range activities {
-		if lastReadID != "" && activities[i].ID <= lastReadID {
+		if !lastReadDate.IsZero() && !activities[i].Date.After(lastReadDate) {
 			activities[i].IsRead = true
 		}
 	}
@@ -173,25 +192,161 @@ func GetActivityFeed(repo *sql.DB, ctx context.Context, userID string, lastReadI
 	return activities, nil
 }
 
-func GetLastReadActivityID(repo *sql.DB, ctx context.Context, userID string) (string, error) {
-	query := `SELECT last_read_activity_id FROM activity_reads WHERE user_id = ?`
-	var id sql.NullString
-	err := repo.QueryRowContext(ctx, query, userID).Scan(&id)
-	if err == sql.ErrNoRows {
-		return "", nil
+// getMentionActivities lists the comments from the last week that mention
+// the user, on submissions they can see
+func getMentionActivities(repo *sql.DB, ctx context.Context, userID string, cfg *config.Config) ([]models.Activity, error) {
+	query := `
+		SELECT c.id, c.user_id, u.username, u.email, u.created_at, u.avatar_type, u.avatar_url, c.text, c.created_at, c.submission_id, COALESCE(gp.prompt, dp.prompt), us.title, us.caption, us.user_id
+		FROM comments c
+		JOIN users u ON c.user_id = u.id
+		JOIN user_submissions us ON us.id = c.submission_id
+		JOIN daily_prompts dp ON us.day = dp.day
+		LEFT JOIN group_prompts gp ON gp.group_id = us.group_id AND gp.day = us.day
+		WHERE c.id IN (SELECT comment_id FROM comment_mentions WHERE user_id = ?)
+		AND c.user_id != ?
+		AND c.deleted_at IS NULL AND c.hidden_at IS NULL
+		AND c.created_at >= datetime('now', '-7 days')
+		AND ` + NotBlockedBy("?", "c.user_id") + `
+		AND ` + NotBlockedBy("us.user_id", "c.user_id") + `
+		AND ` + SubmissionVisibleTo("us") + `
+		ORDER BY c.created_at DESC
+	`
+	args := append([]interface{}{userID, userID, userID}, SubmissionVisibleToArgs(userID)...)
+	rows, err := repo.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activities := []models.Activity{}
+	for rows.Next() {
+		var cID, cUserID, cUsername, cEmail, cAvatarType, cAvatarURL, cText, cSubmissionID, prompt, title, caption, ownerID string
+		var cUserCreatedAt, cCreatedAt time.Time
+		if err := rows.Scan(&cID, &cUserID, &cUsername, &cEmail, &cUserCreatedAt, &cAvatarType, &cAvatarURL, &cText, &cCreatedAt, &cSubmissionID, &prompt, &title, &caption, &ownerID); err != nil {
+			continue
+		}
+		user := models.User{ID: cUserID, Username: cUsername, Email: cEmail, CreatedAt: cUserCreatedAt, AvatarType: cAvatarType, AvatarURL: cAvatarURL}
+		activities = append(activities, models.Activity{
+			ID:     "mention-" + cID,
+			User:   user,
+			Action: models.ActivityActionMention,
+			Date:   cCreatedAt,
+			Comment: &models.Comment{
+				ID:        cID,
+				User:      user,
+				Text:      cText,
+				CreatedAt: cCreatedAt,
+			},
+			Submission: &models.ActivitySubmission{
+				ID:       cSubmissionID,
+				Prompt:   prompt,
+				Title:    title,
+				Caption:  caption,
+				ImageUrl: utils.GetImageUrl(cfg, utils.GetSubmissionFilename(ownerID, cSubmissionID)),
+			},
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	comments := make([]*models.Comment, len(activities))
+	for i := range activities {
+		comments[i] = activities[i].Comment
 	}
+	if err := AttachMentions(repo, ctx, comments); err != nil {
+		return nil, err
+	}
+	return activities, nil
+}
+
+// getRemixActivities lists the remixes of the user's drawings from the last
+// week that they can see
+func getRemixActivities(repo *sql.DB, ctx context.Context, userID string, cfg *config.Config) ([]models.Activity, error) {
+	query := `
+		SELECT us.id, us.created_at, COALESCE(gp.prompt, dp.prompt), us.title, us.caption, u.id, u.username, u.email, u.created_at, u.avatar_type, u.avatar_url
+		FROM user_submissions us
+		JOIN user_submissions parent ON parent.id = us.remix_of
+		JOIN users u ON u.id = us.user_id
+		JOIN daily_prompts dp ON us.day = dp.day
+		LEFT JOIN group_prompts gp ON gp.group_id = us.group_id AND gp.day = us.day
+		WHERE parent.user_id = ? AND us.user_id != ?
+		AND us.created_at >= datetime('now', '-7 days')
+		AND ` + NotBlockedBy("?", "us.user_id") + `
+		AND ` + SubmissionVisibleTo("us") + `
+		ORDER BY us.created_at DESC
+	`
+	args := append([]interface{}{userID, userID, userID}, SubmissionVisibleToArgs(userID)...)
+	rows, err := repo.QueryContext(ctx, query, args...)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if id.Valid {
-		return id.String, nil
+	defer rows.Close()
+
+	activities := []models.Activity{}
+	for rows.Next() {
+		var remixID, prompt, title, caption string
+		var createdAt time.Time
+		var user models.User
+		if err := rows.Scan(&remixID, &createdAt, &prompt, &title, &caption, &user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.AvatarType, &user.AvatarURL); err != nil {
+			continue
+		}
+		activities = append(activities, models.Activity{
+			ID:     "remix-" + remixID,
+			User:   user,
+			Action: models.ActivityActionRemix,
+			Date:   createdAt,
+			Submission: &models.ActivitySubmission{
+				ID:       remixID,
+				Prompt:   prompt,
+				Title:    title,
+				Caption:  caption,
+				ImageUrl: utils.GetImageUrl(cfg, utils.GetSubmissionFilename(user.ID, remixID)),
+			},
+		})
+	}
+	return activities, rows.Err()
+}
+
+// activityDateQueries look up when an activity happened, by the kind its ID
+// starts with
+var activityDateQueries = map[string]string{
+	"comment":  `SELECT created_at FROM comments WHERE id = ?`,
+	"mention":  `SELECT created_at FROM comments WHERE id = ?`,
+	"reaction": `SELECT created_at FROM reactions WHERE id = ?`,
+	"remix":    `SELECT created_at FROM user_submissions WHERE id = ?`,
+}
+
+// GetLastReadActivityDate returns when the last activity the user marked as
+// read happened, the zero time when they haven't marked any
+func GetLastReadActivityDate(repo *sql.DB, ctx context.Context, userID string) (time.Time, error) {
+	query := `SELECT last_read_date FROM activity_reads WHERE user_id = ?`
+	var date sql.NullTime
+	err := repo.QueryRowContext(ctx, query, userID).Scan(&date)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
 	}
-	return "", nil
+	return date.Time, nil
 }
 
+// SetLastReadActivityID marks the activity and everything before it as read.
+// IDs of different kinds of activity don't sort together, so the time the
+// activity happened is stored with it, falling back to now for activities
+// that are gone.
 func SetLastReadActivityID(repo *sql.DB, ctx context.Context, userID, activityID string) error {
-	query := `INSERT INTO activity_reads (user_id, last_read_activity_id, last_read_date) VALUES (?, ?, CURRENT_TIMESTAMP)
+	args := []interface{}{userID, activityID}
+	readDate := "CURRENT_TIMESTAMP"
+	kind, id, _ := strings.Cut(activityID, "-")
+	if dateQuery, ok := activityDateQueries[kind]; ok {
+		readDate = "COALESCE((" + dateQuery + "), CURRENT_TIMESTAMP)"
+		args = append(args, id)
+	}
+
+	query := `INSERT INTO activity_reads (user_id, last_read_activity_id, last_read_date) VALUES (?, ?, ` + readDate + `)
 		ON CONFLICT(user_id) DO UPDATE SET last_read_activity_id = excluded.last_read_activity_id, last_read_date = excluded.last_read_date`
-	_, err := repo.ExecContext(ctx, query, userID, activityID)
+	_, err := repo.ExecContext(ctx, query, args...)
 	return err
 }
@@ -0,0 +1,159 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/db/models"
+	"fmt"
+)
+
+// BlockUser records the block and removes any friendship or invitation
+// between the two users. It reports whether an accepted friendship was
+// removed so friend counts can be recalculated.
+func BlockUser(repo *sql.DB, ctx context.Context, blockerID string, blockedID string) (removedFriend bool, err error) {
+	tx, err := repo.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO user_blocks (blocker_id, blocked_id) VALUES (?, ?)`, blockerID, blockedID)
+	if err != nil {
+		return false, fmt.Errorf("error inserting block: %w", err)
+	}
+
+	var state string
+	err = tx.QueryRowContext(ctx,
+		`DELETE FROM friendships
+		WHERE (user1 = ? AND user2 = ?) OR (user1 = ? AND user2 = ?)
+		RETURNING state`,
+		blockerID, blockedID, blockedID, blockerID).Scan(&state)
+	if err == sql.ErrNoRows {
+		err = nil
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error removing friendship: %w", err)
+	}
+	return state == "accepted", nil
+}
+
+// UnblockUser removes a block, reporting whether there was one
+func UnblockUser(repo *sql.DB, ctx context.Context, blockerID string, blockedID string) (bool, error) {
+	result, err := repo.ExecContext(ctx, `DELETE FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?`, blockerID, blockedID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// HasBlocked reports whether blockerID has blocked blockedID
+func HasBlocked(repo *sql.DB, ctx context.Context, blockerID string, blockedID string) (bool, error) {
+	var blocked bool
+	err := repo.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?)`,
+		blockerID, blockedID).Scan(&blocked)
+	return blocked, err
+}
+
+// IsBlockedEitherWay reports whether either user has blocked the other
+func IsBlockedEitherWay(repo *sql.DB, ctx context.Context, userID string, otherID string) (bool, error) {
+	var blocked bool
+	err := repo.QueryRowContext(ctx,
+		`SELECT EXISTS(
+			SELECT 1 FROM user_blocks
+			WHERE (blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)
+		)`,
+		userID, otherID, otherID, userID).Scan(&blocked)
+	return blocked, err
+}
+
+// GetBlockedUsers returns the users blockerID has blocked, most recent first
+func GetBlockedUsers(repo *sql.DB, ctx context.Context, blockerID string) ([]models.PublicUser, error) {
+	return getRelatedUsers(repo, ctx, `
+		SELECT u.id, u.username, u.created_at, u.avatar_type, u.avatar_url
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = ?
+		ORDER BY b.created_at DESC`, blockerID)
+}
+
+// MuteUser hides mutedID's submissions from muterID's feed. The muted user
+// is not told and nothing else about their relationship changes.
+func MuteUser(repo *sql.DB, ctx context.Context, muterID string, mutedID string) error {
+	_, err := repo.ExecContext(ctx, `INSERT OR IGNORE INTO user_mutes (muter_id, muted_id) VALUES (?, ?)`, muterID, mutedID)
+	return err
+}
+
+// UnmuteUser removes a mute, reporting whether there was one
+func UnmuteUser(repo *sql.DB, ctx context.Context, muterID string, mutedID string) (bool, error) {
+	result, err := repo.ExecContext(ctx, `DELETE FROM user_mutes WHERE muter_id = ? AND muted_id = ?`, muterID, mutedID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// IsMuted reports whether muterID has muted mutedID
+func IsMuted(repo *sql.DB, ctx context.Context, muterID string, mutedID string) (bool, error) {
+	var muted bool
+	err := repo.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM user_mutes WHERE muter_id = ? AND muted_id = ?)`,
+		muterID, mutedID).Scan(&muted)
+	return muted, err
+}
+
+// GetMutedUsers returns the users muterID has muted, most recent first
+func GetMutedUsers(repo *sql.DB, ctx context.Context, muterID string) ([]models.PublicUser, error) {
+	return getRelatedUsers(repo, ctx, `
+		SELECT u.id, u.username, u.created_at, u.avatar_type, u.avatar_url
+		FROM user_mutes m
+		JOIN users u ON u.id = m.muted_id
+		WHERE m.muter_id = ?
+		ORDER BY m.created_at DESC`, muterID)
+}
+
+// GetMutedUserIDs returns the set of users muterID has muted
+func GetMutedUserIDs(repo *sql.DB, ctx context.Context, muterID string) (map[string]bool, error) {
+	rows, err := repo.QueryContext(ctx, `SELECT muted_id FROM user_mutes WHERE muter_id = ?`, muterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	muted := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		muted[id] = true
+	}
+	return muted, rows.Err()
+}
+
+func getRelatedUsers(repo *sql.DB, ctx context.Context, query string, userID string) ([]models.PublicUser, error) {
+	rows, err := repo.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.PublicUser{}
+	for rows.Next() {
+		var user models.PublicUser
+		if err := rows.Scan(&user.ID, &user.Username, &user.CreatedAt, &user.AvatarType, &user.AvatarURL); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
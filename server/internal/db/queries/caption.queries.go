@@ -0,0 +1,43 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Longest text, in characters, a submission can carry
+const (
+	MaxSubmissionTitleLength   = 80
+	MaxSubmissionCaptionLength = 500
+	MaxSubmissionAltTextLength = 1000
+)
+
+// SubmissionText is the optional text the artist adds to a drawing
+type SubmissionText struct {
+	Title   string
+	Caption string
+	AltText string
+}
+
+// GetSubmissionText returns the text of one of the user's submissions,
+// sql.ErrNoRows when it isn't theirs
+func GetSubmissionText(repo *sql.DB, ctx context.Context, submissionID string, userID string) (SubmissionText, error) {
+	var text SubmissionText
+	err := repo.QueryRowContext(ctx,
+		`SELECT title, caption, alt_text FROM user_submissions WHERE id = ? AND user_id = ?`,
+		submissionID, userID).Scan(&text.Title, &text.Caption, &text.AltText)
+	return text, err
+}
+
+// SetSubmissionText replaces the text of one of the user's submissions,
+// reporting whether it was theirs
+func SetSubmissionText(repo *sql.DB, ctx context.Context, submissionID string, userID string, text SubmissionText) (bool, error) {
+	result, err := repo.ExecContext(ctx,
+		`UPDATE user_submissions SET title = ?, caption = ?, alt_text = ? WHERE id = ? AND user_id = ?`,
+		text.Title, text.Caption, text.AltText, submissionID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
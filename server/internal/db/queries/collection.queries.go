@@ -0,0 +1,327 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/config"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/utils"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCollectionNotFound = errors.New("collection not found")
+	ErrFavoriteNotFound   = errors.New("only your own favorites can be added")
+	ErrDuplicateFavorite  = errors.New("a favorite can only be listed once")
+)
+
+const (
+	// Collections a user can have
+	MaxCollectionsPerUser = 50
+	// Longest collection name, in characters
+	MaxCollectionNameLength = 50
+)
+
+func CountCollections(repo *sql.DB, ctx context.Context, userID string) (int, error) {
+	var count int
+	err := repo.QueryRowContext(ctx, `SELECT COUNT(*) FROM favorite_collections WHERE user_id = ?`, userID).Scan(&count)
+	return count, err
+}
+
+func CreateCollection(repo *sql.DB, ctx context.Context, cfg *config.Config, userID string, name string, shared bool) (models.FavoriteCollection, error) {
+	collection := models.FavoriteCollection{ID: uuid.New().String(), Name: name, Items: []models.CollectionItem{}}
+	if shared {
+		code, err := generateInviteCode()
+		if err != nil {
+			return models.FavoriteCollection{}, err
+		}
+		setShareCode(cfg, &collection, code)
+	}
+
+	err := repo.QueryRowContext(ctx,
+		`INSERT INTO favorite_collections (id, user_id, name, share_code) VALUES (?, ?, ?, ?) RETURNING created_at`,
+		collection.ID, userID, name, collection.ShareCode).Scan(&collection.CreatedAt)
+	if err != nil {
+		return models.FavoriteCollection{}, fmt.Errorf("error creating collection: %w", err)
+	}
+	return collection, nil
+}
+
+// GetCollections returns the user's collections with their items, oldest
+// collection first
+func GetCollections(repo *sql.DB, ctx context.Context, cfg *config.Config, userID string) ([]models.FavoriteCollection, error) {
+	rows, err := repo.QueryContext(ctx,
+		`SELECT id, name, share_code, created_at FROM favorite_collections WHERE user_id = ? ORDER BY created_at ASC`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching collections of %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	collections := []models.FavoriteCollection{}
+	for rows.Next() {
+		var collection models.FavoriteCollection
+		var shareCode sql.NullString
+		if err := rows.Scan(&collection.ID, &collection.Name, &shareCode, &collection.CreatedAt); err != nil {
+			return nil, err
+		}
+		if shareCode.Valid {
+			setShareCode(cfg, &collection, shareCode.String)
+		}
+		collections = append(collections, collection)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := loadCollectionItems(repo, ctx, cfg, collections, false); err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// GetCollection returns one of the user's collections with its items
+func GetCollection(repo *sql.DB, ctx context.Context, cfg *config.Config, userID string, collectionID string) (models.FavoriteCollection, error) {
+	var collection models.FavoriteCollection
+	var shareCode sql.NullString
+	err := repo.QueryRowContext(ctx,
+		`SELECT id, name, share_code, created_at FROM favorite_collections WHERE id = ? AND user_id = ?`,
+		collectionID, userID).Scan(&collection.ID, &collection.Name, &shareCode, &collection.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.FavoriteCollection{}, ErrCollectionNotFound
+	}
+	if err != nil {
+		return models.FavoriteCollection{}, err
+	}
+	if shareCode.Valid {
+		setShareCode(cfg, &collection, shareCode.String)
+	}
+
+	collections := []models.FavoriteCollection{collection}
+	if err := loadCollectionItems(repo, ctx, cfg, collections, false); err != nil {
+		return models.FavoriteCollection{}, err
+	}
+	return collections[0], nil
+}
+
+// GetSharedCollection returns the collection shared with the code as anyone
+// sees it. Drawings that are private, hidden by moderation or of today's
+// prompt are left out.
+func GetSharedCollection(repo *sql.DB, ctx context.Context, cfg *config.Config, code string) (models.FavoriteCollection, error) {
+	var collection models.FavoriteCollection
+	owner := models.PublicUser{}
+	err := repo.QueryRowContext(ctx, `
+		SELECT fc.id, fc.name, fc.created_at, u.id, u.username, u.created_at, u.avatar_type, u.avatar_url
+		FROM favorite_collections fc
+		JOIN users u ON u.id = fc.user_id
+		WHERE fc.share_code = ?`,
+		code).Scan(&collection.ID, &collection.Name, &collection.CreatedAt,
+		&owner.ID, &owner.Username, &owner.CreatedAt, &owner.AvatarType, &owner.AvatarURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.FavoriteCollection{}, ErrCollectionNotFound
+	}
+	if err != nil {
+		return models.FavoriteCollection{}, err
+	}
+	collection.User = &owner
+
+	collections := []models.FavoriteCollection{collection}
+	if err := loadCollectionItems(repo, ctx, cfg, collections, true); err != nil {
+		return models.FavoriteCollection{}, err
+	}
+	return collections[0], nil
+}
+
+func setShareCode(cfg *config.Config, collection *models.FavoriteCollection, code string) {
+	url := utils.GetCollectionUrl(cfg, code)
+	collection.ShareCode = &code
+	collection.ShareUrl = &url
+}
+
+// loadCollectionItems fills in the items of the collections in order. Shared
+// collections only get the items anyone may see, public drawings from before
+// today by an artist whose profile is open to everyone.
+func loadCollectionItems(repo *sql.DB, ctx context.Context, cfg *config.Config, collections []models.FavoriteCollection, shared bool) error {
+	if len(collections) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*models.FavoriteCollection, len(collections))
+	args := []interface{}{}
+	for i := range collections {
+		collections[i].Items = []models.CollectionItem{}
+		byID[collections[i].ID] = &collections[i]
+		args = append(args, collections[i].ID)
+	}
+
+	query := `
+		SELECT fci.collection_id, fci.favorite_id, fci.order_num, ` + submissionPreviewColumns + `
+		FROM favorite_collection_items fci
+		JOIN user_favorite_submissions f ON f.id = fci.favorite_id
+		JOIN user_submissions us ON us.id = f.submission_id
+		JOIN users u ON u.id = us.user_id
+		WHERE fci.collection_id IN (` + placeholders(len(collections)) + `)`
+	if shared {
+		query += ` AND us.hidden_at IS NULL AND us.visibility = 'public' AND u.profile_visibility = 'everyone' AND us.day < ?`
+		args = append(args, utils.GetFormattedDate(time.Now()))
+	}
+	query += ` ORDER BY fci.order_num ASC`
+
+	rows, err := repo.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("error fetching collection items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var collectionID string
+		var item models.CollectionItem
+		preview := &item.Submission
+		err := rows.Scan(&collectionID, &item.FavoriteID, &item.OrderNum, &preview.ID, &preview.CreatedAt,
+			&preview.User.ID, &preview.User.Username, &preview.User.CreatedAt, &preview.User.AvatarType, &preview.User.AvatarURL)
+		if err != nil {
+			return err
+		}
+		preview.ImageUrl = utils.GetImageUrl(cfg, utils.GetSubmissionFilename(preview.User.ID, preview.ID))
+		if collection, ok := byID[collectionID]; ok {
+			collection.Items = append(collection.Items, item)
+		}
+	}
+	return rows.Err()
+}
+
+// UpdateCollection renames the collection and starts or stops sharing it.
+// Nil fields are left alone. Sharing a collection again gives it a new code,
+// so links to it from before it stopped being shared stay dead.
+func UpdateCollection(repo *sql.DB, ctx context.Context, userID string, collectionID string, name *string, shared *bool) error {
+	var currentCode sql.NullString
+	err := repo.QueryRowContext(ctx,
+		`SELECT share_code FROM favorite_collections WHERE id = ? AND user_id = ?`,
+		collectionID, userID).Scan(&currentCode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCollectionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	shareCode := currentCode
+	if shared != nil {
+		if !*shared {
+			shareCode = sql.NullString{}
+		} else if !currentCode.Valid {
+			code, err := generateInviteCode()
+			if err != nil {
+				return err
+			}
+			shareCode = sql.NullString{String: code, Valid: true}
+		}
+	}
+
+	_, err = repo.ExecContext(ctx,
+		`UPDATE favorite_collections SET name = COALESCE(?, name), share_code = ? WHERE id = ? AND user_id = ?`,
+		name, shareCode, collectionID, userID)
+	return err
+}
+
+func DeleteCollection(repo *sql.DB, ctx context.Context, userID string, collectionID string) (err error) {
+	tx, err := repo.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM favorite_collections WHERE id = ? AND user_id = ?`, collectionID, userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrCollectionNotFound
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM favorite_collection_items WHERE collection_id = ?`, collectionID)
+	return err
+}
+
+// SetCollectionItems replaces the items of the collection with the given
+// favorites, in that order
+func SetCollectionItems(repo *sql.DB, ctx context.Context, userID string, collectionID string, favoriteIDs []string) (err error) {
+	tx, err := repo.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM favorite_collections WHERE id = ? AND user_id = ?)`,
+		collectionID, userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCollectionNotFound
+	}
+
+	owned, err := getFavoriteIDs(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(favoriteIDs))
+	for _, id := range favoriteIDs {
+		if !owned[id] {
+			return ErrFavoriteNotFound
+		}
+		if seen[id] {
+			return ErrDuplicateFavorite
+		}
+		seen[id] = true
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM favorite_collection_items WHERE collection_id = ?`, collectionID); err != nil {
+		return err
+	}
+	for i, id := range favoriteIDs {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO favorite_collection_items (collection_id, favorite_id, order_num) VALUES (?, ?, ?)`,
+			collectionID, id, i+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getFavoriteIDs returns the set of the user's favorite IDs
+func getFavoriteIDs(ctx context.Context, tx *sql.Tx, userID string) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM user_favorite_submissions WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
@@ -6,6 +6,7 @@ import (
 	"drawer-service-backend/internal/db/models"
 	"fmt"
 	"log"
+	"time"
 )
 
 func GetCommentReactions(repo *sql.DB, ctx context.Context, commentID string) ([]models.Reaction, error) {
@@ -98,3 +99,128 @@ func GetCommentReactionCounts(repo *sql.DB, ctx context.Context, commentID strin
 
 	return counts, nil
 }
+
+// NestComments moves replies under the comment they answer, keeping the
+// order of the flat list. Replies whose parent isn't in the list, because it
+// was hidden, are dropped with it.
+func NestComments(comments []models.Comment) []models.Comment {
+	replies := map[string][]models.Comment{}
+	for _, comment := range comments {
+		if comment.ParentID != "" {
+			replies[comment.ParentID] = append(replies[comment.ParentID], comment)
+		}
+	}
+
+	nested := []models.Comment{}
+	for _, comment := range comments {
+		if comment.ParentID != "" {
+			continue
+		}
+		comment.Replies = replies[comment.ID]
+		comment.ReplyCount = len(comment.Replies)
+		nested = append(nested, comment)
+	}
+	return nested
+}
+
+// GetReplyTarget returns the submission a comment belongs to, its author, and
+// the comment replies to it should hang off. Replies are one level deep, so
+// answering a reply continues its parent's thread.
+func GetReplyTarget(repo *sql.DB, ctx context.Context, commentID string) (submissionID string, authorID string, threadID string, err error) {
+	var parentID sql.NullString
+	err = repo.QueryRowContext(ctx,
+		`SELECT submission_id, user_id, parent_id FROM comments WHERE id = ? AND deleted_at IS NULL AND hidden_at IS NULL`,
+		commentID).Scan(&submissionID, &authorID, &parentID)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	threadID = commentID
+	if parentID.Valid {
+		threadID = parentID.String
+	}
+	return submissionID, authorID, threadID, nil
+}
+
+// CommentRef is who a comment belongs to, used to check who may change it
+type CommentRef struct {
+	SubmissionID      string
+	AuthorID          string
+	SubmissionOwnerID string
+}
+
+// GetCommentRef looks up a comment that hasn't been deleted
+func GetCommentRef(repo *sql.DB, ctx context.Context, commentID string) (CommentRef, error) {
+	var ref CommentRef
+	err := repo.QueryRowContext(ctx, `
+		SELECT c.submission_id, c.user_id, us.user_id
+		FROM comments c
+		JOIN user_submissions us ON us.id = c.submission_id
+		WHERE c.id = ? AND c.deleted_at IS NULL`,
+		commentID).Scan(&ref.SubmissionID, &ref.AuthorID, &ref.SubmissionOwnerID)
+	return ref, err
+}
+
+func UpdateCommentText(repo *sql.DB, ctx context.Context, commentID string, text string) (time.Time, error) {
+	var editedAt time.Time
+	err := repo.QueryRowContext(ctx,
+		`UPDATE comments SET text = ?, edited_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING edited_at`,
+		text, commentID).Scan(&editedAt)
+	return editedAt, err
+}
+
+// DeleteComment removes a comment with its reactions and mentions. A comment with replies
+// is blanked instead so the thread stays readable, and a blanked parent goes
+// away with its last reply.
+func DeleteComment(repo *sql.DB, ctx context.Context, commentID string) (err error) {
+	tx, err := repo.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	var parentID sql.NullString
+	var replies int
+	err = tx.QueryRowContext(ctx,
+		`SELECT parent_id, (SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id) FROM comments c WHERE c.id = ?`,
+		commentID).Scan(&parentID, &replies)
+	if err != nil {
+		return err
+	}
+
+	// Reactions have no foreign key to cascade through
+	if _, err = tx.ExecContext(ctx, `DELETE FROM reactions WHERE content_type = 'comment' AND content_id = ?`, commentID); err != nil {
+		return fmt.Errorf("error deleting comment reactions: %w", err)
+	}
+	// Blanked comments drop their mentions too
+	if _, err = tx.ExecContext(ctx, `DELETE FROM comment_mentions WHERE comment_id = ?`, commentID); err != nil {
+		return fmt.Errorf("error deleting comment mentions: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM text_flags WHERE content_type = 'comment' AND content_id = ?`, commentID); err != nil {
+		return fmt.Errorf("error deleting comment text flag: %w", err)
+	}
+
+	if replies > 0 {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE comments SET text = '', edited_at = NULL, deleted_at = CURRENT_TIMESTAMP WHERE id = ?`, commentID)
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM comments WHERE id = ?`, commentID); err != nil {
+		return err
+	}
+	if parentID.Valid {
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM comments
+			WHERE id = ? AND deleted_at IS NOT NULL
+				AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = comments.id)`,
+			parentID.String)
+	}
+	return err
+}
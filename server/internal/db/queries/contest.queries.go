@@ -0,0 +1,232 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/config"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/utils"
+	"fmt"
+	"time"
+)
+
+// ContestWeek returns the first and last day of the contest week the day is
+// in. Weeks run from Monday to Sunday.
+func ContestWeek(day time.Time) (string, string) {
+	offset := (int(day.Weekday()) + 6) % 7
+	start := day.AddDate(0, 0, -offset)
+	return utils.GetFormattedDate(start), utils.GetFormattedDate(start.AddDate(0, 0, 6))
+}
+
+// VoteTarget is what's needed to check whether a drawing can be voted for
+type VoteTarget struct {
+	UserID string
+	Day    string
+	Kind   string
+}
+
+func GetVoteTarget(repo *sql.DB, ctx context.Context, submissionID string) (VoteTarget, error) {
+	var target VoteTarget
+	err := repo.QueryRowContext(ctx,
+		`SELECT user_id, day, kind FROM user_submissions WHERE id = ?`,
+		submissionID).Scan(&target.UserID, &target.Day, &target.Kind)
+	return target, err
+}
+
+// CastContestVote records the user's vote for the day, replacing any vote
+// they already cast that day
+func CastContestVote(repo *sql.DB, ctx context.Context, voterID string, day string, submissionID string) error {
+	_, err := repo.ExecContext(ctx, `
+		INSERT INTO contest_votes (voter_id, day, submission_id) VALUES (?, ?, ?)
+		ON CONFLICT (voter_id, day) DO UPDATE SET submission_id = excluded.submission_id, created_at = CURRENT_TIMESTAMP`,
+		voterID, day, submissionID)
+	return err
+}
+
+// RemoveContestVote takes back the user's vote of the day for the submission,
+// reporting whether there was one
+func RemoveContestVote(repo *sql.DB, ctx context.Context, voterID string, day string, submissionID string) (bool, error) {
+	result, err := repo.ExecContext(ctx,
+		`DELETE FROM contest_votes WHERE voter_id = ? AND day = ? AND submission_id = ?`,
+		voterID, day, submissionID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetContestVotes returns the votes the user cast between two days, oldest
+// first
+func GetContestVotes(repo *sql.DB, ctx context.Context, voterID string, startDay string, endDay string) ([]models.ContestVote, error) {
+	rows, err := repo.QueryContext(ctx, `
+		SELECT day, submission_id, created_at FROM contest_votes
+		WHERE voter_id = ? AND day BETWEEN ? AND ?
+		ORDER BY day ASC`,
+		voterID, startDay, endDay)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := []models.ContestVote{}
+	for rows.Next() {
+		var vote models.ContestVote
+		if err := rows.Scan(&vote.Day, &vote.SubmissionID, &vote.CreatedAt); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+	return votes, rows.Err()
+}
+
+// GetUntalliedContestWeeks lists the weeks before the given one that have
+// votes but haven't been tallied yet, oldest first
+func GetUntalliedContestWeeks(repo *sql.DB, ctx context.Context, beforeWeek string) ([]string, error) {
+	rows, err := repo.QueryContext(ctx, `
+		SELECT DISTINCT date(v.day, '-6 days', 'weekday 1') AS week
+		FROM contest_votes v
+		WHERE v.day < ?
+			AND NOT EXISTS (SELECT 1 FROM contest_tallies t WHERE t.week = date(v.day, '-6 days', 'weekday 1'))
+		ORDER BY week ASC`,
+		beforeWeek)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var weeks []string
+	for rows.Next() {
+		var week string
+		if err := rows.Scan(&week); err != nil {
+			return nil, err
+		}
+		weeks = append(weeks, week)
+	}
+	return weeks, rows.Err()
+}
+
+// TallyContestWeek picks the winner of every friend circle for the week
+// starting on the given Monday and marks the week as tallied. A circle is a
+// user and their friends, only votes cast by members for drawings by members
+// count, and ties go to the drawing that was made first. Drawings that were
+// since made private or hidden by moderation can't win. Returns the IDs of
+// the users who won in at least one circle, or none when the week was already
+// tallied, so winners are only awarded once however many servers run this.
+func TallyContestWeek(repo *sql.DB, ctx context.Context, week string) (winners []string, err error) {
+	start, err := time.Parse(utils.DateFormat, week)
+	if err != nil {
+		return nil, err
+	}
+	end := utils.GetFormattedDate(start.AddDate(0, 0, 6))
+
+	tx, err := repo.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	// Claiming the week first means a second tally of it adds nothing
+	result, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO contest_tallies (week) VALUES (?)`, week)
+	if err != nil {
+		return nil, fmt.Errorf("error marking contest week %s as tallied: %w", week, err)
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil || claimed == 0 {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		WITH members (circle_id, user_id) AS (
+			SELECT id, id FROM users
+			UNION ALL
+			SELECT user1, user2 FROM friendships WHERE state = 'accepted'
+			UNION ALL
+			SELECT user2, user1 FROM friendships WHERE state = 'accepted'
+		),
+		tally AS (
+			SELECT voters.circle_id, us.id AS submission_id, us.user_id, us.created_at, COUNT(*) AS votes
+			FROM contest_votes v
+			JOIN user_submissions us ON us.id = v.submission_id
+			JOIN members voters ON voters.user_id = v.voter_id
+			JOIN members artists ON artists.circle_id = voters.circle_id AND artists.user_id = us.user_id
+			WHERE v.day BETWEEN ? AND ? AND us.hidden_at IS NULL AND us.visibility != 'private'
+			GROUP BY voters.circle_id, us.id
+		),
+		ranked AS (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY circle_id ORDER BY votes DESC, created_at ASC) AS place
+			FROM tally
+		)
+		INSERT OR IGNORE INTO contest_wins (week, circle_id, user_id, submission_id, votes)
+		SELECT ?, circle_id, user_id, submission_id, votes FROM ranked WHERE place = 1`,
+		week, end, week)
+	if err != nil {
+		return nil, fmt.Errorf("error tallying contest week %s: %w", week, err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT DISTINCT user_id FROM contest_wins WHERE week = ?`, week)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID string
+		if err = rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		winners = append(winners, userID)
+	}
+	return winners, rows.Err()
+}
+
+// GetContestWins lists the user's winning drawings the viewer can see, newest
+// week first
+func GetContestWins(repo *sql.DB, ctx context.Context, cfg *config.Config, userID string, viewerID string) ([]models.ContestWin, error) {
+	query := `
+		SELECT cw.week, MAX(cw.votes), COUNT(*), ` + submissionPreviewColumns + `
+		FROM contest_wins cw
+		JOIN user_submissions us ON us.id = cw.submission_id
+		JOIN users u ON u.id = us.user_id
+		WHERE cw.user_id = ? AND ` + SubmissionVisibleTo("us") + `
+		GROUP BY cw.week, cw.submission_id
+		ORDER BY cw.week DESC, MAX(cw.votes) DESC`
+	args := append([]interface{}{userID}, SubmissionVisibleToArgs(viewerID)...)
+
+	rows, err := repo.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching contest wins of %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	wins := []models.ContestWin{}
+	for rows.Next() {
+		var win models.ContestWin
+		preview := &win.Submission
+		err := rows.Scan(&win.Week, &win.Votes, &win.Circles, &preview.ID, &preview.CreatedAt,
+			&preview.User.ID, &preview.User.Username, &preview.User.CreatedAt, &preview.User.AvatarType, &preview.User.AvatarURL)
+		if err != nil {
+			return nil, err
+		}
+		preview.ImageUrl = utils.GetImageUrl(cfg, utils.GetSubmissionFilename(preview.User.ID, preview.ID))
+		wins = append(wins, win)
+	}
+	return wins, rows.Err()
+}
+
+// CalculateContestWinCount counts the weeks the user won in at least one
+// friend circle
+func CalculateContestWinCount(repo *sql.DB, ctx context.Context, userId string) (int, error) {
+	var count int
+	err := repo.QueryRowContext(ctx, `SELECT COUNT(DISTINCT week) FROM contest_wins WHERE user_id = ?`, userId).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
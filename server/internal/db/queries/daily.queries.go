@@ -22,7 +22,7 @@ type CheckUserSubmittedTodayParams struct {
 }
 
 func CheckUserSubmittedToday(repo *sql.DB, ctx context.Context, userID string) (bool, error) {
-	checkQuery := `SELECT EXISTS(SELECT 1 FROM user_submissions WHERE user_id = ? AND day = ?)`
+	checkQuery := `SELECT EXISTS(SELECT 1 FROM user_submissions WHERE user_id = ? AND day = ? AND kind = 'daily')`
 	var hasSubmitted bool
 
 	todayStr := utils.GetFormattedDate(time.Now())
@@ -32,8 +32,35 @@ func CheckUserSubmittedToday(repo *sql.DB, ctx context.Context, userID string) (
 	return hasSubmitted, err
 }
 
+type SubmissionHash struct {
+	SubmissionID string
+	Hash         string
+}
+
+// GetRecentSubmissionHashes returns the perceptual hashes of a user's
+// submissions made on or after the given day
+func GetRecentSubmissionHashes(repo *sql.DB, ctx context.Context, userID string, sinceDay string) ([]SubmissionHash, error) {
+	query := `SELECT id, phash FROM user_submissions WHERE user_id = ? AND day >= ? AND phash IS NOT NULL ORDER BY day DESC`
+	rows, err := repo.QueryContext(ctx, query, userID, sinceDay)
+	if err != nil {
+		return nil, fmt.Errorf("error querying submission hashes for user %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	hashes := []SubmissionHash{}
+	for rows.Next() {
+		var hash SubmissionHash
+		if err := rows.Scan(&hash.SubmissionID, &hash.Hash); err != nil {
+			return nil, fmt.Errorf("error scanning submission hash: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, rows.Err()
+}
+
 func GetDailyPrompt(repo *sql.DB, ctx context.Context, dateStr string) (models.DailyPrompt, error) {
-	query := `SELECT dp.day, dp.colors, dp.prompt, dp.created_by, u.username, u.email, u.created_at, u.avatar_type, u.avatar_url
+	query := `SELECT dp.day, dp.colors, dp.prompt, dp.palette_mode, dp.created_by, u.username, u.email, u.created_at, u.avatar_type, u.avatar_url
 		FROM daily_prompts dp
 		LEFT JOIN users u ON dp.created_by = u.id
 		WHERE day = ?`
@@ -50,7 +77,7 @@ func GetDailyPrompt(repo *sql.DB, ctx context.Context, dateStr string) (models.D
 		avatarURL     sql.NullString
 	)
 
-	err := row.Scan(&prompt.Day, &colorsJSON, &prompt.Prompt, &userID, &username, &email, &userCreatedAt, &avatarType, &avatarURL)
+	err := row.Scan(&prompt.Day, &colorsJSON, &prompt.Prompt, &prompt.PaletteMode, &userID, &username, &email, &userCreatedAt, &avatarType, &avatarURL)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.DailyPrompt{}, sql.ErrNoRows
@@ -89,15 +116,34 @@ func GetDailyPrompt(repo *sql.DB, ctx context.Context, dateStr string) (models.D
 }
 
 type InsertSubmissionRecordParams struct {
-	UserID string
-	Day    string
+	UserID           string
+	Day              string
+	PaletteCompliant *bool // nil when the image was not analyzed
+	PHash            *string
+	// Set when the image matches one of the user's recent submissions
+	DuplicateOf       *string
+	DuplicateDistance *int
+	// Set when the submission answered a group's prompt
+	GroupID    *string
+	Visibility string
+	// Set for remixes, which don't take up the day's slot
+	RemixOf *string
+	Text    SubmissionText
 }
 
 func InsertSubmissionRecord(repo *sql.DB, ctx context.Context, params InsertSubmissionRecordParams) (string, error) {
 	submissionId := uuid.New().String()
 
-	insertQuery := `INSERT INTO user_submissions (id, user_id, day) VALUES (?, ?, ?) returning id`
-	_, err := repo.ExecContext(ctx, insertQuery, submissionId, params.UserID, params.Day)
+	kind := SubmissionKindDaily
+	if params.RemixOf != nil {
+		kind = SubmissionKindRemix
+	}
+
+	insertQuery := `INSERT INTO user_submissions (id, user_id, day, palette_compliant, phash, duplicate_of, duplicate_distance, group_id, visibility, kind, remix_of, title, caption, alt_text)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) returning id`
+	_, err := repo.ExecContext(ctx, insertQuery, submissionId, params.UserID, params.Day, params.PaletteCompliant,
+		params.PHash, params.DuplicateOf, params.DuplicateDistance, params.GroupID, params.Visibility, kind, params.RemixOf,
+		params.Text.Title, params.Text.Caption, params.Text.AltText)
 
 	return submissionId, err
 }
@@ -154,7 +200,7 @@ func GenerateAndInsertDailyPrompt(repo *sql.DB, ctx context.Context, dateStr str
 func GetFuturePrompts(repo *sql.DB, ctx context.Context) ([]models.DailyPrompt, error) {
 	today := utils.GetFormattedDate(time.Now())
 
-	query := `SELECT dp.day, dp.colors, dp.prompt, dp.created_by, u.username, u.email, u.created_at, u.avatar_type, u.avatar_url
+	query := `SELECT dp.day, dp.colors, dp.prompt, dp.palette_mode, dp.created_by, u.username, u.email, u.created_at, u.avatar_type, u.avatar_url
 	FROM daily_prompts dp
 	LEFT JOIN users u ON dp.created_by = u.id
 	WHERE day >= ? ORDER BY day ASC`
@@ -177,7 +223,7 @@ func GetFuturePrompts(repo *sql.DB, ctx context.Context) ([]models.DailyPrompt,
 			avatarURL     sql.NullString
 		)
 
-		err := rows.Scan(&prompt.Day, &colorsJSON, &prompt.Prompt, &userID, &username, &email, &userCreatedAt, &avatarType, &avatarURL)
+		err := rows.Scan(&prompt.Day, &colorsJSON, &prompt.Prompt, &prompt.PaletteMode, &userID, &username, &email, &userCreatedAt, &avatarType, &avatarURL)
 		if err != nil {
 			log.Printf("Error scanning future prompt row AAA: %v", err)
 			continue
@@ -212,14 +258,14 @@ func GetFuturePrompts(repo *sql.DB, ctx context.Context) ([]models.DailyPrompt,
 }
 
 // CreateDailyPrompt creates a new daily prompt for a specific day
-func CreateDailyPrompt(repo *sql.DB, ctx context.Context, day string, prompt string, colors []string) error {
+func CreateDailyPrompt(repo *sql.DB, ctx context.Context, day string, prompt string, colors []string, paletteMode string) error {
 	colorsJSON, err := json.Marshal(colors)
 	if err != nil {
 		return fmt.Errorf("error marshaling colors to JSON: %w", err)
 	}
 
-	query := `INSERT INTO daily_prompts (day, colors, prompt) VALUES (?, ?, ?)`
-	_, err = repo.ExecContext(ctx, query, day, string(colorsJSON), prompt)
+	query := `INSERT INTO daily_prompts (day, colors, prompt, palette_mode) VALUES (?, ?, ?, ?)`
+	_, err = repo.ExecContext(ctx, query, day, string(colorsJSON), prompt, paletteMode)
 	if err != nil {
 		return fmt.Errorf("error creating daily prompt for %s: %w", day, err)
 	}
@@ -228,14 +274,14 @@ func CreateDailyPrompt(repo *sql.DB, ctx context.Context, day string, prompt str
 }
 
 // UpdateDailyPrompt updates an existing daily prompt for a specific day
-func UpdateDailyPrompt(repo *sql.DB, ctx context.Context, day string, prompt string, colors []string, createdById *string) error {
+func UpdateDailyPrompt(repo *sql.DB, ctx context.Context, day string, prompt string, colors []string, createdById *string, paletteMode string) error {
 	colorsJSON, err := json.Marshal(colors)
 	if err != nil {
 		return fmt.Errorf("error marshaling colors to JSON: %w", err)
 	}
 
-	query := `UPDATE daily_prompts SET colors = ?, prompt = ?, created_by = ? WHERE day = ?`
-	result, err := repo.ExecContext(ctx, query, string(colorsJSON), prompt, createdById, day)
+	query := `UPDATE daily_prompts SET colors = ?, prompt = ?, created_by = ?, palette_mode = ? WHERE day = ?`
+	result, err := repo.ExecContext(ctx, query, string(colorsJSON), prompt, createdById, paletteMode, day)
 	if err != nil {
 		return fmt.Errorf("error updating daily prompt for %s: %w", day, err)
 	}
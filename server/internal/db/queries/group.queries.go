@@ -0,0 +1,417 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/config"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	GroupRoleOwner  = "owner"
+	GroupRoleMember = "member"
+)
+
+var (
+	ErrGroupNotFound  = errors.New("group not found")
+	ErrAlreadyInGroup = errors.New("user is already a member of the group")
+	ErrNoDailyPrompt  = errors.New("no daily prompt for the day")
+)
+
+func CreateGroup(repo *sql.DB, ctx context.Context, ownerID string, name string, description string) (group models.Group, err error) {
+	code, err := generateInviteCode()
+	if err != nil {
+		return models.Group{}, err
+	}
+
+	tx, err := repo.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Group{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	group = models.Group{
+		ID:          uuid.New().String(),
+		Name:        name,
+		Description: description,
+		InviteCode:  code,
+		Role:        GroupRoleOwner,
+		MemberCount: 1,
+	}
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO groups (id, name, description, invite_code, created_by) VALUES (?, ?, ?, ?, ?) RETURNING created_at`,
+		group.ID, name, description, code, ownerID).Scan(&group.CreatedAt)
+	if err != nil {
+		return models.Group{}, fmt.Errorf("error creating group: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO group_members (group_id, user_id, role) VALUES (?, ?, ?)`, group.ID, ownerID, GroupRoleOwner)
+	if err != nil {
+		return models.Group{}, fmt.Errorf("error adding group owner: %w", err)
+	}
+	return group, nil
+}
+
+// GetUserGroups returns the groups the user belongs to, oldest membership first
+func GetUserGroups(repo *sql.DB, ctx context.Context, userID string) ([]models.Group, error) {
+	query := `
+		SELECT g.id, g.name, g.description, g.invite_code, gm.role, g.created_at,
+			(SELECT COUNT(*) FROM group_members WHERE group_id = g.id)
+		FROM group_members gm
+		JOIN groups g ON g.id = gm.group_id
+		WHERE gm.user_id = ?
+		ORDER BY gm.joined_at ASC`
+	rows, err := repo.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching groups for user %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	groups := []models.Group{}
+	for rows.Next() {
+		var group models.Group
+		if err := rows.Scan(&group.ID, &group.Name, &group.Description, &group.InviteCode, &group.Role, &group.CreatedAt, &group.MemberCount); err != nil {
+			return nil, fmt.Errorf("error scanning group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// GetGroup returns a group with its members. Groups are private, so it
+// returns ErrGroupNotFound unless the user is a member.
+func GetGroup(repo *sql.DB, ctx context.Context, groupID string, userID string) (models.Group, error) {
+	var group models.Group
+	err := repo.QueryRowContext(ctx, `
+		SELECT g.id, g.name, g.description, g.invite_code, gm.role, g.created_at
+		FROM groups g
+		JOIN group_members gm ON gm.group_id = g.id AND gm.user_id = ?
+		WHERE g.id = ?`, userID, groupID).
+		Scan(&group.ID, &group.Name, &group.Description, &group.InviteCode, &group.Role, &group.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Group{}, ErrGroupNotFound
+	}
+	if err != nil {
+		return models.Group{}, fmt.Errorf("error fetching group %s: %w", groupID, err)
+	}
+
+	rows, err := repo.QueryContext(ctx, `
+		SELECT u.id, u.username, u.created_at, u.avatar_type, u.avatar_url, gm.role, gm.joined_at
+		FROM group_members gm
+		JOIN users u ON u.id = gm.user_id
+		WHERE gm.group_id = ?
+		ORDER BY gm.role = 'owner' DESC, u.username ASC`, groupID)
+	if err != nil {
+		return models.Group{}, fmt.Errorf("error fetching members of group %s: %w", groupID, err)
+	}
+	defer rows.Close()
+
+	group.Members = []models.GroupMember{}
+	for rows.Next() {
+		var member models.GroupMember
+		if err := rows.Scan(&member.User.ID, &member.User.Username, &member.User.CreatedAt, &member.User.AvatarType, &member.User.AvatarURL, &member.Role, &member.JoinedAt); err != nil {
+			return models.Group{}, fmt.Errorf("error scanning group member: %w", err)
+		}
+		group.Members = append(group.Members, member)
+	}
+	group.MemberCount = len(group.Members)
+	return group, rows.Err()
+}
+
+// GetGroupRole returns the user's role in the group, or an empty string if
+// they aren't a member
+func GetGroupRole(repo *sql.DB, ctx context.Context, groupID string, userID string) (string, error) {
+	var role string
+	err := repo.QueryRowContext(ctx, `SELECT role FROM group_members WHERE group_id = ? AND user_id = ?`, groupID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+// JoinGroup adds the user to the group the invite code belongs to
+func JoinGroup(repo *sql.DB, ctx context.Context, code string, userID string) (string, error) {
+	var groupID string
+	err := repo.QueryRowContext(ctx, `SELECT id FROM groups WHERE invite_code = ?`, code).Scan(&groupID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrGroupNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	result, err := repo.ExecContext(ctx,
+		`INSERT OR IGNORE INTO group_members (group_id, user_id, role) VALUES (?, ?, ?)`,
+		groupID, userID, GroupRoleMember)
+	if err != nil {
+		return "", fmt.Errorf("error joining group %s: %w", groupID, err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return "", err
+	} else if affected == 0 {
+		return groupID, ErrAlreadyInGroup
+	}
+	return groupID, nil
+}
+
+// RemoveGroupMember removes a member from a group. Owners can't be removed,
+// the group has to be deleted instead.
+func RemoveGroupMember(repo *sql.DB, ctx context.Context, groupID string, userID string) (bool, error) {
+	result, err := repo.ExecContext(ctx,
+		`DELETE FROM group_members WHERE group_id = ? AND user_id = ? AND role = ?`,
+		groupID, userID, GroupRoleMember)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// DeleteGroup removes a group, its members and prompts. Submissions made for
+// its prompts fall back to showing the daily prompt.
+func DeleteGroup(repo *sql.DB, ctx context.Context, groupID string) (err error) {
+	tx, err := repo.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	for _, query := range []string{
+		`UPDATE user_submissions SET group_id = NULL WHERE group_id = ?`,
+		`DELETE FROM group_prompts WHERE group_id = ?`,
+		`DELETE FROM group_members WHERE group_id = ?`,
+		`DELETE FROM groups WHERE id = ?`,
+	} {
+		if _, err = tx.ExecContext(ctx, query, groupID); err != nil {
+			return fmt.Errorf("error deleting group %s: %w", groupID, err)
+		}
+	}
+	return nil
+}
+
+// ResetGroupInviteCode replaces the group's invite code so old links stop working
+func ResetGroupInviteCode(repo *sql.DB, ctx context.Context, groupID string) (string, error) {
+	code, err := generateInviteCode()
+	if err != nil {
+		return "", err
+	}
+	_, err = repo.ExecContext(ctx, `UPDATE groups SET invite_code = ? WHERE id = ?`, code, groupID)
+	return code, err
+}
+
+// GetGroupFeed returns the submissions members made for the group or for the
+// daily prompt between fromDay and toDay, newest first, leaving out users the
+// requester has blocked, been blocked by or muted and drawings hidden from the
+// requester
+func GetGroupFeed(repo *sql.DB, ctx context.Context, cfg *config.Config, groupID string, requesterID string, fromDay string, toDay string) ([]*models.UserPromptSubmission, error) {
+	query := `
+		SELECT us.id, us.day, COALESCE(gp.colors, dp.colors), COALESCE(gp.prompt, dp.prompt), us.created_at, us.visibility,
+			us.title, us.caption, us.alt_text, u.id, u.username, u.created_at, u.avatar_type, u.avatar_url
+		FROM user_submissions us
+		JOIN group_members gm ON gm.user_id = us.user_id AND gm.group_id = ?
+		JOIN users u ON u.id = us.user_id
+		JOIN daily_prompts dp ON us.day = dp.day
+		LEFT JOIN group_prompts gp ON gp.group_id = us.group_id AND gp.day = us.day
+		WHERE us.day BETWEEN ? AND ? AND us.kind = 'daily'
+			AND (us.group_id = ? OR us.group_id IS NULL)
+			AND NOT EXISTS (
+				SELECT 1 FROM user_blocks b
+				WHERE (b.blocker_id = ? AND b.blocked_id = us.user_id) OR (b.blocker_id = us.user_id AND b.blocked_id = ?)
+			)
+			AND NOT EXISTS (SELECT 1 FROM user_mutes m WHERE m.muter_id = ? AND m.muted_id = us.user_id)
+			AND ` + SubmissionVisibleTo("us") + `
+		ORDER BY us.day DESC, us.created_at DESC`
+	args := append([]interface{}{groupID, fromDay, toDay, groupID, requesterID, requesterID, requesterID}, SubmissionVisibleToArgs(requesterID)...)
+	rows, err := repo.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching feed for group %s: %w", groupID, err)
+	}
+	defer rows.Close()
+
+	feed := []*models.UserPromptSubmission{}
+	subMap := map[string]*models.UserPromptSubmission{}
+	for rows.Next() {
+		var (
+			submission models.UserPromptSubmission
+			colorsJSON string
+		)
+		err := rows.Scan(&submission.ID, &submission.Day, &colorsJSON, &submission.Prompt, &submission.CreatedAt, &submission.Visibility,
+			&submission.Title, &submission.Caption, &submission.AltText, &submission.User.ID, &submission.User.Username, &submission.User.CreatedAt, &submission.User.AvatarType, &submission.User.AvatarURL)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning group feed row: %w", err)
+		}
+		_ = json.Unmarshal([]byte(colorsJSON), &submission.Colors)
+		submission.ImageUrl = utils.GetImageUrl(cfg, utils.GetSubmissionFilename(submission.User.ID, submission.ID))
+		submission.Comments = []models.Comment{}
+		submission.Reactions = []models.Reaction{}
+		submission.Counts = []models.ReactionCount{}
+
+		feed = append(feed, &submission)
+		subMap[submission.ID] = &submission
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(feed) == 0 {
+		return feed, nil
+	}
+
+	args = make([]interface{}, 0, len(feed))
+	for _, submission := range feed {
+		args = append(args, submission.ID)
+	}
+	countRows, err := repo.QueryContext(ctx, `
+		SELECT r.content_id, r.reaction_id, COUNT(*) as count
+		FROM reactions r
+		JOIN user_submissions us ON us.id = r.content_id
+		WHERE r.content_type = 'submission' AND r.content_id IN (`+placeholders(len(args))+`)
+			AND `+NotBlockedBy("us.user_id", "r.user_id")+`
+		GROUP BY r.content_id, r.reaction_id
+		ORDER BY r.content_id, count DESC, r.reaction_id ASC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching reaction counts for group %s: %w", groupID, err)
+	}
+	defer countRows.Close()
+	for countRows.Next() {
+		var submissionID string
+		var count models.ReactionCount
+		if err := countRows.Scan(&submissionID, &count.ReactionID, &count.Count); err != nil {
+			return nil, fmt.Errorf("error scanning reaction count: %w", err)
+		}
+		subMap[submissionID].Counts = append(subMap[submissionID].Counts, count)
+	}
+	if err := countRows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := LockSpoilers(repo, ctx, requesterID, feed); err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+func GetGroupPrompt(repo *sql.DB, ctx context.Context, groupID string, day string) (models.DailyPrompt, error) {
+	prompts, err := getGroupPrompts(repo, ctx, `WHERE gp.group_id = ? AND gp.day = ?`, groupID, day)
+	if err != nil {
+		return models.DailyPrompt{}, err
+	}
+	if len(prompts) == 0 {
+		return models.DailyPrompt{}, sql.ErrNoRows
+	}
+	return prompts[0], nil
+}
+
+// GetGroupPrompts returns the group's prompts from the given day onwards
+func GetGroupPrompts(repo *sql.DB, ctx context.Context, groupID string, fromDay string) ([]models.DailyPrompt, error) {
+	return getGroupPrompts(repo, ctx, `WHERE gp.group_id = ? AND gp.day >= ?`, groupID, fromDay)
+}
+
+func getGroupPrompts(repo *sql.DB, ctx context.Context, where string, args ...interface{}) ([]models.DailyPrompt, error) {
+	query := `
+		SELECT gp.group_id, gp.day, gp.colors, gp.prompt, gp.palette_mode,
+			u.id, u.username, u.created_at, u.avatar_type, u.avatar_url
+		FROM group_prompts gp
+		LEFT JOIN users u ON u.id = gp.created_by
+		` + where + `
+		ORDER BY gp.day ASC`
+	rows, err := repo.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching group prompts: %w", err)
+	}
+	defer rows.Close()
+
+	prompts := []models.DailyPrompt{}
+	for rows.Next() {
+		var (
+			prompt                                  models.DailyPrompt
+			colorsJSON                              string
+			userID, username, avatarType, avatarURL sql.NullString
+			userCreatedAt                           sql.NullTime
+		)
+		err := rows.Scan(&prompt.GroupID, &prompt.Day, &colorsJSON, &prompt.Prompt, &prompt.PaletteMode,
+			&userID, &username, &userCreatedAt, &avatarType, &avatarURL)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning group prompt: %w", err)
+		}
+		if err := json.Unmarshal([]byte(colorsJSON), &prompt.Colors); err != nil {
+			return nil, fmt.Errorf("error parsing colors JSON: %w", err)
+		}
+		if userID.Valid {
+			prompt.CreatedBy = &models.User{
+				ID:         userID.String,
+				Username:   username.String,
+				CreatedAt:  userCreatedAt.Time,
+				AvatarType: avatarType.String,
+				AvatarURL:  avatarURL.String,
+			}
+		}
+		prompts = append(prompts, prompt)
+	}
+	return prompts, rows.Err()
+}
+
+// SetGroupPrompt creates or replaces the group's prompt for a day. Drawings
+// are read through the day's daily prompt, so days without one return
+// ErrNoDailyPrompt.
+func SetGroupPrompt(repo *sql.DB, ctx context.Context, groupID string, day string, prompt string, colors []string, paletteMode string, createdBy string) error {
+	colorsJSON, err := json.Marshal(colors)
+	if err != nil {
+		return fmt.Errorf("error marshaling colors: %w", err)
+	}
+
+	result, err := repo.ExecContext(ctx, `
+		INSERT INTO group_prompts (group_id, day, prompt, colors, palette_mode, created_by)
+		SELECT ?, ?, ?, ?, ?, ?
+		WHERE EXISTS (SELECT 1 FROM daily_prompts WHERE day = ?)
+		ON CONFLICT (group_id, day) DO UPDATE SET
+			prompt = excluded.prompt,
+			colors = excluded.colors,
+			palette_mode = excluded.palette_mode,
+			created_by = excluded.created_by`,
+		groupID, day, prompt, string(colorsJSON), paletteMode, createdBy, day)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		return ErrNoDailyPrompt
+	}
+	return err
+}
+
+func DeleteGroupPrompt(repo *sql.DB, ctx context.Context, groupID string, day string) (bool, error) {
+	result, err := repo.ExecContext(ctx, `DELETE FROM group_prompts WHERE group_id = ? AND day = ?`, groupID, day)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GroupPromptHasSubmissions reports whether anyone already drew the group's
+// prompt for a day, after which it can no longer be changed
+func GroupPromptHasSubmissions(repo *sql.DB, ctx context.Context, groupID string, day string) (bool, error) {
+	var exists bool
+	err := repo.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM user_submissions WHERE group_id = ? AND day = ?)`,
+		groupID, day).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,224 @@
+package queries
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"drawer-service-backend/internal/db/models"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInviteCodeNotFound = errors.New("invite code not found")
+	ErrInviteCodeExpired  = errors.New("invite code has expired or been used up")
+	ErrInviteCodeOwn      = errors.New("cannot redeem your own invite code")
+	ErrInviteBlocked      = errors.New("a block exists between the users")
+	ErrAlreadyFriends     = errors.New("users are already friends")
+)
+
+// Letters and digits that can't be mistaken for one another when read aloud
+// or typed from a screenshot
+const inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const inviteCodeLength = 8
+
+func generateInviteCode() (string, error) {
+	randomBytes := make([]byte, inviteCodeLength)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", fmt.Errorf("failed to generate invite code: %w", err)
+	}
+	code := make([]byte, inviteCodeLength)
+	for i, b := range randomBytes {
+		code[i] = inviteCodeAlphabet[int(b)%len(inviteCodeAlphabet)]
+	}
+	return string(code), nil
+}
+
+func CreateInviteCode(repo *sql.DB, ctx context.Context, inviterID string, expiresAt *time.Time, maxUses *int) (models.InviteCode, error) {
+	code, err := generateInviteCode()
+	if err != nil {
+		return models.InviteCode{}, err
+	}
+
+	invite := models.InviteCode{Code: code, ExpiresAt: expiresAt, MaxUses: maxUses}
+	err = repo.QueryRowContext(ctx,
+		`INSERT INTO invite_codes (code, inviter_id, expires_at, max_uses) VALUES (?, ?, ?, ?) RETURNING created_at`,
+		code, inviterID, expiresAt, maxUses).Scan(&invite.CreatedAt)
+	if err != nil {
+		return models.InviteCode{}, fmt.Errorf("error creating invite code: %w", err)
+	}
+	return invite, nil
+}
+
+func GetInviteCodes(repo *sql.DB, ctx context.Context, inviterID string) ([]models.InviteCode, error) {
+	rows, err := repo.QueryContext(ctx,
+		`SELECT code, expires_at, max_uses, uses, created_at FROM invite_codes WHERE inviter_id = ? ORDER BY created_at DESC`,
+		inviterID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching invite codes: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []models.InviteCode{}
+	for rows.Next() {
+		var invite models.InviteCode
+		var expiresAt sql.NullTime
+		var maxUses sql.NullInt64
+		if err := rows.Scan(&invite.Code, &expiresAt, &maxUses, &invite.Uses, &invite.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning invite code: %w", err)
+		}
+		if expiresAt.Valid {
+			invite.ExpiresAt = &expiresAt.Time
+		}
+		if maxUses.Valid {
+			n := int(maxUses.Int64)
+			invite.MaxUses = &n
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
+
+// DeleteInviteCode revokes one of the inviter's codes. Referrals already made
+// through it are kept.
+func DeleteInviteCode(repo *sql.DB, ctx context.Context, inviterID string, code string) (bool, error) {
+	result, err := repo.ExecContext(ctx, `DELETE FROM invite_codes WHERE code = ? AND inviter_id = ?`, code, inviterID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetInviteCodeInviter returns who created a code that can still be redeemed
+func GetInviteCodeInviter(repo *sql.DB, ctx context.Context, code string) (*models.User, error) {
+	var inviterID string
+	var expiresAt sql.NullTime
+	var maxUses sql.NullInt64
+	var uses int
+	err := repo.QueryRowContext(ctx,
+		`SELECT inviter_id, expires_at, max_uses, uses FROM invite_codes WHERE code = ?`,
+		code).Scan(&inviterID, &expiresAt, &maxUses, &uses)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInviteCodeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !inviteCodeUsable(expiresAt, maxUses, uses) {
+		return nil, ErrInviteCodeExpired
+	}
+	return GetUserByID(repo, ctx, inviterID)
+}
+
+func inviteCodeUsable(expiresAt sql.NullTime, maxUses sql.NullInt64, uses int) bool {
+	if expiresAt.Valid && !time.Now().Before(expiresAt.Time) {
+		return false
+	}
+	return !maxUses.Valid || int64(uses) < maxUses.Int64
+}
+
+// RedeemInviteCode makes the user friends with the code's creator, replacing
+// any pending invitation between them. Only a code redeemed while signing up
+// counts as a referral, and only the first one for the new user. It returns
+// the inviter's ID and whether a referral was recorded.
+func RedeemInviteCode(repo *sql.DB, ctx context.Context, code string, userID string, newUser bool) (inviterID string, referred bool, err error) {
+	tx, err := repo.BeginTx(ctx, nil)
+	if err != nil {
+		return "", false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	var expiresAt sql.NullTime
+	var maxUses sql.NullInt64
+	var uses int
+	err = tx.QueryRowContext(ctx,
+		`SELECT inviter_id, expires_at, max_uses, uses FROM invite_codes WHERE code = ?`,
+		code).Scan(&inviterID, &expiresAt, &maxUses, &uses)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, ErrInviteCodeNotFound
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if !inviteCodeUsable(expiresAt, maxUses, uses) {
+		return "", false, ErrInviteCodeExpired
+	}
+	if inviterID == userID {
+		return "", false, ErrInviteCodeOwn
+	}
+
+	var blocked bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS(
+			SELECT 1 FROM user_blocks
+			WHERE (blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)
+		)`,
+		inviterID, userID, userID, inviterID).Scan(&blocked)
+	if err != nil {
+		return "", false, err
+	}
+	if blocked {
+		return "", false, ErrInviteBlocked
+	}
+
+	// Determine user1 and user2 (user1 > user2)
+	user1, user2 := inviterID, userID
+	if user1 < user2 {
+		user1, user2 = user2, user1
+	}
+
+	var state string
+	err = tx.QueryRowContext(ctx, `SELECT state FROM friendships WHERE user1 = ? AND user2 = ?`, user1, user2).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO friendships (user1, user2, state, inviter_id) VALUES (?, ?, 'accepted', ?)`,
+			user1, user2, inviterID)
+	} else if err == nil && state == "accepted" {
+		return "", false, ErrAlreadyFriends
+	} else if err == nil {
+		// A pending invitation in either direction is accepted by the code
+		_, err = tx.ExecContext(ctx, `UPDATE friendships SET state = 'accepted' WHERE user1 = ? AND user2 = ?`, user1, user2)
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("error creating friendship: %w", err)
+	}
+
+	// The checks above read the code without locking it, so the use is only
+	// counted while the code is still usable. Otherwise a concurrent
+	// redemption took the last use.
+	claimed, err := tx.ExecContext(ctx,
+		`UPDATE invite_codes SET uses = uses + 1
+		WHERE code = ? AND (max_uses IS NULL OR uses < max_uses) AND (expires_at IS NULL OR expires_at > ?)`,
+		code, time.Now().UTC())
+	if err != nil {
+		return "", false, err
+	}
+	used, err := claimed.RowsAffected()
+	if err != nil {
+		return "", false, err
+	}
+	if used == 0 {
+		return "", false, ErrInviteCodeExpired
+	}
+	if !newUser {
+		return inviterID, false, nil
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`INSERT OR IGNORE INTO referrals (invitee_id, inviter_id, code) VALUES (?, ?, ?)`,
+		userID, inviterID, code)
+	if err != nil {
+		return "", false, fmt.Errorf("error recording referral: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	return inviterID, affected > 0, err
+}
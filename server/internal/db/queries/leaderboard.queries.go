@@ -0,0 +1,153 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/db/models"
+	"time"
+)
+
+// GetLeaderboardMembers returns the user and their friends. Friends who hide
+// their profile from everyone are left out, and so are friends who hide their
+// streak when the leaderboard ranks streaks.
+func GetLeaderboardMembers(repo *sql.DB, ctx context.Context, userID string, streaks bool) ([]models.User, error) {
+	query := `
+		SELECT u.id, u.username, u.created_at, u.avatar_type, u.avatar_url
+		FROM users u
+		WHERE u.id = ? OR (
+			EXISTS (SELECT 1 FROM friendships f WHERE f.state = 'accepted'
+				AND ((f.user1 = ? AND f.user2 = u.id) OR (f.user2 = ? AND f.user1 = u.id)))
+			AND u.profile_visibility != 'nobody'
+			AND (? = 0 OR u.show_streak = 1))`
+
+	rows, err := repo.QueryContext(ctx, query, userID, userID, userID, streaks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.CreatedAt, &user.AvatarType, &user.AvatarURL); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// GetFreshCalculatedStats returns the saved value of a stat for each of the
+// users whose value was calculated within maxAge. Users missing from the map
+// need theirs recalculated.
+func GetFreshCalculatedStats(repo *sql.DB, ctx context.Context, userIDs []string, statType string, maxAge time.Duration) (map[string]int, error) {
+	stats := make(map[string]int)
+	if len(userIDs) == 0 {
+		return stats, nil
+	}
+
+	query := `
+		SELECT user_id, stat_value
+		FROM user_stat_calculations
+		WHERE stat_type = ? AND last_updated_at >= ? AND user_id IN (` + placeholders(len(userIDs)) + `)`
+	args := []interface{}{statType, time.Now().Add(-maxAge)}
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+
+	return scanUserCounts(repo.QueryContext(ctx, query, args...))
+}
+
+// CountDrawingsSince counts the daily drawings each user made on or after the
+// given day
+func CountDrawingsSince(repo *sql.DB, ctx context.Context, userIDs []string, since string) (map[string]int, error) {
+	query := `
+		SELECT user_id, COUNT(*)
+		FROM user_submissions
+		WHERE kind = 'daily' AND day >= ? AND user_id IN (` + placeholders(len(userIDs)) + `)
+		GROUP BY user_id`
+	return scanUserCounts(repo.QueryContext(ctx, query, userCountArgs(since, userIDs)...))
+}
+
+// CountCommentsSince counts the comments each user wrote on or after the
+// given day
+func CountCommentsSince(repo *sql.DB, ctx context.Context, userIDs []string, since string) (map[string]int, error) {
+	query := `
+		SELECT user_id, COUNT(*)
+		FROM comments
+		WHERE date(created_at) >= ? AND user_id IN (` + placeholders(len(userIDs)) + `)
+		GROUP BY user_id`
+	return scanUserCounts(repo.QueryContext(ctx, query, userCountArgs(since, userIDs)...))
+}
+
+// CountReactionsReceivedSince counts the reactions other users left on each
+// user's drawings and comments on or after the given day. An empty since
+// counts them all.
+func CountReactionsReceivedSince(repo *sql.DB, ctx context.Context, userIDs []string, since string) (map[string]int, error) {
+	query := `
+		SELECT owner_id, COUNT(*)
+		FROM (
+			SELECT us.user_id AS owner_id, r.user_id AS reactor_id, r.created_at
+			FROM reactions r
+			JOIN user_submissions us ON r.content_type = 'submission' AND us.id = r.content_id
+			UNION ALL
+			SELECT c.user_id, r.user_id, r.created_at
+			FROM reactions r
+			JOIN comments c ON r.content_type = 'comment' AND CAST(c.id AS TEXT) = r.content_id
+		)
+		WHERE reactor_id != owner_id AND date(created_at) >= ? AND owner_id IN (` + placeholders(len(userIDs)) + `)
+		GROUP BY owner_id`
+	return scanUserCounts(repo.QueryContext(ctx, query, userCountArgs(since, userIDs)...))
+}
+
+// GetDrawingDaysSince returns the days each user made a daily drawing on or
+// after the given day, oldest first
+func GetDrawingDaysSince(repo *sql.DB, ctx context.Context, userIDs []string, since string) (map[string][]string, error) {
+	query := `
+		SELECT user_id, day
+		FROM user_submissions
+		WHERE kind = 'daily' AND day >= ? AND user_id IN (` + placeholders(len(userIDs)) + `)
+		ORDER BY day ASC`
+
+	rows, err := repo.QueryContext(ctx, query, userCountArgs(since, userIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := make(map[string][]string)
+	for rows.Next() {
+		var userID, day string
+		if err := rows.Scan(&userID, &day); err != nil {
+			return nil, err
+		}
+		days[userID] = append(days[userID], day)
+	}
+	return days, rows.Err()
+}
+
+func userCountArgs(since string, userIDs []string) []interface{} {
+	args := []interface{}{since}
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+	return args
+}
+
+func scanUserCounts(rows *sql.Rows, err error) (map[string]int, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var userID string
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, err
+		}
+		counts[userID] = count
+	}
+	return counts, rows.Err()
+}
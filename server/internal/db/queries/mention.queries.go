@@ -0,0 +1,165 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/mentions"
+	"fmt"
+	"strings"
+)
+
+// ResolveMentions matches parsed mentions against the commenter's friends,
+// ignoring case unless two friends' names only differ by it. Mentions of
+// anyone else are dropped, as are users past mentions.MaxPerComment.
+func ResolveMentions(repo *sql.DB, ctx context.Context, userID string, parsed []mentions.Mention) ([]models.Mention, error) {
+	resolved := []models.Mention{}
+	if len(parsed) == 0 {
+		return resolved, nil
+	}
+
+	args := []interface{}{userID, userID}
+	for _, mention := range parsed {
+		args = append(args, strings.ToLower(mention.Username))
+	}
+	rows, err := repo.QueryContext(ctx, `
+		SELECT u.id, u.username
+		FROM users u
+		JOIN friendships f ON f.state = 'accepted'
+			AND ((f.user1 = ? AND f.user2 = u.id) OR (f.user2 = ? AND f.user1 = u.id))
+		WHERE lower(u.username) IN (`+placeholders(len(parsed))+`)`,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving mentions: %w", err)
+	}
+	defer rows.Close()
+
+	byName := map[string]models.User{}
+	byLowerName := map[string][]models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Username); err != nil {
+			return nil, err
+		}
+		byName[user.Username] = user
+		lower := strings.ToLower(user.Username)
+		byLowerName[lower] = append(byLowerName[lower], user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	mentioned := map[string]bool{}
+	for _, mention := range parsed {
+		user, ok := byName[mention.Username]
+		if !ok {
+			matches := byLowerName[strings.ToLower(mention.Username)]
+			if len(matches) != 1 {
+				continue
+			}
+			user = matches[0]
+		}
+		if !mentioned[user.ID] && len(mentioned) >= mentions.MaxPerComment {
+			continue
+		}
+		mentioned[user.ID] = true
+
+		resolved = append(resolved, models.Mention{
+			UserID:   user.ID,
+			Username: user.Username,
+			Start:    mention.Start,
+			Length:   mention.Length,
+		})
+	}
+	return resolved, nil
+}
+
+// SetCommentMentions replaces the mentions stored for a comment and returns
+// the users that weren't mentioned in it before.
+func SetCommentMentions(repo *sql.DB, ctx context.Context, commentID string, mentioned []models.Mention) (added []string, err error) {
+	tx, err := repo.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, `SELECT DISTINCT user_id FROM comment_mentions WHERE comment_id = ?`, commentID)
+	if err != nil {
+		return nil, err
+	}
+	previous := map[string]bool{}
+	for rows.Next() {
+		var userID string
+		if err = rows.Scan(&userID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		previous[userID] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM comment_mentions WHERE comment_id = ?`, commentID); err != nil {
+		return nil, fmt.Errorf("error clearing mentions: %w", err)
+	}
+	for _, mention := range mentioned {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO comment_mentions (comment_id, user_id, start, length) VALUES (?, ?, ?, ?)`,
+			commentID, mention.UserID, mention.Start, mention.Length)
+		if err != nil {
+			return nil, fmt.Errorf("error storing mention of %s: %w", mention.UserID, err)
+		}
+		if !previous[mention.UserID] {
+			previous[mention.UserID] = true
+			added = append(added, mention.UserID)
+		}
+	}
+	return added, nil
+}
+
+// AttachMentions fills in the mentions of each comment in one query
+func AttachMentions(repo *sql.DB, ctx context.Context, comments []*models.Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	byID := map[string]*models.Comment{}
+	args := make([]interface{}, 0, len(comments))
+	for _, comment := range comments {
+		comment.Mentions = []models.Mention{}
+		byID[comment.ID] = comment
+		args = append(args, comment.ID)
+	}
+
+	rows, err := repo.QueryContext(ctx, `
+		SELECT cm.comment_id, cm.user_id, u.username, cm.start, cm.length
+		FROM comment_mentions cm
+		JOIN users u ON u.id = cm.user_id
+		WHERE cm.comment_id IN (`+placeholders(len(args))+`)
+		ORDER BY cm.comment_id, cm.start`,
+		args...)
+	if err != nil {
+		return fmt.Errorf("error fetching comment mentions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var commentID string
+		var mention models.Mention
+		if err := rows.Scan(&commentID, &mention.UserID, &mention.Username, &mention.Start, &mention.Length); err != nil {
+			return err
+		}
+		if comment, ok := byID[commentID]; ok {
+			comment.Mentions = append(comment.Mentions, mention)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,113 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/db/models"
+	"errors"
+)
+
+// Who can see a user's profile
+const (
+	ProfileVisibilityEveryone = "everyone"
+	ProfileVisibilityFriends  = "friends"
+	ProfileVisibilityNobody   = "nobody"
+)
+
+// Who can see a submission besides its author
+const (
+	SubmissionVisibilityPrivate = "private"
+	SubmissionVisibilityFriends = "friends"
+	SubmissionVisibilityPublic  = "public"
+)
+
+func IsValidProfileVisibility(visibility string) bool {
+	switch visibility {
+	case ProfileVisibilityEveryone, ProfileVisibilityFriends, ProfileVisibilityNobody:
+		return true
+	}
+	return false
+}
+
+func IsValidSubmissionVisibility(visibility string) bool {
+	switch visibility {
+	case SubmissionVisibilityPrivate, SubmissionVisibilityFriends, SubmissionVisibilityPublic:
+		return true
+	}
+	return false
+}
+
+func GetPrivacySettings(repo *sql.DB, ctx context.Context, userID string) (models.PrivacySettings, error) {
+	var settings models.PrivacySettings
+	err := repo.QueryRowContext(ctx,
+		`SELECT profile_visibility, show_streak, show_achievements FROM users WHERE id = ?`,
+		userID).Scan(&settings.ProfileVisibility, &settings.ShowStreak, &settings.ShowAchievements)
+	return settings, err
+}
+
+func UpdatePrivacySettings(repo *sql.DB, ctx context.Context, userID string, settings models.PrivacySettings) error {
+	_, err := repo.ExecContext(ctx,
+		`UPDATE users SET profile_visibility = ?, show_streak = ?, show_achievements = ? WHERE id = ?`,
+		settings.ProfileVisibility, settings.ShowStreak, settings.ShowAchievements, userID)
+	return err
+}
+
+// CanViewProfile reports whether the viewer may see the user's drawings,
+// friends and stats according to the user's profile visibility
+func CanViewProfile(repo *sql.DB, ctx context.Context, userID string, viewerID string, visibility string) (bool, error) {
+	if userID == viewerID {
+		return true, nil
+	}
+	switch visibility {
+	case ProfileVisibilityEveryone:
+		return true, nil
+	case ProfileVisibilityFriends:
+		return AreFriends(repo, ctx, userID, viewerID)
+	}
+	return false, nil
+}
+
+// CanViewSubmission reports whether the submission exists and the viewer is
+// allowed to see it
+func CanViewSubmission(repo *sql.DB, ctx context.Context, submissionID string, viewerID string) (bool, error) {
+	var visible bool
+	args := append(SubmissionVisibleToArgs(viewerID), submissionID)
+	err := repo.QueryRowContext(ctx,
+		`SELECT `+SubmissionVisibleTo("us")+` FROM user_submissions us WHERE us.id = ?`,
+		args...).Scan(&visible)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return visible, err
+}
+
+// CanViewComment reports whether the comment is one the viewer gets shown on
+// its submission: not hidden by moderation, unless it's theirs, and not by
+// someone the submission's owner blocked. The submission itself is checked
+// with CanViewSubmission.
+func CanViewComment(repo *sql.DB, ctx context.Context, commentID string, viewerID string) (bool, error) {
+	var visible bool
+	err := repo.QueryRowContext(ctx,
+		`SELECT `+CommentVisibleTo("c")+` AND `+NotBlockedBy("us.user_id", "c.user_id")+`
+		FROM comments c
+		JOIN user_submissions us ON us.id = c.submission_id
+		WHERE c.id = ?`,
+		viewerID, commentID).Scan(&visible)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return visible, err
+}
+
+// SetSubmissionVisibility changes who can see one of the user's submissions.
+// It returns false when the user has no such submission.
+func SetSubmissionVisibility(repo *sql.DB, ctx context.Context, submissionID string, userID string, visibility string) (bool, error) {
+	result, err := repo.ExecContext(ctx,
+		`UPDATE user_submissions SET visibility = ? WHERE id = ? AND user_id = ?`,
+		visibility, submissionID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
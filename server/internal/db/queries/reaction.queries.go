@@ -0,0 +1,114 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/db/models"
+	"fmt"
+)
+
+// What a reaction type can be left on
+const (
+	ReactionAppliesToSubmission = "submission"
+	ReactionAppliesToComment    = "comment"
+	ReactionAppliesToBoth       = "both"
+)
+
+func IsValidReactionAppliesTo(appliesTo string) bool {
+	switch appliesTo {
+	case ReactionAppliesToSubmission, ReactionAppliesToComment, ReactionAppliesToBoth:
+		return true
+	}
+	return false
+}
+
+// ReactionAppliesTo reports whether the reaction type can be left on contentType
+func ReactionAppliesTo(reactionType models.ReactionType, contentType string) bool {
+	return reactionType.AppliesTo == ReactionAppliesToBoth || reactionType.AppliesTo == contentType
+}
+
+// Unlocked is worked out the same way as HasRewardUnlocked
+const reactionTypeColumns = `
+	rt.id, rt.emoji, rt.asset_url, rt.applies_to, COALESCE(rt.reward_id, ''), rt.active, rt.sort_order,
+	rt.reward_id IS NULL OR EXISTS (
+		SELECT 1 FROM reward_unlocks r
+		JOIN user_achievements ua ON ua.achievement_id = r.achievement_id AND ua.user_id = ?
+		WHERE r.id = rt.reward_id
+	)`
+
+func scanReactionType(row interface{ Scan(...interface{}) error }) (models.ReactionType, error) {
+	var reactionType models.ReactionType
+	err := row.Scan(&reactionType.ID, &reactionType.Emoji, &reactionType.AssetURL, &reactionType.AppliesTo,
+		&reactionType.RewardID, &reactionType.Active, &reactionType.SortOrder, &reactionType.Unlocked)
+	return reactionType, err
+}
+
+// GetReactionTypes lists the reaction catalog, with whether userID has
+// unlocked each reaction. Inactive reactions are only listed when asked for.
+func GetReactionTypes(repo *sql.DB, ctx context.Context, userID string, includeInactive bool) ([]models.ReactionType, error) {
+	query := `SELECT ` + reactionTypeColumns + ` FROM reaction_types rt`
+	if !includeInactive {
+		query += ` WHERE rt.active = 1`
+	}
+	query += ` ORDER BY rt.sort_order, rt.created_at`
+
+	rows, err := repo.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching reaction types: %w", err)
+	}
+	defer rows.Close()
+
+	reactionTypes := []models.ReactionType{}
+	for rows.Next() {
+		reactionType, err := scanReactionType(rows)
+		if err != nil {
+			return nil, err
+		}
+		reactionTypes = append(reactionTypes, reactionType)
+	}
+	return reactionTypes, rows.Err()
+}
+
+func GetReactionType(repo *sql.DB, ctx context.Context, reactionID string, userID string) (models.ReactionType, error) {
+	row := repo.QueryRowContext(ctx, `SELECT `+reactionTypeColumns+` FROM reaction_types rt WHERE rt.id = ?`, userID, reactionID)
+	return scanReactionType(row)
+}
+
+func CreateReactionType(repo *sql.DB, ctx context.Context, reactionType models.ReactionType) error {
+	_, err := repo.ExecContext(ctx, `
+		INSERT INTO reaction_types (id, emoji, asset_url, applies_to, reward_id, active, sort_order)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		reactionType.ID, reactionType.Emoji, reactionType.AssetURL, reactionType.AppliesTo,
+		sql.NullString{String: reactionType.RewardID, Valid: reactionType.RewardID != ""},
+		reactionType.Active, reactionType.SortOrder)
+	return err
+}
+
+func UpdateReactionType(repo *sql.DB, ctx context.Context, reactionType models.ReactionType) error {
+	_, err := repo.ExecContext(ctx, `
+		UPDATE reaction_types
+		SET emoji = ?, asset_url = ?, applies_to = ?, reward_id = ?, active = ?, sort_order = ?
+		WHERE id = ?`,
+		reactionType.Emoji, reactionType.AssetURL, reactionType.AppliesTo,
+		sql.NullString{String: reactionType.RewardID, Valid: reactionType.RewardID != ""},
+		reactionType.Active, reactionType.SortOrder, reactionType.ID)
+	return err
+}
+
+func RewardUnlockExists(repo *sql.DB, ctx context.Context, rewardID string) (bool, error) {
+	var exists bool
+	err := repo.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM reward_unlocks WHERE id = ?)`, rewardID).Scan(&exists)
+	return exists, err
+}
+
+// HasReacted reports whether the user has left this reaction on the content
+func HasReacted(repo *sql.DB, ctx context.Context, userID, contentType, contentID, reactionID string) (bool, error) {
+	var exists bool
+	err := repo.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM reactions
+			WHERE user_id = ? AND content_type = ? AND content_id = ? AND reaction_id = ?
+		)`,
+		userID, contentType, contentID, reactionID).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,131 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/config"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/utils"
+	"fmt"
+)
+
+// What a submission is. Users get one daily drawing per day and can remix
+// other drawings on top of it.
+const (
+	SubmissionKindDaily = "daily"
+	SubmissionKindRemix = "remix"
+)
+
+const (
+	// Remixes a user can make per day
+	MaxRemixesPerDay = 10
+	// How far up a remix chain the lineage is followed
+	maxLineageDepth = 50
+)
+
+// RemixTarget is what a remix inherits from the submission it answers
+type RemixTarget struct {
+	ID     string
+	UserID string
+	// Remixes count as drawings of the same prompt
+	Day     string
+	GroupID *string
+}
+
+func GetRemixTarget(repo *sql.DB, ctx context.Context, submissionID string) (RemixTarget, error) {
+	var target RemixTarget
+	var groupID sql.NullString
+	err := repo.QueryRowContext(ctx,
+		`SELECT id, user_id, day, group_id FROM user_submissions WHERE id = ?`,
+		submissionID).Scan(&target.ID, &target.UserID, &target.Day, &groupID)
+	if groupID.Valid {
+		target.GroupID = &groupID.String
+	}
+	return target, err
+}
+
+// CountRemixesOnDay counts the remixes the user made on the given day
+func CountRemixesOnDay(repo *sql.DB, ctx context.Context, userID string, day string) (int, error) {
+	var count int
+	err := repo.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM user_submissions
+		WHERE user_id = ? AND kind = 'remix' AND date(created_at) = ?`,
+		userID, day).Scan(&count)
+	return count, err
+}
+
+const submissionPreviewColumns = `us.id, us.created_at, u.id, u.username, u.created_at, u.avatar_type, u.avatar_url`
+
+func scanSubmissionPreview(rows *sql.Rows, cfg *config.Config) (models.SubmissionPreview, error) {
+	var preview models.SubmissionPreview
+	err := rows.Scan(&preview.ID, &preview.CreatedAt,
+		&preview.User.ID, &preview.User.Username, &preview.User.CreatedAt, &preview.User.AvatarType, &preview.User.AvatarURL)
+	preview.ImageUrl = utils.GetImageUrl(cfg, utils.GetSubmissionFilename(preview.User.ID, preview.ID))
+	return preview, err
+}
+
+// GetRemixes lists the remixes of a submission the viewer can see, oldest
+// first, leaving out users the viewer blocked
+func GetRemixes(repo *sql.DB, ctx context.Context, cfg *config.Config, submissionID string, viewerID string) ([]models.SubmissionPreview, error) {
+	query := `
+		SELECT ` + submissionPreviewColumns + `
+		FROM user_submissions us
+		JOIN users u ON u.id = us.user_id
+		WHERE us.remix_of = ? AND ` + NotBlockedBy("?", "us.user_id") + ` AND ` + SubmissionVisibleTo("us") + `
+		ORDER BY us.created_at ASC`
+	args := append([]interface{}{submissionID, viewerID}, SubmissionVisibleToArgs(viewerID)...)
+
+	rows, err := repo.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching remixes of %s: %w", submissionID, err)
+	}
+	defer rows.Close()
+
+	remixes := []models.SubmissionPreview{}
+	for rows.Next() {
+		preview, err := scanSubmissionPreview(rows, cfg)
+		if err != nil {
+			return nil, err
+		}
+		remixes = append(remixes, preview)
+	}
+	return remixes, rows.Err()
+}
+
+// GetRemixLineage follows a remix back up its chain, returning the
+// submissions it descends from that the viewer can see, starting with the
+// original drawing
+func GetRemixLineage(repo *sql.DB, ctx context.Context, cfg *config.Config, submissionID string, viewerID string) ([]models.SubmissionPreview, error) {
+	query := `
+		WITH RECURSIVE lineage (id, depth) AS (
+			SELECT remix_of, 1 FROM user_submissions WHERE id = ? AND remix_of IS NOT NULL
+			UNION ALL
+			SELECT parent.remix_of, lineage.depth + 1
+			FROM user_submissions parent
+			JOIN lineage ON parent.id = lineage.id
+			WHERE parent.remix_of IS NOT NULL AND lineage.depth < ?
+		)
+		SELECT ` + submissionPreviewColumns + `
+		FROM lineage
+		JOIN user_submissions us ON us.id = lineage.id
+		JOIN users u ON u.id = us.user_id
+		WHERE ` + NotBlockedBy("?", "us.user_id") + ` AND ` + SubmissionVisibleTo("us") + `
+		ORDER BY lineage.depth DESC`
+	args := append([]interface{}{submissionID, maxLineageDepth, viewerID}, SubmissionVisibleToArgs(viewerID)...)
+
+	rows, err := repo.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching lineage of %s: %w", submissionID, err)
+	}
+	defer rows.Close()
+
+	lineage := []models.SubmissionPreview{}
+	for rows.Next() {
+		preview, err := scanSubmissionPreview(rows, cfg)
+		if err != nil {
+			return nil, err
+		}
+		lineage = append(lineage, preview)
+	}
+	return lineage, rows.Err()
+}
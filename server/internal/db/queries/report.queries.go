@@ -0,0 +1,362 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/config"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/utils"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// What can be reported
+const (
+	ReportContentSubmission       = "submission"
+	ReportContentComment          = "comment"
+	ReportContentUser             = "user"
+	ReportContentPromptSuggestion = "prompt_suggestion"
+)
+
+// Why it was reported
+const (
+	ReportReasonSpam          = "spam"
+	ReportReasonHarassment    = "harassment"
+	ReportReasonHate          = "hate"
+	ReportReasonInappropriate = "inappropriate"
+	ReportReasonOther         = "other"
+)
+
+// What a moderator did about it
+const (
+	ReportActionDismiss = "dismiss"
+	ReportActionHide    = "hide"
+	ReportActionDelete  = "delete"
+	ReportActionWarn    = "warn"
+	ReportActionSuspend = "suspend"
+)
+
+const (
+	ReportStatusOpen     = "open"
+	ReportStatusResolved = "resolved"
+)
+
+func IsValidReportContentType(contentType string) bool {
+	switch contentType {
+	case ReportContentSubmission, ReportContentComment, ReportContentUser, ReportContentPromptSuggestion:
+		return true
+	}
+	return false
+}
+
+func IsValidReportReason(reason string) bool {
+	switch reason {
+	case ReportReasonSpam, ReportReasonHarassment, ReportReasonHate, ReportReasonInappropriate, ReportReasonOther:
+		return true
+	}
+	return false
+}
+
+func IsValidReportAction(action string) bool {
+	switch action {
+	case ReportActionDismiss, ReportActionHide, ReportActionDelete, ReportActionWarn, ReportActionSuspend:
+		return true
+	}
+	return false
+}
+
+// Tables of the content types that can be hidden. Users can't, a reported
+// username gets reset instead.
+var hideableTables = map[string]string{
+	ReportContentSubmission:       "user_submissions",
+	ReportContentComment:          "comments",
+	ReportContentPromptSuggestion: "prompt_suggestions",
+}
+
+func IsHideableContent(contentType string) bool {
+	_, ok := hideableTables[contentType]
+	return ok
+}
+
+// GetReportedContentOwner returns who a piece of content belongs to, or
+// sql.ErrNoRows when it doesn't exist. Prompt suggestions from deleted users
+// have no owner.
+func GetReportedContentOwner(repo *sql.DB, ctx context.Context, contentType string, contentID string) (string, error) {
+	var query string
+	switch contentType {
+	case ReportContentSubmission:
+		query = `SELECT user_id FROM user_submissions WHERE id = ?`
+	case ReportContentComment:
+		query = `SELECT user_id FROM comments WHERE id = ? AND deleted_at IS NULL`
+	case ReportContentUser:
+		query = `SELECT id FROM users WHERE id = ?`
+	case ReportContentPromptSuggestion:
+		query = `SELECT COALESCE(user_id, '') FROM prompt_suggestions WHERE id = ?`
+	default:
+		return "", fmt.Errorf("invalid content type: %s", contentType)
+	}
+
+	var ownerID string
+	err := repo.QueryRowContext(ctx, query, contentID).Scan(&ownerID)
+	return ownerID, err
+}
+
+// GetReportedOwner returns how many reports a piece of content has and who
+// it belonged to when reported, which still works once it's been deleted
+func GetReportedOwner(repo *sql.DB, ctx context.Context, contentType string, contentID string) (int, string, error) {
+	var count int
+	var ownerID string
+	err := repo.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(MAX(content_owner_id), '') FROM reports
+		WHERE content_type = ? AND content_id = ?`,
+		contentType, contentID).Scan(&count, &ownerID)
+	return count, ownerID, err
+}
+
+// CreateReport files a report, returning false when the reporter already
+// reported this content
+func CreateReport(repo *sql.DB, ctx context.Context, reporterID, contentType, contentID, ownerID, reason, details string) (bool, error) {
+	result, err := repo.ExecContext(ctx, `
+		INSERT OR IGNORE INTO reports (reporter_id, content_type, content_id, content_owner_id, reason, details)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		reporterID, contentType, contentID, sql.NullString{String: ownerID, Valid: ownerID != ""}, reason, details)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// CountOpenReports counts the distinct users with an open report of the content
+func CountOpenReports(repo *sql.DB, ctx context.Context, contentType string, contentID string) (int, error) {
+	var count int
+	err := repo.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT reporter_id) FROM reports
+		WHERE content_type = ? AND content_id = ? AND status = 'open'`,
+		contentType, contentID).Scan(&count)
+	return count, err
+}
+
+// SetContentHidden hides content from everyone but its author, or shows it
+// again. It returns false when the content was already in that state.
+func SetContentHidden(repo *sql.DB, ctx context.Context, contentType string, contentID string, hidden bool) (bool, error) {
+	table, ok := hideableTables[contentType]
+	if !ok {
+		return false, fmt.Errorf("%s content can't be hidden", contentType)
+	}
+
+	query := `UPDATE ` + table + ` SET hidden_at = NULL WHERE id = ? AND hidden_at IS NOT NULL`
+	if hidden {
+		query = `UPDATE ` + table + ` SET hidden_at = CURRENT_TIMESTAMP WHERE id = ? AND hidden_at IS NULL`
+	}
+	result, err := repo.ExecContext(ctx, query, contentID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// ReportFilter narrows down the moderation queue. Empty fields match anything.
+type ReportFilter struct {
+	Status      string
+	ContentType string
+	Reason      string
+}
+
+// GetReportQueue lists reported content matching the filter, most reported
+// first
+func GetReportQueue(repo *sql.DB, ctx context.Context, filter ReportFilter, cfg *config.Config) ([]models.ReportedContent, error) {
+	conditions := []string{"1 = 1"}
+	args := []interface{}{}
+	if filter.Status != "" {
+		conditions = append(conditions, "r.status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.ContentType != "" {
+		conditions = append(conditions, "r.content_type = ?")
+		args = append(args, filter.ContentType)
+	}
+	having := ""
+	if filter.Reason != "" {
+		having = "HAVING SUM(r.reason = ?) > 0"
+		args = append(args, filter.Reason)
+	}
+
+	// Grouped first, the latest report's row is joined back in to read its
+	// timestamp as a plain column
+	query := `
+		SELECT g.content_type, g.content_id, COALESCE(g.owner_id, ''), COALESCE(ou.username, ''),
+			CASE g.content_type
+				WHEN 'submission' THEN (SELECT NULLIF(TRIM(us.title || char(10) || us.caption || char(10) || us.alt_text, char(10)), '') FROM user_submissions us WHERE us.id = g.content_id)
+				WHEN 'comment' THEN (SELECT c.text FROM comments c WHERE c.id = g.content_id AND c.deleted_at IS NULL)
+				WHEN 'prompt_suggestion' THEN (SELECT ps.prompt FROM prompt_suggestions ps WHERE ps.id = g.content_id)
+				WHEN 'user' THEN (SELECT u.username FROM users u WHERE u.id = g.content_id)
+			END,
+			CASE g.content_type
+				WHEN 'submission' THEN (SELECT us.hidden_at IS NOT NULL FROM user_submissions us WHERE us.id = g.content_id)
+				WHEN 'comment' THEN (SELECT c.hidden_at IS NOT NULL FROM comments c WHERE c.id = g.content_id)
+				WHEN 'prompt_suggestion' THEN (SELECT ps.hidden_at IS NOT NULL FROM prompt_suggestions ps WHERE ps.id = g.content_id)
+			END,
+			g.report_count, g.reasons, g.status, lr.created_at
+		FROM (
+			SELECT r.content_type, r.content_id, MAX(r.content_owner_id) AS owner_id,
+				COUNT(DISTINCT r.reporter_id) AS report_count, GROUP_CONCAT(r.reason) AS reasons,
+				MIN(r.status) AS status, MAX(r.id) AS latest_id
+			FROM reports r
+			WHERE ` + strings.Join(conditions, " AND ") + `
+			GROUP BY r.content_type, r.content_id
+			` + having + `
+		) g
+		JOIN reports lr ON lr.id = g.latest_id
+		LEFT JOIN users ou ON ou.id = g.owner_id
+		ORDER BY g.report_count DESC, lr.created_at DESC
+		LIMIT 100`
+
+	rows, err := repo.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching report queue: %w", err)
+	}
+	defer rows.Close()
+
+	queue := []models.ReportedContent{}
+	for rows.Next() {
+		var item models.ReportedContent
+		var preview, reasons sql.NullString
+		var hidden sql.NullBool
+		err := rows.Scan(&item.ContentType, &item.ContentID, &item.OwnerID, &item.OwnerUsername, &preview, &hidden,
+			&item.ReportCount, &reasons, &item.Status, &item.LastReportedAt)
+		if err != nil {
+			return nil, err
+		}
+		item.Preview = preview.String
+		item.Hidden = hidden.Bool
+		item.Reasons = map[string]int{}
+		for _, reason := range strings.Split(reasons.String, ",") {
+			if reason != "" {
+				item.Reasons[reason]++
+			}
+		}
+		if item.ContentType == ReportContentSubmission {
+			item.ImageURL = utils.GetImageUrl(cfg, utils.GetSubmissionFilename(item.OwnerID, item.ContentID))
+		}
+		queue = append(queue, item)
+	}
+	return queue, rows.Err()
+}
+
+// GetReports lists the individual reports of a piece of content, newest first
+func GetReports(repo *sql.DB, ctx context.Context, contentType string, contentID string) ([]models.Report, error) {
+	rows, err := repo.QueryContext(ctx, `
+		SELECT r.id, r.reason, r.details, r.status, COALESCE(r.action, ''), r.resolved_at, r.created_at,
+			u.id, u.username, u.email, u.created_at, u.avatar_type, u.avatar_url
+		FROM reports r
+		JOIN users u ON u.id = r.reporter_id
+		WHERE r.content_type = ? AND r.content_id = ?
+		ORDER BY r.created_at DESC`,
+		contentType, contentID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []models.Report{}
+	for rows.Next() {
+		var report models.Report
+		var resolvedAt sql.NullTime
+		err := rows.Scan(&report.ID, &report.Reason, &report.Details, &report.Status, &report.Action, &resolvedAt, &report.CreatedAt,
+			&report.Reporter.ID, &report.Reporter.Username, &report.Reporter.Email, &report.Reporter.CreatedAt,
+			&report.Reporter.AvatarType, &report.Reporter.AvatarURL)
+		if err != nil {
+			return nil, err
+		}
+		if resolvedAt.Valid {
+			report.ResolvedAt = &resolvedAt.Time
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// ResolveReports closes the open reports of a piece of content with the
+// action a moderator took, returning how many there were
+func ResolveReports(repo *sql.DB, ctx context.Context, contentType string, contentID string, action string, moderatorID string) (int64, error) {
+	result, err := repo.ExecContext(ctx, `
+		UPDATE reports SET status = 'resolved', action = ?, resolved_by = ?, resolved_at = CURRENT_TIMESTAMP
+		WHERE content_type = ? AND content_id = ? AND status = 'open'`,
+		action, moderatorID, contentType, contentID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func WarnUser(repo *sql.DB, ctx context.Context, userID string) error {
+	_, err := repo.ExecContext(ctx, `UPDATE users SET warning_count = warning_count + 1 WHERE id = ?`, userID)
+	return err
+}
+
+func SuspendUser(repo *sql.DB, ctx context.Context, userID string, until time.Time) error {
+	_, err := repo.ExecContext(ctx, `UPDATE users SET suspended_until = ? WHERE id = ?`, until, userID)
+	return err
+}
+
+// ResetUsername replaces a reported username with one made from the user's
+// ID, which the user can change again
+func ResetUsername(repo *sql.DB, ctx context.Context, userID string) (string, error) {
+	username := strings.ReplaceAll(userID, "-", "")
+	if len(username) > 10 {
+		username = username[:10]
+	}
+	username = "user_" + username
+
+	_, err := repo.ExecContext(ctx, `UPDATE users SET username = ? WHERE id = ?`, username, userID)
+	return username, err
+}
+
+func DeletePromptSuggestion(repo *sql.DB, ctx context.Context, suggestionID string) error {
+	if _, err := repo.ExecContext(ctx, `DELETE FROM text_flags WHERE content_type = 'prompt_suggestion' AND content_id = ?`, suggestionID); err != nil {
+		return err
+	}
+	_, err := repo.ExecContext(ctx, `DELETE FROM prompt_suggestions WHERE id = ?`, suggestionID)
+	return err
+}
+
+// DeleteSubmission removes a submission along with its comments, reactions,
+// favorites, contest votes and wins and stroke log.
+func DeleteSubmission(repo *sql.DB, ctx context.Context, submissionID string) (err error) {
+	tx, err := repo.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	for _, query := range []string{
+		`DELETE FROM reactions WHERE content_type = 'comment' AND content_id IN (SELECT id FROM comments WHERE submission_id = ?)`,
+		`DELETE FROM comment_mentions WHERE comment_id IN (SELECT id FROM comments WHERE submission_id = ?)`,
+		`DELETE FROM text_flags WHERE content_type = 'comment' AND content_id IN (SELECT id FROM comments WHERE submission_id = ?)`,
+		`DELETE FROM comments WHERE submission_id = ?`,
+		`DELETE FROM reactions WHERE content_type = 'submission' AND content_id = ?`,
+		`DELETE FROM text_flags WHERE content_type = 'submission' AND content_id = ?`,
+		`DELETE FROM favorite_collection_items WHERE favorite_id IN (SELECT id FROM user_favorite_submissions WHERE submission_id = ?)`,
+		`DELETE FROM user_favorite_submissions WHERE submission_id = ?`,
+		`DELETE FROM submission_strokes WHERE submission_id = ?`,
+		`DELETE FROM contest_votes WHERE submission_id = ?`,
+		`DELETE FROM contest_wins WHERE submission_id = ?`,
+		`UPDATE user_submissions SET duplicate_of = NULL WHERE duplicate_of = ?`,
+		`UPDATE user_submissions SET remix_of = NULL WHERE remix_of = ?`,
+		`DELETE FROM user_submissions WHERE id = ?`,
+	} {
+		if _, err = tx.ExecContext(ctx, query, submissionID); err != nil {
+			return fmt.Errorf("error deleting submission %s: %w", submissionID, err)
+		}
+	}
+	return nil
+}
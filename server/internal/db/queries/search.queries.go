@@ -0,0 +1,174 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/config"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/utils"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// What a row of the search index holds, the source of a highlight
+const (
+	SearchSourcePrompt     = "prompt"
+	SearchSourceSubmission = "submission"
+	SearchSourceComment    = "comment"
+)
+
+const (
+	// Words of a search that are looked for, the rest are ignored
+	maxSearchTerms = 10
+	// Tokens around each highlight
+	snippetTokens = 16
+	// What snippet() puts around matched terms. They can't be typed into
+	// indexed text, so they're swapped for <mark> tags once the snippet is
+	// escaped.
+	snippetOpen  = "\x02"
+	snippetClose = "\x03"
+)
+
+var ErrEmptySearch = errors.New("search has no words to look for")
+
+// SearchSubmissions finds the submissions the viewer can see whose prompt,
+// title, caption, alt text or comments match the search, best match first.
+// Every word has to match and the last one may be the start of a word, so
+// results show up while typing. Drawings of today's prompt by others are left
+// out until the viewer has drawn theirs, and so are users and comments the
+// viewer can't see.
+func SearchSubmissions(repo *sql.DB, ctx context.Context, cfg *config.Config, viewerID string, search string, limit int, offset int) (models.SearchResponse, error) {
+	match := searchMatchQuery(search)
+	if match == "" {
+		return models.SearchResponse{}, ErrEmptySearch
+	}
+	today := utils.GetFormattedDate(time.Now())
+
+	// Prompts match every drawing of their day except the ones answering a
+	// group's own prompt. Only the page of submissions is picked in page, its
+	// highlights are joined back in after.
+	query := `
+		WITH hits AS (
+			SELECT r.content_type, r.content_id, bm25(search_index) AS score,
+				snippet(search_index, 0, char(2), char(3), '…', ` + fmt.Sprint(snippetTokens) + `) AS snippet
+			FROM search_index
+			JOIN search_index_rows r ON r.id = search_index.rowid
+			WHERE search_index MATCH ?
+		),
+		submission_hits AS (
+			SELECT us.id AS submission_id, h.content_type, '' AS comment_id, h.score, h.snippet
+			FROM hits h
+			JOIN user_submissions us ON h.content_type = 'submission' AND us.id = h.content_id
+			UNION ALL
+			SELECT us.id, h.content_type, '', h.score, h.snippet
+			FROM hits h
+			JOIN user_submissions us ON h.content_type = 'prompt' AND us.day = h.content_id
+			LEFT JOIN group_prompts gp ON gp.group_id = us.group_id AND gp.day = us.day
+			WHERE gp.group_id IS NULL
+			UNION ALL
+			SELECT c.submission_id, h.content_type, h.content_id, h.score, h.snippet
+			FROM hits h
+			JOIN comments c ON h.content_type = 'comment' AND CAST(c.id AS TEXT) = h.content_id
+			JOIN user_submissions us ON us.id = c.submission_id
+			WHERE c.deleted_at IS NULL AND ` + CommentVisibleTo("c") + `
+				AND ` + NotBlockedBy("us.user_id", "c.user_id") + `
+				AND ` + NotBlockedBy("?", "c.user_id") + `
+		),
+		page AS (
+			SELECT sh.submission_id, MIN(sh.score) AS score
+			FROM submission_hits sh
+			JOIN user_submissions us ON us.id = sh.submission_id
+			WHERE NOT (us.day = ? AND us.user_id != ?
+					AND NOT EXISTS (SELECT 1 FROM user_submissions mine WHERE mine.user_id = ? AND mine.day = ? AND mine.kind = 'daily'))
+				AND ` + NotBlockedBy("?", "us.user_id") + `
+				AND ` + SubmissionVisibleTo("us") + `
+			GROUP BY sh.submission_id
+			ORDER BY MIN(sh.score) ASC, MAX(us.created_at) DESC, sh.submission_id
+			LIMIT ? OFFSET ?
+		)
+		SELECT COALESCE(gp.prompt, dp.prompt), us.title, us.caption, sh.content_type, sh.comment_id, sh.snippet,
+			` + submissionPreviewColumns + `
+		FROM page p
+		JOIN user_submissions us ON us.id = p.submission_id
+		JOIN users u ON u.id = us.user_id
+		JOIN daily_prompts dp ON dp.day = us.day
+		LEFT JOIN group_prompts gp ON gp.group_id = us.group_id AND gp.day = us.day
+		JOIN submission_hits sh ON sh.submission_id = p.submission_id
+		ORDER BY p.score ASC, us.created_at DESC, us.id, sh.score ASC`
+
+	args := []interface{}{match, viewerID, viewerID, today, viewerID, viewerID, today, viewerID}
+	args = append(args, SubmissionVisibleToArgs(viewerID)...)
+	// One extra to tell whether there's another page
+	args = append(args, limit+1, offset)
+
+	rows, err := repo.QueryContext(ctx, query, args...)
+	if err != nil {
+		return models.SearchResponse{}, fmt.Errorf("error searching submissions: %w", err)
+	}
+	defer rows.Close()
+
+	response := models.SearchResponse{Results: []models.SearchResult{}}
+	for rows.Next() {
+		var result models.SearchResult
+		var highlight models.SearchHighlight
+		preview := &result.Submission
+		err := rows.Scan(&result.Prompt, &result.Title, &result.Caption, &highlight.Source, &highlight.CommentID, &highlight.Snippet,
+			&preview.ID, &preview.CreatedAt,
+			&preview.User.ID, &preview.User.Username, &preview.User.CreatedAt, &preview.User.AvatarType, &preview.User.AvatarURL)
+		if err != nil {
+			return models.SearchResponse{}, err
+		}
+		highlight.Snippet = markSnippet(highlight.Snippet)
+
+		last := len(response.Results) - 1
+		if last < 0 || response.Results[last].Submission.ID != preview.ID {
+			preview.ImageUrl = utils.GetImageUrl(cfg, utils.GetSubmissionFilename(preview.User.ID, preview.ID))
+			result.Highlights = []models.SearchHighlight{}
+			response.Results = append(response.Results, result)
+			last++
+		}
+		response.Results[last].Highlights = append(response.Results[last].Highlights, highlight)
+	}
+	if err := rows.Err(); err != nil {
+		return models.SearchResponse{}, err
+	}
+
+	if len(response.Results) > limit {
+		response.Results = response.Results[:limit]
+		next := offset + limit
+		response.NextOffset = &next
+	}
+	return response, nil
+}
+
+// searchMatchQuery turns what the user typed into an FTS5 query. Only the
+// words are kept and each is quoted, so nothing typed is read as FTS5 syntax.
+func searchMatchQuery(search string) string {
+	words := strings.FieldsFunc(search, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) > maxSearchTerms {
+		words = words[:maxSearchTerms]
+	}
+	if len(words) == 0 {
+		return ""
+	}
+
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = `"` + word + `"`
+	}
+	terms[len(terms)-1] += "*"
+	return strings.Join(terms, " ")
+}
+
+// markSnippet escapes a snippet for HTML and marks its matched terms. The
+// lines of a submission's title, caption and alt text are run together.
+func markSnippet(snippet string) string {
+	snippet = html.EscapeString(strings.Join(strings.Fields(snippet), " "))
+	return strings.NewReplacer(snippetOpen, "<mark>", snippetClose, "</mark>").Replace(snippet)
+}
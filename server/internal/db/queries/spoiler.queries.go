@@ -0,0 +1,51 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/utils"
+	"time"
+)
+
+// LockSpoilers locks other users' drawings of today's prompt until the viewer
+// has submitted their own, so nobody sees an answer before drawing it
+func LockSpoilers(repo *sql.DB, ctx context.Context, viewerID string, submissions []*models.UserPromptSubmission) error {
+	today := utils.GetFormattedDate(time.Now())
+	hasSpoilers := false
+	for _, submission := range submissions {
+		if submission.Day == today && submission.User.ID != viewerID {
+			hasSpoilers = true
+			break
+		}
+	}
+	if !hasSpoilers {
+		return nil
+	}
+
+	hasSubmittedToday, err := CheckUserSubmittedToday(repo, ctx, viewerID)
+	if err != nil || hasSubmittedToday {
+		return err
+	}
+
+	for _, submission := range submissions {
+		if submission.Day == today && submission.User.ID != viewerID {
+			submission.Lock()
+		}
+	}
+	return nil
+}
+
+// IsSpoiler reports whether the submission is locked for the viewer by
+// LockSpoilers
+func IsSpoiler(repo *sql.DB, ctx context.Context, submissionID string, viewerID string) (bool, error) {
+	today := utils.GetFormattedDate(time.Now())
+	var spoiler bool
+	err := repo.QueryRowContext(ctx, `
+		SELECT us.day = ? AND us.user_id != ?
+			AND NOT EXISTS (SELECT 1 FROM user_submissions mine WHERE mine.user_id = ? AND mine.day = ? AND mine.kind = 'daily')
+		FROM user_submissions us
+		WHERE us.id = ?`,
+		today, viewerID, viewerID, today, submissionID).Scan(&spoiler)
+	return spoiler, err
+}
@@ -170,7 +170,7 @@ func CalculateFriendCount(repo *sql.DB, ctx context.Context, userId string) (int
 	query := `
 		SELECT COUNT(*)
 		FROM friendships
-		WHERE user1 = ? OR user2 = ?
+		WHERE (user1 = ? OR user2 = ?)
 		AND state = 'accepted'
 	`
 
@@ -188,7 +188,25 @@ func CalculateSubmissionCount(repo *sql.DB, ctx context.Context, userId string)
 	query := `
 		SELECT COUNT(*)
 		FROM user_submissions
-		WHERE user_id = ?
+		WHERE user_id = ? AND kind = 'daily'
+	`
+
+	var count int
+	err := repo.QueryRowContext(ctx, query, userId).Scan(&count)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CalculatePalettePuristCount counts submissions that passed the palette check
+func CalculatePalettePuristCount(repo *sql.DB, ctx context.Context, userId string) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM user_submissions
+		WHERE user_id = ? AND palette_compliant = 1
 	`
 
 	var count int
@@ -201,6 +219,17 @@ func CalculateSubmissionCount(repo *sql.DB, ctx context.Context, userId string)
 	return count, nil
 }
 
+// CalculateReferralCount counts the users who joined through the user's
+// invite codes
+func CalculateReferralCount(repo *sql.DB, ctx context.Context, userId string) (int, error) {
+	var count int
+	err := repo.QueryRowContext(ctx, `SELECT COUNT(*) FROM referrals WHERE inviter_id = ?`, userId).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func GetIncompleteAchievements(repo *sql.DB, ctx context.Context, userId string) ([]models.Achievement, error) {
 	query := `
 		SELECT a.id, a.name, a.description, a.image_url, a.achievement_field, a.achievement_value, ua.created_at, r.id, r.name, r.description, r.created_at
@@ -354,7 +383,7 @@ func CalculateSubmissionActiveStreak(repo *sql.DB, ctx context.Context, userID s
 	var currentStreak int
 	rows, err := repo.QueryContext(ctx, `
 		SELECT day FROM user_submissions
-		WHERE user_id = ?
+		WHERE user_id = ? AND kind = 'daily'
 		ORDER BY day DESC
 	`, userID)
 	if err != nil {
@@ -412,7 +441,7 @@ func CalculateSubmissionMaxStreak(repo *sql.DB, ctx context.Context, userID stri
 	// and 'user_id' for filtering.
 	rows, err := repo.QueryContext(ctx, `
 		SELECT day FROM user_submissions
-		WHERE user_id = ?
+		WHERE user_id = ? AND kind = 'daily'
 		ORDER BY day ASC
 	`, userID)
 	if err != nil {
@@ -509,13 +538,27 @@ func HasRewardUnlocked(repo *sql.DB, ctx context.Context, userId string, rewardI
 }
 
 func InsertCalculatedStat(repo *sql.DB, ctx context.Context, userId string, statType string, statValue int) error {
-	query := `
-	INSERT OR IGNORE INTO user_stat_calculations (id, user_id, stat_type, stat_value, last_updated_at)
+	// There is no unique key on (user_id, stat_type) so update first and only
+	// insert when the stat hasn't been saved before
+	updateQuery := `
+	UPDATE user_stat_calculations SET stat_value = $1, last_updated_at = $2
+	WHERE user_id = $3 AND stat_type = $4
+	`
+
+	res, err := repo.ExecContext(ctx, updateQuery, statValue, time.Now(), userId, statType)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	insertQuery := `
+	INSERT INTO user_stat_calculations (id, user_id, stat_type, stat_value, last_updated_at)
 	VALUES ($1, $2, $3, $4, $5)
-	UPDATE user_stat_calculations SET user_id = $2, stat_type = $3, stat_value = $4, last_updated_at = $5
 	`
 
-	_, err := repo.ExecContext(ctx, query, uuid.New().String(), userId, statType, statValue, time.Now())
+	_, err = repo.ExecContext(ctx, insertQuery, uuid.New().String(), userId, statType, statValue, time.Now())
 	return err
 }
 
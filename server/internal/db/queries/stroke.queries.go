@@ -0,0 +1,63 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/db/models"
+	"encoding/json"
+	"fmt"
+)
+
+func InsertSubmissionStrokes(repo *sql.DB, ctx context.Context, submissionID string, data models.StrokeData) error {
+	strokeJSON, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error marshaling stroke data to JSON: %w", err)
+	}
+
+	query := `INSERT INTO submission_strokes (submission_id, stroke_data) VALUES (?, ?)`
+	_, err = repo.ExecContext(ctx, query, submissionID, string(strokeJSON))
+	if err != nil {
+		return fmt.Errorf("error inserting strokes for submission %s: %w", submissionID, err)
+	}
+
+	return nil
+}
+
+// GetSubmissionStrokes returns the stroke log for a submission along with the
+// prompt palette its color indexes refer to
+func GetSubmissionStrokes(repo *sql.DB, ctx context.Context, submissionID string) (models.SubmissionStrokes, error) {
+	query := `
+		SELECT ss.submission_id, ss.stroke_data, ss.created_at, COALESCE(gp.colors, dp.colors)
+		FROM submission_strokes ss
+		JOIN user_submissions us ON ss.submission_id = us.id
+		JOIN daily_prompts dp ON us.day = dp.day
+		LEFT JOIN group_prompts gp ON gp.group_id = us.group_id AND gp.day = us.day
+		WHERE ss.submission_id = ?`
+
+	var (
+		result     models.SubmissionStrokes
+		strokeJSON string
+		colorsJSON string
+		createdAt  sql.NullTime
+	)
+	err := repo.QueryRowContext(ctx, query, submissionID).Scan(&result.SubmissionID, &strokeJSON, &createdAt, &colorsJSON)
+	if err != nil {
+		return models.SubmissionStrokes{}, err
+	}
+
+	if err := json.Unmarshal([]byte(strokeJSON), &result.Strokes); err != nil {
+		return models.SubmissionStrokes{}, fmt.Errorf("error parsing stroke data JSON: %w", err)
+	}
+	if err := json.Unmarshal([]byte(colorsJSON), &result.Colors); err != nil {
+		return models.SubmissionStrokes{}, fmt.Errorf("error parsing colors JSON: %w", err)
+	}
+	result.CreatedAt = createdAt.Time
+
+	return result, nil
+}
+
+func HasSubmissionStrokes(repo *sql.DB, ctx context.Context, submissionID string) (bool, error) {
+	var exists bool
+	err := repo.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM submission_strokes WHERE submission_id = ?)`, submissionID).Scan(&exists)
+	return exists, err
+}
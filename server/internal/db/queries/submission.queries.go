@@ -3,7 +3,10 @@ package queries
 import (
 	"context"
 	"database/sql"
+	"drawer-service-backend/internal/config"
 	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/utils"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -25,7 +28,8 @@ func GetSubmissionReactions(repo *sql.DB, ctx context.Context, submissionID stri
 			u.avatar_url
 		FROM reactions r
 		JOIN users u ON r.user_id = u.id
-		WHERE r.content_type = 'submission' AND r.content_id = ?
+		JOIN user_submissions us ON us.id = r.content_id
+		WHERE r.content_type = 'submission' AND r.content_id = ? AND ` + NotBlockedBy("us.user_id", "r.user_id") + `
 		ORDER BY r.created_at ASC
 	`
 
@@ -73,7 +77,7 @@ func ToggleReaction(repo *sql.DB, ctx context.Context, userID, contentType, cont
 	case "submission":
 		contentExistsQuery = `SELECT 1 FROM user_submissions WHERE id = ?`
 	case "comment":
-		contentExistsQuery = `SELECT 1 FROM comments WHERE id = ?`
+		contentExistsQuery = `SELECT 1 FROM comments WHERE id = ? AND deleted_at IS NULL AND hidden_at IS NULL`
 	default:
 		return fmt.Errorf("invalid content type: %s", contentType)
 	}
@@ -129,8 +133,9 @@ func GetSubmissionReactionCounts(repo *sql.DB, ctx context.Context, submissionID
 		SELECT
 			reaction_id,
 			COUNT(*) as count
-		FROM reactions
-		WHERE content_type = 'submission' AND content_id = ?
+		FROM reactions r
+		JOIN user_submissions us ON us.id = r.content_id
+		WHERE content_type = 'submission' AND content_id = ? AND ` + NotBlockedBy("us.user_id", "r.user_id") + `
 		GROUP BY reaction_id
 		ORDER BY count DESC, reaction_id ASC
 	`
@@ -178,8 +183,12 @@ func ToggleFavoriteSubmission(repo *sql.DB, ctx context.Context, userID, submiss
 	var favID string
 	err = repo.QueryRowContext(ctx, "SELECT id FROM user_favorite_submissions WHERE user_id = ? AND submission_id = ?", userID, submissionID).Scan(&favID)
 	if err == nil {
-		// Already favorited, so remove
-		_, delErr := repo.ExecContext(ctx, "DELETE FROM user_favorite_submissions WHERE id = ?", favID)
+		// Already favorited, so remove it and take it out of collections
+		_, delErr := repo.ExecContext(ctx, "DELETE FROM favorite_collection_items WHERE favorite_id = ?", favID)
+		if delErr != nil {
+			return false, delErr
+		}
+		_, delErr = repo.ExecContext(ctx, "DELETE FROM user_favorite_submissions WHERE id = ?", favID)
 		if delErr != nil {
 			return false, delErr
 		}
@@ -230,3 +239,177 @@ func SwapFavoriteOrder(repo *sql.DB, ctx context.Context, userID, favID1, favID2
 	_, err = tx.ExecContext(ctx, `UPDATE user_favorite_submissions SET order_num = CASE WHEN id = ? THEN ? WHEN id = ? THEN ? END WHERE id IN (?, ?) AND user_id = ?`, favID1, order2, favID2, order1, favID1, favID2, userID)
 	return err
 }
+
+var ErrInvalidFavoriteOrder = errors.New("the order must list each of your favorites exactly once")
+
+// ReorderFavorites renumbers all of the user's favorites to follow the given
+// order, which must list every favorite once
+func ReorderFavorites(repo *sql.DB, ctx context.Context, userID string, favoriteIDs []string) (err error) {
+	tx, err := repo.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	owned, err := getFavoriteIDs(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	if len(favoriteIDs) != len(owned) {
+		return ErrInvalidFavoriteOrder
+	}
+	seen := make(map[string]bool, len(favoriteIDs))
+	for _, id := range favoriteIDs {
+		if !owned[id] || seen[id] {
+			return ErrInvalidFavoriteOrder
+		}
+		seen[id] = true
+	}
+
+	for i, id := range favoriteIDs {
+		_, err = tx.ExecContext(ctx, `UPDATE user_favorite_submissions SET order_num = ? WHERE id = ? AND user_id = ?`, i+1, id, userID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPaletteFlaggedSubmissions returns submissions that failed the palette
+// check on prompts running in flag mode, newest first
+func GetPaletteFlaggedSubmissions(repo *sql.DB, ctx context.Context, cfg *config.Config) ([]models.UserPromptSubmission, error) {
+	query := `
+		SELECT us.id, us.day, COALESCE(gp.colors, dp.colors), COALESCE(gp.prompt, dp.prompt), us.created_at,
+			u.id, u.username, u.email, u.created_at, u.avatar_type, u.avatar_url
+		FROM user_submissions us
+		JOIN daily_prompts dp ON us.day = dp.day
+		LEFT JOIN group_prompts gp ON gp.group_id = us.group_id AND gp.day = us.day
+		JOIN users u ON us.user_id = u.id
+		WHERE us.palette_compliant = 0 AND COALESCE(gp.palette_mode, dp.palette_mode) = 'flag'
+		ORDER BY us.created_at DESC
+		LIMIT 200`
+
+	rows, err := repo.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying palette flagged submissions: %w", err)
+	}
+	defer rows.Close()
+
+	submissions := []models.UserPromptSubmission{}
+	for rows.Next() {
+		var (
+			submission models.UserPromptSubmission
+			colorsJSON string
+		)
+		err := rows.Scan(&submission.ID, &submission.Day, &colorsJSON, &submission.Prompt, &submission.CreatedAt,
+			&submission.User.ID, &submission.User.Username, &submission.User.Email, &submission.User.CreatedAt,
+			&submission.User.AvatarType, &submission.User.AvatarURL)
+		if err != nil {
+			log.Printf("Error scanning palette flagged submission: %v", err)
+			continue
+		}
+
+		_ = json.Unmarshal([]byte(colorsJSON), &submission.Colors)
+		submission.ImageUrl = utils.GetImageUrl(cfg, utils.GetSubmissionFilename(submission.User.ID, submission.ID))
+		submission.Comments = []models.Comment{}
+		submission.Reactions = []models.Reaction{}
+		submission.Counts = []models.ReactionCount{}
+		submissions = append(submissions, submission)
+	}
+
+	return submissions, rows.Err()
+}
+
+// GetDuplicateSubmissionPairs returns submissions flagged as near-duplicates
+// alongside the earlier drawing they matched, newest first
+func GetDuplicateSubmissionPairs(repo *sql.DB, ctx context.Context, cfg *config.Config) ([]models.DuplicatePair, error) {
+	query := `
+		SELECT us.id, us.day, COALESCE(gp.prompt, dp.prompt), us.created_at, orig.id, orig.day, COALESCE(ogp.prompt, odp.prompt), orig.created_at, us.duplicate_distance,
+			u.id, u.username, u.email, u.created_at, u.avatar_type, u.avatar_url
+		FROM user_submissions us
+		JOIN user_submissions orig ON us.duplicate_of = orig.id
+		JOIN daily_prompts dp ON us.day = dp.day
+		LEFT JOIN group_prompts gp ON gp.group_id = us.group_id AND gp.day = us.day
+		JOIN daily_prompts odp ON orig.day = odp.day
+		LEFT JOIN group_prompts ogp ON ogp.group_id = orig.group_id AND ogp.day = orig.day
+		JOIN users u ON us.user_id = u.id
+		ORDER BY us.created_at DESC
+		LIMIT 200`
+
+	rows, err := repo.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying duplicate submissions: %w", err)
+	}
+	defer rows.Close()
+
+	pairs := []models.DuplicatePair{}
+	for rows.Next() {
+		var (
+			pair     models.DuplicatePair
+			user     models.User
+			distance sql.NullInt64
+		)
+		err := rows.Scan(&pair.Submission.ID, &pair.Submission.Day, &pair.Submission.Prompt, &pair.Submission.CreatedAt,
+			&pair.Original.ID, &pair.Original.Day, &pair.Original.Prompt, &pair.Original.CreatedAt, &distance,
+			&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.AvatarType, &user.AvatarURL)
+		if err != nil {
+			log.Printf("Error scanning duplicate submission pair: %v", err)
+			continue
+		}
+
+		pair.Distance = int(distance.Int64)
+		for _, sub := range []*models.UserPromptSubmission{&pair.Submission, &pair.Original} {
+			sub.User = user
+			sub.ImageUrl = utils.GetImageUrl(cfg, utils.GetSubmissionFilename(user.ID, sub.ID))
+			sub.Colors = []string{}
+			sub.Comments = []models.Comment{}
+			sub.Reactions = []models.Reaction{}
+			sub.Counts = []models.ReactionCount{}
+		}
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, rows.Err()
+}
+
+// GetUserSubmissionsBetween returns a user's submissions the viewer can see
+// from one day to another inclusive, ordered by day. Only the fields needed to
+// render them are filled in.
+func GetUserSubmissionsBetween(repo *sql.DB, ctx context.Context, userID string, viewerID string, fromDay string, toDay string) ([]models.UserPromptSubmission, error) {
+	query := `
+		SELECT us.id, us.day, COALESCE(gp.colors, dp.colors), COALESCE(gp.prompt, dp.prompt), us.created_at
+		FROM user_submissions us
+		JOIN daily_prompts dp ON us.day = dp.day
+		LEFT JOIN group_prompts gp ON gp.group_id = us.group_id AND gp.day = us.day
+		WHERE us.user_id = ? AND us.day BETWEEN ? AND ? AND us.kind = 'daily' AND ` + SubmissionVisibleTo("us") + `
+		ORDER BY us.day ASC`
+
+	args := append([]interface{}{userID, fromDay, toDay}, SubmissionVisibleToArgs(viewerID)...)
+	rows, err := repo.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying submissions for user %s from %s to %s: %w", userID, fromDay, toDay, err)
+	}
+	defer rows.Close()
+
+	submissions := []models.UserPromptSubmission{}
+	for rows.Next() {
+		var (
+			submission models.UserPromptSubmission
+			colorsJSON string
+		)
+		if err := rows.Scan(&submission.ID, &submission.Day, &colorsJSON, &submission.Prompt, &submission.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning submission: %w", err)
+		}
+		_ = json.Unmarshal([]byte(colorsJSON), &submission.Colors)
+		submission.User.ID = userID
+		submissions = append(submissions, submission)
+	}
+
+	return submissions, rows.Err()
+}
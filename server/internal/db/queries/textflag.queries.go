@@ -0,0 +1,91 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/db/models"
+	"encoding/json"
+	"fmt"
+)
+
+// FlagText records text that matched the profanity filter for review,
+// replacing any earlier flag of the same content
+func FlagText(repo *sql.DB, ctx context.Context, contentType, contentID, userID, text string, terms []string) error {
+	termsJSON, err := json.Marshal(terms)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.ExecContext(ctx, `
+		INSERT INTO text_flags (content_type, content_id, user_id, text, terms)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (content_type, content_id) DO UPDATE SET
+			user_id = excluded.user_id, text = excluded.text, terms = excluded.terms,
+			reviewed_by = NULL, reviewed_at = NULL, created_at = CURRENT_TIMESTAMP`,
+		contentType, contentID, userID, text, string(termsJSON))
+	return err
+}
+
+// ClearTextFlag drops the open flag of content whose text was changed to
+// something clean
+func ClearTextFlag(repo *sql.DB, ctx context.Context, contentType, contentID string) error {
+	_, err := repo.ExecContext(ctx, `
+		DELETE FROM text_flags WHERE content_type = ? AND content_id = ? AND reviewed_at IS NULL`,
+		contentType, contentID)
+	return err
+}
+
+// GetTextFlagOwner returns who wrote the flagged text when the content has an
+// open flag, or sql.ErrNoRows when it doesn't
+func GetTextFlagOwner(repo *sql.DB, ctx context.Context, contentType, contentID string) (string, error) {
+	var ownerID string
+	err := repo.QueryRowContext(ctx, `
+		SELECT COALESCE(user_id, '') FROM text_flags
+		WHERE content_type = ? AND content_id = ? AND reviewed_at IS NULL`,
+		contentType, contentID).Scan(&ownerID)
+	return ownerID, err
+}
+
+// GetTextFlags lists the flags no admin has looked at yet, newest first
+func GetTextFlags(repo *sql.DB, ctx context.Context) ([]models.TextFlag, error) {
+	rows, err := repo.QueryContext(ctx, `
+		SELECT f.content_type, f.content_id, f.text, f.terms, f.created_at,
+			COALESCE(u.id, ''), COALESCE(u.username, ''), COALESCE(u.avatar_type, ''), COALESCE(u.avatar_url, '')
+		FROM text_flags f
+		LEFT JOIN users u ON u.id = f.user_id
+		WHERE f.reviewed_at IS NULL
+		ORDER BY f.created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching text flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []models.TextFlag{}
+	for rows.Next() {
+		var flag models.TextFlag
+		var termsJSON string
+		err := rows.Scan(&flag.ContentType, &flag.ContentID, &flag.Text, &termsJSON, &flag.CreatedAt,
+			&flag.User.ID, &flag.User.Username, &flag.User.AvatarType, &flag.User.AvatarURL)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(termsJSON), &flag.Terms); err != nil {
+			return nil, fmt.Errorf("error parsing terms of text flag: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+	return flags, rows.Err()
+}
+
+// ResolveTextFlag marks the content's open flag as reviewed
+func ResolveTextFlag(repo *sql.DB, ctx context.Context, contentType, contentID, moderatorID string) (bool, error) {
+	result, err := repo.ExecContext(ctx, `
+		UPDATE text_flags SET reviewed_by = ?, reviewed_at = CURRENT_TIMESTAMP
+		WHERE content_type = ? AND content_id = ? AND reviewed_at IS NULL`,
+		moderatorID, contentType, contentID)
+	if err != nil {
+		return false, err
+	}
+	resolved, err := result.RowsAffected()
+	return resolved > 0, err
+}
@@ -41,12 +41,13 @@ func GetUserByEmail(repo *sql.DB, ctx context.Context, email string) (*models.Us
 }
 
 func GetUserFromDB(repo *sql.DB, ctx context.Context, userID string) (models.User, error) {
-	query := `SELECT id, username, email, role, created_at, avatar_type, avatar_url FROM users WHERE id = ?`
+	query := `SELECT id, username, email, role, created_at, avatar_type, avatar_url, suspended_until FROM users WHERE id = ?`
 
 	row := repo.QueryRowContext(ctx, query, userID)
 
 	var user models.User
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.CreatedAt, &user.AvatarType, &user.AvatarURL)
+	var suspendedUntil sql.NullTime
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.CreatedAt, &user.AvatarType, &user.AvatarURL, &suspendedUntil)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.User{}, err
@@ -55,6 +56,9 @@ func GetUserFromDB(repo *sql.DB, ctx context.Context, userID string) (models.Use
 		log.Printf("Error fetching user %s: %v", userID, err)
 		return models.User{}, fmt.Errorf("failed to fetch user: %w", err)
 	}
+	if suspendedUntil.Valid {
+		user.SuspendedUntil = &suspendedUntil.Time
+	}
 
 	return user, nil
 }
@@ -108,11 +112,24 @@ func GetUserDataFromDB(repo *sql.DB, ctx context.Context, userID string, cfg *co
 		return models.GetMeResponse{}, err
 	}
 
+	// Muted friends stay friends but their submissions are left out of the feed
+	mutedIDs, err := GetMutedUserIDs(repo, ctx, userID)
+	if err != nil {
+		log.Printf("Error fetching muted users for user %s: %v", userID, err)
+		return models.GetMeResponse{}, err
+	}
+	feedIDs := []string{}
+	for _, id := range friendIDs {
+		if !mutedIDs[id] {
+			feedIDs = append(feedIDs, id)
+		}
+	}
+
 	submissionIDs := []interface{}{userID}
 	whereClause := "us.user_id = ?"
-	if len(friendIDs) > 0 {
-		whereClause = "(us.user_id = ? OR us.user_id IN (" + strings.Repeat("?,", len(friendIDs)-1) + "?) )"
-		for _, id := range friendIDs {
+	if len(feedIDs) > 0 {
+		whereClause = "(us.user_id = ? OR us.user_id IN (" + strings.Repeat("?,", len(feedIDs)-1) + "?) )"
+		for _, id := range feedIDs {
 			submissionIDs = append(submissionIDs, id)
 		}
 	}
@@ -122,9 +139,9 @@ func GetUserDataFromDB(repo *sql.DB, ctx context.Context, userID string, cfg *co
 		SELECT
 			us.id as submission_id,
 			us.day,
-			dp.colors,
-			dp.prompt,
-			dp.created_by,
+			COALESCE(gp.colors, dp.colors),
+			COALESCE(gp.prompt, dp.prompt),
+			CASE WHEN gp.group_id IS NULL THEN dp.created_by ELSE gp.created_by END,
 			dpu.username,
 			dpu.email,
 			dpu.created_at,
@@ -137,6 +154,10 @@ func GetUserDataFromDB(repo *sql.DB, ctx context.Context, userID string, cfg *co
 			u.avatar_type,
 			u.avatar_url,
 			us.created_at as submission_created_at,
+			us.visibility,
+			us.title,
+			us.caption,
+			us.alt_text,
 			c.id as comment_id,
 			c.text as comment_text,
 			cu.id as comment_user_id,
@@ -145,17 +166,22 @@ func GetUserDataFromDB(repo *sql.DB, ctx context.Context, userID string, cfg *co
 			cu.created_at as comment_user_created_at,
 			cu.avatar_type as comment_user_avatar_type,
 			cu.avatar_url as comment_user_avatar_url,
-			c.created_at as comment_created_at
+			c.created_at as comment_created_at,
+			c.parent_id as comment_parent_id,
+			c.edited_at as comment_edited_at,
+			c.deleted_at IS NOT NULL as comment_deleted
 		FROM user_submissions us
 		JOIN daily_prompts dp ON us.day = dp.day
+		LEFT JOIN group_prompts gp ON gp.group_id = us.group_id AND gp.day = us.day
 		JOIN users u ON us.user_id = u.id
-		LEFT JOIN comments c ON c.submission_id = us.id
+		LEFT JOIN comments c ON c.submission_id = us.id AND ` + NotBlockedBy("us.user_id", "c.user_id") + ` AND ` + CommentVisibleTo("c") + `
 		LEFT JOIN users cu ON c.user_id = cu.id
-		LEFT JOIN users dpu ON dp.created_by = dpu.id
-		WHERE ` + whereClause + `
+		LEFT JOIN users dpu ON dpu.id = CASE WHEN gp.group_id IS NULL THEN dp.created_by ELSE gp.created_by END
+		WHERE ` + whereClause + ` AND us.kind = 'daily' AND ` + SubmissionVisibleTo("us") + `
 		ORDER BY us.day DESC, submission_created_at DESC, c.created_at ASC`
 
-	rows, err := repo.QueryContext(ctx, submissionQuery, submissionIDs...)
+	submissionArgs := append([]interface{}{userID}, submissionIDs...)
+	rows, err := repo.QueryContext(ctx, submissionQuery, append(submissionArgs, SubmissionVisibleToArgs(userID)...)...)
 	if err != nil {
 		log.Printf("Error fetching submissions and comments: %v", err)
 		return models.GetMeResponse{}, err
@@ -169,10 +195,13 @@ func GetUserDataFromDB(repo *sql.DB, ctx context.Context, userID string, cfg *co
 
 	for rows.Next() {
 		var (
-			subID, day, colorsJSON, prompt, subUserID, subUsername, subUserEmail, subAvatarType, subAvatarURL                                                                                                                      string
+			subID, day, colorsJSON, prompt, subUserID, subUsername, subUserEmail, subAvatarType, subAvatarURL, subVisibility, subTitle, subCaption, subAltText                                                                     string
 			subUserCreatedAt, subCreatedAt                                                                                                                                                                                         time.Time
 			commentID, commentText, commentUserID, commentUsername, commentUserEmail, commentUserAvatarType, commentUserAvatarURL, createdByUserID, createdByUsername, createdByUserEmail, createdByAvatarType, createdByAvatarURL sql.NullString
 			commentUserCreatedAt, commentCreatedAt, createdByUserCreatedAt                                                                                                                                                         sql.NullTime
+			commentParentID                                                                                                                                                                                                        sql.NullString
+			commentEditedAt                                                                                                                                                                                                        sql.NullTime
+			commentDeleted                                                                                                                                                                                                         sql.NullBool
 		)
 		err := rows.Scan(
 			&subID,
@@ -192,6 +221,10 @@ func GetUserDataFromDB(repo *sql.DB, ctx context.Context, userID string, cfg *co
 			&subAvatarType,
 			&subAvatarURL,
 			&subCreatedAt,
+			&subVisibility,
+			&subTitle,
+			&subCaption,
+			&subAltText,
 			&commentID,
 			&commentText,
 			&commentUserID,
@@ -201,6 +234,9 @@ func GetUserDataFromDB(repo *sql.DB, ctx context.Context, userID string, cfg *co
 			&commentUserAvatarType,
 			&commentUserAvatarURL,
 			&commentCreatedAt,
+			&commentParentID,
+			&commentEditedAt,
+			&commentDeleted,
 		)
 		if err != nil {
 			log.Printf("Error scanning submission+comment row: %v", err)
@@ -226,10 +262,14 @@ func GetUserDataFromDB(repo *sql.DB, ctx context.Context, userID string, cfg *co
 					AvatarType: subAvatarType,
 					AvatarURL:  subAvatarURL,
 				},
-				ImageUrl:  utils.GetImageUrl(cfg, filename),
-				Comments:  []models.Comment{},
-				Reactions: []models.Reaction{},
-				Counts:    []models.ReactionCount{},
+				ImageUrl:   utils.GetImageUrl(cfg, filename),
+				Comments:   []models.Comment{},
+				Reactions:  []models.Reaction{},
+				Counts:     []models.ReactionCount{},
+				Visibility: subVisibility,
+				Title:      subTitle,
+				Caption:    subCaption,
+				AltText:    subAltText,
 			}
 			subMap[subID] = &submission
 			// Add to feed as a flat list
@@ -256,6 +296,11 @@ func GetUserDataFromDB(repo *sql.DB, ctx context.Context, userID string, cfg *co
 				CreatedAt: commentCreatedAt.Time,
 				Reactions: []models.Reaction{},
 				Counts:    []models.ReactionCount{},
+				ParentID:  commentParentID.String,
+				Deleted:   commentDeleted.Bool,
+			}
+			if commentEditedAt.Valid {
+				comment.EditedAt = &commentEditedAt.Time
 			}
 			sub.Comments = append(sub.Comments, comment)
 		}
@@ -286,7 +331,9 @@ func GetUserDataFromDB(repo *sql.DB, ctx context.Context, userID string, cfg *co
 				u.avatar_url
 			FROM reactions r
 			JOIN users u ON r.user_id = u.id
+			JOIN user_submissions us ON us.id = r.content_id
 			WHERE r.content_type = 'submission' AND r.content_id IN (` + placeholders(len(submissionIDList)) + `)
+				AND ` + NotBlockedBy("us.user_id", "r.user_id") + `
 			ORDER BY r.created_at ASC
 		`
 		reactionArgs := make([]interface{}, len(submissionIDList))
@@ -332,8 +379,10 @@ func GetUserDataFromDB(repo *sql.DB, ctx context.Context, userID string, cfg *co
 				content_id as submission_id,
 				reaction_id,
 				COUNT(*) as count
-			FROM reactions
+			FROM reactions r
+			JOIN user_submissions us ON us.id = r.content_id
 			WHERE content_type = 'submission' AND content_id IN (` + placeholders(len(submissionIDList)) + `)
+				AND ` + NotBlockedBy("us.user_id", "r.user_id") + `
 			GROUP BY content_id, reaction_id
 			ORDER BY content_id, count DESC, reaction_id ASC
 		`
@@ -457,7 +506,22 @@ func GetUserDataFromDB(repo *sql.DB, ctx context.Context, userID string, cfg *co
 		}
 	}
 
-	totalDrawingsQuery := `SELECT COUNT(*) FROM user_submissions WHERE user_id = ?`
+	// Replies are nested once the flat list has its reactions and mentions
+	// attached
+	comments := []*models.Comment{}
+	for _, submission := range response.Feed {
+		for i := range submission.Comments {
+			comments = append(comments, &submission.Comments[i])
+		}
+	}
+	if err := AttachMentions(repo, ctx, comments); err != nil {
+		log.Printf("Error fetching comment mentions: %v", err)
+	}
+	for _, submission := range response.Feed {
+		submission.Comments = NestComments(submission.Comments)
+	}
+
+	totalDrawingsQuery := `SELECT COUNT(*) FROM user_submissions WHERE user_id = ? AND kind = 'daily'`
 	var totalDrawings int
 	err = repo.QueryRowContext(ctx, totalDrawingsQuery, userID).Scan(&totalDrawings)
 	if err != nil {
@@ -469,7 +533,7 @@ func GetUserDataFromDB(repo *sql.DB, ctx context.Context, userID string, cfg *co
 	var currentStreak int
 	rows, err = repo.QueryContext(ctx, `
 		SELECT day FROM user_submissions
-		WHERE user_id = ?
+		WHERE user_id = ? AND kind = 'daily'
 		ORDER BY day DESC
 	`, userID)
 	if err != nil {
@@ -518,10 +582,16 @@ func GetUserDataFromDB(repo *sql.DB, ctx context.Context, userID string, cfg *co
 
 	response.Stats = models.UserStats{
 		TotalDrawings: totalDrawings,
-		CurrentStreak: currentStreak,
+		CurrentStreak: &currentStreak,
+	}
+
+	// Locked before favorites are built since they copy the submissions
+	if err := LockSpoilers(repo, ctx, userID, response.Feed); err != nil {
+		log.Printf("Error locking today's submissions for user %s: %v", userID, err)
+		return models.GetMeResponse{}, err
 	}
 
-	favQuery := `SELECT id, submission_id, created_at, order_num FROM user_favorite_submissions WHERE user_id = ? ORDER BY order_num DESC`
+	favQuery := `SELECT id, submission_id, created_at, order_num FROM user_favorite_submissions WHERE user_id = ? ORDER BY order_num ASC`
 	favRows, err := repo.QueryContext(ctx, favQuery, userID)
 	if err != nil {
 		log.Printf("Error fetching favorites for user %s: %v", userID, err)
@@ -639,11 +709,24 @@ func GetUserProfileFromDB(repo *sql.DB, ctx context.Context, userID string, requ
 		return models.GetMeResponse{}, err
 	}
 
+	// The requester's mutes apply to the friends shown alongside this profile
+	mutedIDs, err := GetMutedUserIDs(repo, ctx, requesterID)
+	if err != nil {
+		log.Printf("Error fetching muted users for user %s: %v", requesterID, err)
+		return models.GetMeResponse{}, err
+	}
+	feedIDs := []string{}
+	for _, id := range friendIDs {
+		if !mutedIDs[id] {
+			feedIDs = append(feedIDs, id)
+		}
+	}
+
 	submissionIDs := []interface{}{userID}
 	whereClause := "us.user_id = ?"
-	if len(friendIDs) > 0 {
-		whereClause = "(us.user_id = ? OR us.user_id IN (" + strings.Repeat("?,", len(friendIDs)-1) + "?) )"
-		for _, id := range friendIDs {
+	if len(feedIDs) > 0 {
+		whereClause = "(us.user_id = ? OR us.user_id IN (" + strings.Repeat("?,", len(feedIDs)-1) + "?) )"
+		for _, id := range feedIDs {
 			submissionIDs = append(submissionIDs, id)
 		}
 	}
@@ -653,9 +736,9 @@ func GetUserProfileFromDB(repo *sql.DB, ctx context.Context, userID string, requ
 		SELECT
 			us.id as submission_id,
 			us.day,
-			dp.colors,
-			dp.prompt,
-			dp.created_by,
+			COALESCE(gp.colors, dp.colors),
+			COALESCE(gp.prompt, dp.prompt),
+			CASE WHEN gp.group_id IS NULL THEN dp.created_by ELSE gp.created_by END,
 			dpu.username,
 			dpu.email,
 			dpu.created_at,
@@ -668,6 +751,10 @@ func GetUserProfileFromDB(repo *sql.DB, ctx context.Context, userID string, requ
 			u.avatar_type,
 			u.avatar_url,
 			us.created_at as submission_created_at,
+			us.visibility,
+			us.title,
+			us.caption,
+			us.alt_text,
 			c.id as comment_id,
 			c.text as comment_text,
 			cu.id as comment_user_id,
@@ -676,17 +763,22 @@ func GetUserProfileFromDB(repo *sql.DB, ctx context.Context, userID string, requ
 			cu.created_at as comment_user_created_at,
 			cu.avatar_type as comment_user_avatar_type,
 			cu.avatar_url as comment_user_avatar_url,
-			c.created_at as comment_created_at
+			c.created_at as comment_created_at,
+			c.parent_id as comment_parent_id,
+			c.edited_at as comment_edited_at,
+			c.deleted_at IS NOT NULL as comment_deleted
 		FROM user_submissions us
 		JOIN daily_prompts dp ON us.day = dp.day
+		LEFT JOIN group_prompts gp ON gp.group_id = us.group_id AND gp.day = us.day
 		JOIN users u ON us.user_id = u.id
-		LEFT JOIN comments c ON c.submission_id = us.id
+		LEFT JOIN comments c ON c.submission_id = us.id AND ` + NotBlockedBy("us.user_id", "c.user_id") + ` AND ` + CommentVisibleTo("c") + `
 		LEFT JOIN users cu ON c.user_id = cu.id
-		LEFT JOIN users dpu ON dp.created_by = dpu.id
-		WHERE ` + whereClause + `
+		LEFT JOIN users dpu ON dpu.id = CASE WHEN gp.group_id IS NULL THEN dp.created_by ELSE gp.created_by END
+		WHERE ` + whereClause + ` AND us.kind = 'daily' AND ` + SubmissionVisibleTo("us") + `
 		ORDER BY us.day DESC, submission_created_at DESC, c.created_at ASC`
 
-	rows, err := repo.QueryContext(ctx, submissionQuery, submissionIDs...)
+	submissionArgs := append([]interface{}{requesterID}, submissionIDs...)
+	rows, err := repo.QueryContext(ctx, submissionQuery, append(submissionArgs, SubmissionVisibleToArgs(requesterID)...)...)
 	if err != nil {
 		log.Printf("Error fetching submissions and comments: %v", err)
 		return models.GetMeResponse{}, err
@@ -700,10 +792,13 @@ func GetUserProfileFromDB(repo *sql.DB, ctx context.Context, userID string, requ
 
 	for rows.Next() {
 		var (
-			subID, day, colorsJSON, prompt, subUserID, subUsername, subUserEmail, subAvatarType, subAvatarURL                                                                                                                      string
+			subID, day, colorsJSON, prompt, subUserID, subUsername, subUserEmail, subAvatarType, subAvatarURL, subVisibility, subTitle, subCaption, subAltText                                                                     string
 			subUserCreatedAt, subCreatedAt                                                                                                                                                                                         time.Time
 			commentID, commentText, commentUserID, commentUsername, commentUserEmail, commentUserAvatarType, commentUserAvatarURL, createdByUserID, createdByUsername, createdByUserEmail, createdByAvatarType, createdByAvatarURL sql.NullString
 			commentUserCreatedAt, commentCreatedAt, createdByUserCreatedAt                                                                                                                                                         sql.NullTime
+			commentParentID                                                                                                                                                                                                        sql.NullString
+			commentEditedAt                                                                                                                                                                                                        sql.NullTime
+			commentDeleted                                                                                                                                                                                                         sql.NullBool
 		)
 		err := rows.Scan(
 			&subID,
@@ -723,6 +818,10 @@ func GetUserProfileFromDB(repo *sql.DB, ctx context.Context, userID string, requ
 			&subAvatarType,
 			&subAvatarURL,
 			&subCreatedAt,
+			&subVisibility,
+			&subTitle,
+			&subCaption,
+			&subAltText,
 			&commentID,
 			&commentText,
 			&commentUserID,
@@ -732,6 +831,9 @@ func GetUserProfileFromDB(repo *sql.DB, ctx context.Context, userID string, requ
 			&commentUserAvatarType,
 			&commentUserAvatarURL,
 			&commentCreatedAt,
+			&commentParentID,
+			&commentEditedAt,
+			&commentDeleted,
 		)
 		if err != nil {
 			log.Printf("Error scanning submission+comment row: %v", err)
@@ -755,10 +857,14 @@ func GetUserProfileFromDB(repo *sql.DB, ctx context.Context, userID string, requ
 					AvatarType: subAvatarType,
 					AvatarURL:  subAvatarURL,
 				},
-				ImageUrl:  utils.GetImageUrl(cfg, utils.GetSubmissionFilename(subUserID, subID)),
-				Comments:  []models.Comment{},
-				Reactions: []models.Reaction{},
-				Counts:    []models.ReactionCount{},
+				ImageUrl:   utils.GetImageUrl(cfg, utils.GetSubmissionFilename(subUserID, subID)),
+				Comments:   []models.Comment{},
+				Reactions:  []models.Reaction{},
+				Counts:     []models.ReactionCount{},
+				Visibility: subVisibility,
+				Title:      subTitle,
+				Caption:    subCaption,
+				AltText:    subAltText,
 			}
 
 			if createdByUserID.Valid {
@@ -797,6 +903,11 @@ func GetUserProfileFromDB(repo *sql.DB, ctx context.Context, userID string, requ
 				CreatedAt: commentCreatedAt.Time,
 				Reactions: []models.Reaction{},
 				Counts:    []models.ReactionCount{},
+				ParentID:  commentParentID.String,
+				Deleted:   commentDeleted.Bool,
+			}
+			if commentEditedAt.Valid {
+				comment.EditedAt = &commentEditedAt.Time
 			}
 			sub.Comments = append(sub.Comments, comment)
 		}
@@ -828,7 +939,9 @@ func GetUserProfileFromDB(repo *sql.DB, ctx context.Context, userID string, requ
 				u.avatar_url
 			FROM reactions r
 			JOIN users u ON r.user_id = u.id
+			JOIN user_submissions us ON us.id = r.content_id
 			WHERE r.content_type = 'submission' AND r.content_id IN (` + placeholders(len(submissionIDList)) + `)
+				AND ` + NotBlockedBy("us.user_id", "r.user_id") + `
 			ORDER BY r.created_at ASC
 		`
 		reactionArgs := make([]interface{}, len(submissionIDList))
@@ -874,8 +987,10 @@ func GetUserProfileFromDB(repo *sql.DB, ctx context.Context, userID string, requ
 				content_id as submission_id,
 				reaction_id,
 				COUNT(*) as count
-			FROM reactions
+			FROM reactions r
+			JOIN user_submissions us ON us.id = r.content_id
 			WHERE content_type = 'submission' AND content_id IN (` + placeholders(len(submissionIDList)) + `)
+				AND ` + NotBlockedBy("us.user_id", "r.user_id") + `
 			GROUP BY content_id, reaction_id
 			ORDER BY content_id, count DESC, reaction_id ASC
 		`
@@ -999,7 +1114,22 @@ func GetUserProfileFromDB(repo *sql.DB, ctx context.Context, userID string, requ
 		}
 	}
 
-	totalDrawingsQuery := `SELECT COUNT(*) FROM user_submissions WHERE user_id = ?`
+	// Replies are nested once the flat list has its reactions and mentions
+	// attached
+	comments := []*models.Comment{}
+	for _, submission := range response.Feed {
+		for i := range submission.Comments {
+			comments = append(comments, &submission.Comments[i])
+		}
+	}
+	if err := AttachMentions(repo, ctx, comments); err != nil {
+		log.Printf("Error fetching comment mentions: %v", err)
+	}
+	for _, submission := range response.Feed {
+		submission.Comments = NestComments(submission.Comments)
+	}
+
+	totalDrawingsQuery := `SELECT COUNT(*) FROM user_submissions WHERE user_id = ? AND kind = 'daily'`
 	var totalDrawings int
 	err = repo.QueryRowContext(ctx, totalDrawingsQuery, userID).Scan(&totalDrawings)
 	if err != nil {
@@ -1011,7 +1141,7 @@ func GetUserProfileFromDB(repo *sql.DB, ctx context.Context, userID string, requ
 	var currentStreak int
 	rows, err = repo.QueryContext(ctx, `
 		SELECT day FROM user_submissions
-		WHERE user_id = ?
+		WHERE user_id = ? AND kind = 'daily'
 		ORDER BY day DESC
 	`, userID)
 	if err != nil {
@@ -1056,7 +1186,19 @@ func GetUserProfileFromDB(repo *sql.DB, ctx context.Context, userID string, requ
 
 	response.Stats = models.UserStats{
 		TotalDrawings: totalDrawings,
-		CurrentStreak: currentStreak,
+		CurrentStreak: &currentStreak,
+	}
+
+	response.ContestWins, err = GetContestWins(repo, ctx, cfg, userID, requesterID)
+	if err != nil {
+		log.Printf("Error fetching contest wins for user %s: %v", userID, err)
+		response.ContestWins = []models.ContestWin{}
+	}
+
+	// Locked before favorites are built since they copy the submissions
+	if err := LockSpoilers(repo, ctx, requesterID, response.Feed); err != nil {
+		log.Printf("Error locking today's submissions for user %s: %v", requesterID, err)
+		return models.GetMeResponse{}, err
 	}
 
 	favQuery := `SELECT id, submission_id, created_at, order_num FROM user_favorite_submissions WHERE user_id = ? ORDER BY order_num ASC`
@@ -1125,6 +1267,32 @@ func GetUserFriends(repo *sql.DB, ctx context.Context, userID string) ([]string,
 	return friends, nil
 }
 
+// AreFriends reports whether two users have an accepted friendship
+func AreFriends(repo *sql.DB, ctx context.Context, userID string, otherID string) (bool, error) {
+	query := `SELECT EXISTS(
+		SELECT 1 FROM friendships
+		WHERE ((user1 = ? AND user2 = ?) OR (user1 = ? AND user2 = ?)) AND state = 'accepted'
+	)`
+	var friends bool
+	err := repo.QueryRowContext(ctx, query, userID, otherID, otherID, userID).Scan(&friends)
+	return friends, err
+}
+
+// RemoveFriend deletes the accepted friendship between two users, or the
+// invitation userID sent otherID, and returns the state it had. Incoming
+// invitations are left alone, those go through deny-invitation. Returns
+// sql.ErrNoRows when there was nothing to remove.
+func RemoveFriend(repo *sql.DB, ctx context.Context, userID string, otherID string) (string, error) {
+	var state string
+	err := repo.QueryRowContext(ctx,
+		`DELETE FROM friendships
+		WHERE ((user1 = ? AND user2 = ?) OR (user1 = ? AND user2 = ?))
+			AND (state = 'accepted' OR (state = 'pending' AND inviter_id = ?))
+		RETURNING state`,
+		userID, otherID, otherID, userID, userID).Scan(&state)
+	return state, err
+}
+
 func CreateUser(repo *sql.DB, ctx context.Context, username string, email string) (*models.User, error) {
 	var user models.User
 	insertSQL := `
@@ -1202,3 +1370,86 @@ func UpdateUserAvatarType(repo *sql.DB, ctx context.Context, userID string, avat
 
 	return nil
 }
+
+// SearchUsers finds users whose username starts with prefix, leaving out the
+// requester and anyone blocked in either direction
+func SearchUsers(repo *sql.DB, ctx context.Context, requesterID string, prefix string, limit int) ([]models.UserSearchResult, error) {
+	// Wildcards typed by the user are matched literally
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+
+	query := `
+		SELECT u.id, u.username, u.created_at, u.avatar_type, u.avatar_url, COALESCE(f.state, '')
+		FROM users u
+		LEFT JOIN friendships f ON (f.user1 = ? AND f.user2 = u.id) OR (f.user1 = u.id AND f.user2 = ?)
+		WHERE u.username LIKE ? ESCAPE '\' AND u.id != ?
+			AND NOT EXISTS (
+				SELECT 1 FROM user_blocks b
+				WHERE (b.blocker_id = ? AND b.blocked_id = u.id) OR (b.blocker_id = u.id AND b.blocked_id = ?)
+			)
+		ORDER BY u.username ASC
+		LIMIT ?`
+	rows, err := repo.QueryContext(ctx, query, requesterID, requesterID, escaped+"%", requesterID, requesterID, requesterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error searching users: %w", err)
+	}
+	defer rows.Close()
+
+	results := []models.UserSearchResult{}
+	for rows.Next() {
+		var result models.UserSearchResult
+		err := rows.Scan(&result.User.ID, &result.User.Username, &result.User.CreatedAt, &result.User.AvatarType, &result.User.AvatarURL, &result.Friendship)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning user search row: %w", err)
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// GetFriendSuggestions returns friends of the user's friends they have no
+// friendship or invitation with yet, ranked by how many friends they share
+func GetFriendSuggestions(repo *sql.DB, ctx context.Context, userID string, limit int) ([]models.FriendSuggestion, error) {
+	query := `
+		WITH my_friends AS (
+			SELECT CASE WHEN user1 = ? THEN user2 ELSE user1 END AS id
+			FROM friendships
+			WHERE (user1 = ? OR user2 = ?) AND state = 'accepted'
+		),
+		candidates AS (
+			SELECT CASE WHEN f.user1 = mf.id THEN f.user2 ELSE f.user1 END AS id
+			FROM friendships f
+			JOIN my_friends mf ON f.user1 = mf.id OR f.user2 = mf.id
+			WHERE f.state = 'accepted'
+		)
+		SELECT u.id, u.username, u.created_at, u.avatar_type, u.avatar_url, COUNT(*) AS mutual
+		FROM candidates c
+		JOIN users u ON u.id = c.id
+		WHERE c.id != ?
+			AND NOT EXISTS (
+				SELECT 1 FROM friendships f
+				WHERE (f.user1 = ? AND f.user2 = c.id) OR (f.user1 = c.id AND f.user2 = ?)
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM user_blocks b
+				WHERE (b.blocker_id = ? AND b.blocked_id = c.id) OR (b.blocker_id = c.id AND b.blocked_id = ?)
+			)
+		GROUP BY u.id, u.username, u.created_at, u.avatar_type, u.avatar_url
+		ORDER BY mutual DESC, u.username ASC
+		LIMIT ?`
+	rows, err := repo.QueryContext(ctx, query, userID, userID, userID, userID, userID, userID, userID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching friend suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := []models.FriendSuggestion{}
+	for rows.Next() {
+		var suggestion models.FriendSuggestion
+		err := rows.Scan(&suggestion.User.ID, &suggestion.User.Username, &suggestion.User.CreatedAt, &suggestion.User.AvatarType, &suggestion.User.AvatarURL, &suggestion.MutualFriends)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning friend suggestion row: %w", err)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, rows.Err()
+}
@@ -9,3 +9,36 @@ func placeholders(n int) string {
 	s := strings.Repeat("?,", n)
 	return s[:len(s)-1]
 }
+
+// NotBlockedBy is an SQL condition that holds unless the user in ownerColumn
+// has blocked the user in authorColumn. It hides blocked users' comments and
+// reactions on the owner's submissions.
+func NotBlockedBy(ownerColumn string, authorColumn string) string {
+	return "NOT EXISTS (SELECT 1 FROM user_blocks ub WHERE ub.blocker_id = " + ownerColumn + " AND ub.blocked_id = " + authorColumn + ")"
+}
+
+// SubmissionVisibleTo is an SQL condition that holds when the viewer may see
+// the submission aliased as alias: their own, public ones, and friends-only
+// ones by a friend, by someone they share a group with or drawn for a group
+// they're in. Group members count as friends so the group feed and the
+// drawings it links to agree. Submissions hidden by moderation are only seen
+// by their author. Its arguments come from SubmissionVisibleToArgs.
+func SubmissionVisibleTo(alias string) string {
+	return `(` + alias + `.user_id = ? OR (` + alias + `.hidden_at IS NULL AND (` + alias + `.visibility = 'public' OR (` + alias + `.visibility = 'friends' AND (
+		EXISTS (SELECT 1 FROM friendships vf WHERE vf.state = 'accepted'
+			AND ((vf.user1 = ` + alias + `.user_id AND vf.user2 = ?) OR (vf.user2 = ` + alias + `.user_id AND vf.user1 = ?)))
+		OR EXISTS (SELECT 1 FROM group_members vgm WHERE vgm.group_id = ` + alias + `.group_id AND vgm.user_id = ?)
+		OR EXISTS (SELECT 1 FROM group_members vgv JOIN group_members vga ON vga.group_id = vgv.group_id
+			WHERE vgv.user_id = ? AND vga.user_id = ` + alias + `.user_id))))))`
+}
+
+// CommentVisibleTo is an SQL condition that holds unless the comment aliased
+// as alias was hidden by moderation, which only its author still sees. Its
+// argument is the viewer's ID.
+func CommentVisibleTo(alias string) string {
+	return "(" + alias + ".hidden_at IS NULL OR " + alias + ".user_id = ?)"
+}
+
+func SubmissionVisibleToArgs(viewerID string) []interface{} {
+	return []interface{}{viewerID, viewerID, viewerID, viewerID, viewerID}
+}
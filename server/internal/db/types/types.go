@@ -14,4 +14,8 @@ var (
 	COMMENT_TOTAL     AchievementField = "COMMENT_TOTAL"
 	REACTION_TOTAL    AchievementField = "REACTION_TOTAL"
 	FRIEND_TOTAL      AchievementField = "FRIEND_TOTAL"
+	// Submissions that stayed within the prompt palette
+	PALETTE_PURIST_TOTAL AchievementField = "PALETTE_PURIST_TOTAL"
+	// Users who joined through the user's invite codes
+	REFERRAL_TOTAL AchievementField = "REFERRAL_TOTAL"
 )
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"database/sql"
 	"drawer-service-backend/internal/achievements"
 	"drawer-service-backend/internal/context"
 	"drawer-service-backend/internal/db/models"
 	"drawer-service-backend/internal/db/queries"
 	"drawer-service-backend/internal/middleware"
+	"errors"
 	"log"
 	"net/http"
 
@@ -42,10 +44,32 @@ func HandlerGetMyAchievements(c *gin.Context) {
 }
 
 func HandlerGetUserAchievements(c *gin.Context) {
+	requester := middleware.GetUser(c)
 	userId := c.Param("id")
 	appCtx := context.GetCtx(c)
 	ctx := c.Request.Context()
 
+	if userId != requester.ID {
+		privacy, err := queries.GetPrivacySettings(appCtx.DB, ctx, userId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		canView, err := queries.CanViewProfile(appCtx.DB, ctx, userId, requester.ID, privacy.ProfileVisibility)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !canView || !privacy.ShowAchievements {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This user's achievements are private"})
+			return
+		}
+	}
+
 	achievements, rewards, err := queries.GetAchievementsAndRewardsByUserID(appCtx.DB, ctx, userId)
 
 	if err != nil {
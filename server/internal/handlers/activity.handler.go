@@ -21,14 +21,14 @@ func HandleGetActivity(c *gin.Context) {
 	ctx := c.Request.Context()
 	requester := middleware.GetUser(c)
 
-	lastReadID, err := queries.GetLastReadActivityID(appCtx.DB, ctx, userID)
+	lastReadDate, err := queries.GetLastReadActivityDate(appCtx.DB, ctx, userID)
 	if err != nil {
-		log.Printf("HandleGetActivity: error getting lastReadID for user %s: %v", userID, err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to get last read activity id"})
+		log.Printf("HandleGetActivity: error getting lastReadDate for user %s: %v", userID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to get last read activity"})
 		return
 	}
 
-	activities, err := queries.GetActivityFeed(appCtx.DB, ctx, userID, lastReadID, appCtx.Config)
+	activities, err := queries.GetActivityFeed(appCtx.DB, ctx, userID, lastReadDate, appCtx.Config)
 	if err != nil {
 		log.Printf("HandleGetActivity: error getting activity feed for user %s: %v", userID, err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to get activity feed"})
@@ -4,6 +4,7 @@ import (
 	"drawer-service-backend/internal/context"
 	"drawer-service-backend/internal/db/queries"
 	"drawer-service-backend/internal/middleware"
+	"drawer-service-backend/internal/palette"
 	"drawer-service-backend/internal/utils"
 	"log"
 	"net/http"
@@ -121,6 +122,8 @@ type CreatePromptRequest struct {
 	Prompt    string   `json:"prompt" binding:"required"`
 	Colors    []string `json:"colors" binding:"required"`
 	CreatedBy string   `json:"createdBy"`
+	// One of off, reward, flag or reject. Defaults to flag
+	PaletteMode string `json:"paletteMode"`
 }
 
 func HandleCreatePrompt(c *gin.Context) {
@@ -148,11 +151,19 @@ func HandleCreatePrompt(c *gin.Context) {
 		}
 	}
 
+	if req.PaletteMode == "" {
+		req.PaletteMode = string(palette.MODE_FLAG)
+	}
+	if !palette.IsValidMode(req.PaletteMode) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid palette mode. Must be one of off, reward, flag or reject"})
+		return
+	}
+
 	// Check if prompt already exists for this day
 	_, err := queries.GetDailyPrompt(appCtx.DB, c.Request.Context(), req.Day)
 	if err == nil {
 		// Prompt exists, update it instead
-		err = queries.UpdateDailyPrompt(appCtx.DB, c.Request.Context(), req.Day, req.Prompt, req.Colors, &req.CreatedBy)
+		err = queries.UpdateDailyPrompt(appCtx.DB, c.Request.Context(), req.Day, req.Prompt, req.Colors, &req.CreatedBy, req.PaletteMode)
 		if err != nil {
 			log.Printf("Error updating prompt for day %s: %v", req.Day, err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to update prompt"})
@@ -168,7 +179,7 @@ func HandleCreatePrompt(c *gin.Context) {
 	}
 
 	// Prompt doesn't exist, create new one
-	err = queries.CreateDailyPrompt(appCtx.DB, c.Request.Context(), req.Day, req.Prompt, req.Colors)
+	err = queries.CreateDailyPrompt(appCtx.DB, c.Request.Context(), req.Day, req.Prompt, req.Colors, req.PaletteMode)
 	if err != nil {
 		log.Printf("Error creating prompt for day %s: %v", req.Day, err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create prompt"})
@@ -198,3 +209,49 @@ func HandleGetAdminActionStats(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, stats)
 }
+
+// HandleGetPaletteFlags lists submissions that were flagged for using colors
+// outside of their prompt palette
+func HandleGetPaletteFlags(c *gin.Context) {
+	appCtx := context.GetCtx(c)
+
+	submissions, err := queries.GetPaletteFlaggedSubmissions(appCtx.DB, c.Request.Context(), appCtx.Config)
+	if err != nil {
+		log.Printf("Error fetching palette flagged submissions: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch flagged submissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"submissions": submissions})
+}
+
+// HandleGetDuplicateFlags lists submissions that look like an earlier drawing
+// by the same user, paired with the drawing they matched
+func HandleGetDuplicateFlags(c *gin.Context) {
+	appCtx := context.GetCtx(c)
+
+	pairs, err := queries.GetDuplicateSubmissionPairs(appCtx.DB, c.Request.Context(), appCtx.Config)
+	if err != nil {
+		log.Printf("Error fetching duplicate submission pairs: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch duplicate submissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pairs": pairs})
+}
+
+// HandleGetTextFlags lists comments, submission captions, prompt suggestions
+// and usernames the profanity filter let through for review. They're acted on
+// like reported content.
+func HandleGetTextFlags(c *gin.Context) {
+	appCtx := context.GetCtx(c)
+
+	flags, err := queries.GetTextFlags(appCtx.DB, c.Request.Context())
+	if err != nil {
+		log.Printf("Error fetching text flags: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch flagged text"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"flags": flags})
+}
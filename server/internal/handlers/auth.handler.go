@@ -18,6 +18,8 @@ import (
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
+	// Optional code from an invite link
+	InviteCode string `json:"inviteCode"`
 }
 
 func HandleVerifyEmail(c *gin.Context) {
@@ -126,6 +128,10 @@ func HandleRegister(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Username cannot contain spaces"})
 		return
 	}
+	screened, ok := screenUsername(c, req.Username)
+	if !ok {
+		return
+	}
 
 	// Check if user already exists
 	existingUser, err := queries.GetUserByEmail(appCtx.DB, ctx, req.Email)
@@ -149,6 +155,17 @@ func HandleRegister(c *gin.Context) {
 		return
 	}
 
+	if screened.Flagged {
+		updateTextFlag(c, screened, queries.ReportContentUser, user.ID, user.ID)
+	}
+
+	// A bad invite code shouldn't stop the account from being created
+	if req.InviteCode != "" {
+		if err := redeemInviteCode(appCtx, ctx, strings.ToUpper(req.InviteCode), user.ID, true); err != nil {
+			log.Printf("Failed to redeem invite code %s for new user %s: %v", req.InviteCode, user.ID, err)
+		}
+	}
+
 	// Skip email verification for development environment
 	if appCtx.Config.Env != "production" {
 		setAuthCookie(c, user.ID)
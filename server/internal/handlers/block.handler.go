@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	requestContext "drawer-service-backend/internal/context"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/middleware"
+	"drawer-service-backend/internal/stats"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// targetUserID reads the :id param and makes sure it names another existing
+// user, aborting the request otherwise.
+func targetUserID(c *gin.Context, requesterID string, action string) (string, bool) {
+	appCtx := requestContext.GetCtx(c)
+	otherID := c.Param("id")
+	if otherID == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		return "", false
+	}
+	if otherID == requesterID {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "You cannot " + action + " yourself"})
+		return "", false
+	}
+
+	if _, err := queries.GetUserByID(appCtx.DB, c.Request.Context(), otherID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return "", false
+		}
+		log.Printf("Error fetching user %s: %v", otherID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return "", false
+	}
+	return otherID, true
+}
+
+func HandleBlockUser(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	otherID, ok := targetUserID(c, requester.ID, "block")
+	if !ok {
+		return
+	}
+
+	removedFriend, err := queries.BlockUser(appCtx.DB, c.Request.Context(), requester.ID, otherID)
+	if err != nil {
+		log.Printf("Error blocking %s for user %s: %v", otherID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to block user"})
+		return
+	}
+
+	if removedFriend {
+		go func() {
+			for _, userID := range []string{requester.ID, otherID} {
+				if _, err := stats.CalculateFriendTotal(appCtx.DB, context.Background(), userID, 0); err != nil {
+					log.Printf("Error recalculating friend total for %s: %v", userID, err)
+				}
+			}
+		}()
+	}
+
+	log.Printf("User %s blocked %s", requester.ID, otherID)
+	c.JSON(http.StatusOK, gin.H{"message": "User blocked"})
+}
+
+func HandleUnblockUser(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	otherID := c.Param("id")
+
+	removed, err := queries.UnblockUser(appCtx.DB, c.Request.Context(), requester.ID, otherID)
+	if err != nil {
+		log.Printf("Error unblocking %s for user %s: %v", otherID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock user"})
+		return
+	}
+	if !removed {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "User is not blocked"})
+		return
+	}
+
+	// The friendship removed by the block is not restored
+	log.Printf("User %s unblocked %s", requester.ID, otherID)
+	c.JSON(http.StatusOK, gin.H{"message": "User unblocked"})
+}
+
+func HandleGetBlockedUsers(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	users, err := queries.GetBlockedUsers(appCtx.DB, c.Request.Context(), requester.ID)
+	if err != nil {
+		log.Printf("Error fetching blocked users for %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blocked users"})
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+func HandleMuteUser(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	otherID, ok := targetUserID(c, requester.ID, "mute")
+	if !ok {
+		return
+	}
+
+	if err := queries.MuteUser(appCtx.DB, c.Request.Context(), requester.ID, otherID); err != nil {
+		log.Printf("Error muting %s for user %s: %v", otherID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to mute user"})
+		return
+	}
+
+	log.Printf("User %s muted %s", requester.ID, otherID)
+	c.JSON(http.StatusOK, gin.H{"message": "User muted"})
+}
+
+func HandleUnmuteUser(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	otherID := c.Param("id")
+
+	removed, err := queries.UnmuteUser(appCtx.DB, c.Request.Context(), requester.ID, otherID)
+	if err != nil {
+		log.Printf("Error unmuting %s for user %s: %v", otherID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmute user"})
+		return
+	}
+	if !removed {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "User is not muted"})
+		return
+	}
+
+	log.Printf("User %s unmuted %s", requester.ID, otherID)
+	c.JSON(http.StatusOK, gin.H{"message": "User unmuted"})
+}
+
+func HandleGetMutedUsers(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	users, err := queries.GetMutedUsers(appCtx.DB, c.Request.Context(), requester.ID)
+	if err != nil {
+		log.Printf("Error fetching muted users for %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch muted users"})
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"database/sql"
+	requestContext "drawer-service-backend/internal/context"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/middleware"
+	"drawer-service-backend/internal/textfilter"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleUpdateSubmissionText sets the title, caption or alt text of one of the
+// requester's submissions. Fields left out of the body are kept.
+func HandleUpdateSubmissionText(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	submissionID := c.Param("id")
+
+	var body struct {
+		Title   *string `json:"title"`
+		Caption *string `json:"caption"`
+		AltText *string `json:"altText"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	text, err := queries.GetSubmissionText(appCtx.DB, c.Request.Context(), submissionID, requester.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching text of submission %s for user %s: %v", submissionID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to update submission"})
+		return
+	}
+	if body.Title != nil {
+		text.Title = *body.Title
+	}
+	if body.Caption != nil {
+		text.Caption = *body.Caption
+	}
+	if body.AltText != nil {
+		text.AltText = *body.AltText
+	}
+
+	text, screened, ok := bindSubmissionText(c, text)
+	if !ok {
+		return
+	}
+
+	updated, err := queries.SetSubmissionText(appCtx.DB, c.Request.Context(), submissionID, requester.ID, text)
+	if err != nil {
+		log.Printf("Error updating text of submission %s for user %s: %v", submissionID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to update submission"})
+		return
+	}
+	if !updated {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+	updateTextFlag(c, screened, queries.ReportContentSubmission, submissionID, requester.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"title":   text.Title,
+		"caption": text.Caption,
+		"altText": text.AltText,
+	})
+}
+
+// readSubmissionText reads the optional title, caption and alt text from a
+// submit form
+func readSubmissionText(c *gin.Context) (queries.SubmissionText, textfilter.Result, bool) {
+	return bindSubmissionText(c, queries.SubmissionText{
+		Title:   c.PostForm("title"),
+		Caption: c.PostForm("caption"),
+		AltText: c.PostForm("altText"),
+	})
+}
+
+// bindSubmissionText trims and checks the text of a submission and runs it
+// through the profanity filter. The returned result covers all three fields
+// together, so a submission has at most one text flag.
+func bindSubmissionText(c *gin.Context, text queries.SubmissionText) (queries.SubmissionText, textfilter.Result, bool) {
+	fields := []struct {
+		name  string
+		value *string
+		max   int
+	}{
+		{"Title", &text.Title, queries.MaxSubmissionTitleLength},
+		{"Caption", &text.Caption, queries.MaxSubmissionCaptionLength},
+		{"Alt text", &text.AltText, queries.MaxSubmissionAltTextLength},
+	}
+
+	combined := textfilter.Result{}
+	parts := []string{}
+	for _, field := range fields {
+		*field.value = strings.TrimSpace(*field.value)
+		if utf8.RuneCountInString(*field.value) > field.max {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s max length is %d characters", field.name, field.max)})
+			return text, combined, false
+		}
+		if *field.value == "" {
+			continue
+		}
+
+		screened, ok := screenText(c, *field.value)
+		if !ok {
+			return text, combined, false
+		}
+		*field.value = screened.Text
+		parts = append(parts, screened.Text)
+		combined.Matches = append(combined.Matches, screened.Matches...)
+		combined.Flagged = combined.Flagged || screened.Flagged
+	}
+	combined.Text = strings.Join(parts, "\n")
+
+	return text, combined, true
+}
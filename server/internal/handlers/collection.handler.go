@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	requestContext "drawer-service-backend/internal/context"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/middleware"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleReorderFavorites puts all of the requester's favorites in the given
+// order at once
+func HandleReorderFavorites(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	var body struct {
+		IDs []string `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "A list of favorite ids is required"})
+		return
+	}
+
+	err := queries.ReorderFavorites(appCtx.DB, c.Request.Context(), requester.ID, body.IDs)
+	if errors.Is(err, queries.ErrInvalidFavoriteOrder) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The order must list each of your favorites exactly once"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error reordering favorites of user %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder favorites"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func HandleGetCollections(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	collections, err := queries.GetCollections(appCtx.DB, c.Request.Context(), appCtx.Config, requester.ID)
+	if err != nil {
+		log.Printf("Error fetching collections of user %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch collections"})
+		return
+	}
+
+	c.JSON(http.StatusOK, collections)
+}
+
+func HandleGetCollection(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	collection, err := queries.GetCollection(appCtx.DB, c.Request.Context(), appCtx.Config, requester.ID, c.Param("id"))
+	if err != nil {
+		abortCollectionError(c, err, "Failed to fetch collection")
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+// HandleGetSharedCollection shows a shared collection to anyone with its
+// link, logged in or not
+func HandleGetSharedCollection(c *gin.Context) {
+	appCtx := requestContext.GetCtx(c)
+	code := strings.ToUpper(c.Param("code"))
+
+	collection, err := queries.GetSharedCollection(appCtx.DB, c.Request.Context(), appCtx.Config, code)
+	if err != nil {
+		abortCollectionError(c, err, "Failed to fetch collection")
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+func HandleCreateCollection(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	var body struct {
+		Name   string `json:"name" binding:"required"`
+		Shared bool   `json:"shared"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Collection name is required"})
+		return
+	}
+	name, ok := bindCollectionName(c, body.Name)
+	if !ok {
+		return
+	}
+
+	count, err := queries.CountCollections(appCtx.DB, c.Request.Context(), requester.ID)
+	if err != nil {
+		log.Printf("Error counting collections of user %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create collection"})
+		return
+	}
+	if count >= queries.MaxCollectionsPerUser {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("You can have at most %d collections", queries.MaxCollectionsPerUser)})
+		return
+	}
+
+	collection, err := queries.CreateCollection(appCtx.DB, c.Request.Context(), appCtx.Config, requester.ID, name, body.Shared)
+	if err != nil {
+		log.Printf("Error creating collection for user %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create collection"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, collection)
+}
+
+// HandleUpdateCollection renames a collection or starts or stops sharing it
+func HandleUpdateCollection(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	collectionID := c.Param("id")
+
+	var body struct {
+		Name   *string `json:"name"`
+		Shared *bool   `json:"shared"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if body.Name != nil {
+		name, ok := bindCollectionName(c, *body.Name)
+		if !ok {
+			return
+		}
+		body.Name = &name
+	}
+
+	err := queries.UpdateCollection(appCtx.DB, c.Request.Context(), requester.ID, collectionID, body.Name, body.Shared)
+	if err != nil {
+		abortCollectionError(c, err, "Failed to update collection")
+		return
+	}
+
+	collection, err := queries.GetCollection(appCtx.DB, c.Request.Context(), appCtx.Config, requester.ID, collectionID)
+	if err != nil {
+		abortCollectionError(c, err, "Failed to fetch collection")
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+func HandleDeleteCollection(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	err := queries.DeleteCollection(appCtx.DB, c.Request.Context(), requester.ID, c.Param("id"))
+	if err != nil {
+		abortCollectionError(c, err, "Failed to delete collection")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collection deleted"})
+}
+
+// HandleSetCollectionItems replaces what's in a collection with the given
+// favorites, in that order, so adding, removing and reordering are one call
+func HandleSetCollectionItems(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	collectionID := c.Param("id")
+
+	var body struct {
+		IDs []string `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "A list of favorite ids is required"})
+		return
+	}
+
+	err := queries.SetCollectionItems(appCtx.DB, c.Request.Context(), requester.ID, collectionID, body.IDs)
+	if err != nil {
+		abortCollectionError(c, err, "Failed to update collection")
+		return
+	}
+
+	collection, err := queries.GetCollection(appCtx.DB, c.Request.Context(), appCtx.Config, requester.ID, collectionID)
+	if err != nil {
+		abortCollectionError(c, err, "Failed to fetch collection")
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+// bindCollectionName trims and checks a collection name. Shared collections
+// show their name to anyone, so it goes through the profanity filter the way
+// usernames do.
+func bindCollectionName(c *gin.Context, name string) (string, bool) {
+	appCtx := requestContext.GetCtx(c)
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Collection name is required"})
+		return "", false
+	}
+	if utf8.RuneCountInString(name) > queries.MaxCollectionNameLength {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Collection name max length is %d characters", queries.MaxCollectionNameLength)})
+		return "", false
+	}
+
+	screened, ok := checkScreened(c, appCtx.TextFilter.CheckName(name), "Collection name contains language that isn't allowed")
+	return screened.Text, ok
+}
+
+func abortCollectionError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, queries.ErrCollectionNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+	case errors.Is(err, queries.ErrFavoriteNotFound):
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Only your own favorites can be added to a collection"})
+	case errors.Is(err, queries.ErrDuplicateFavorite):
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "A favorite can only be in a collection once"})
+	default:
+		// Shared collections are fetched without a user, so log the path
+		log.Printf("Collection request %s %s failed: %v", c.Request.Method, c.Request.URL.Path, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
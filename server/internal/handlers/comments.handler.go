@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
 	"drawer-service-backend/internal/achievements"
 	requestContext "drawer-service-backend/internal/context"
 	"drawer-service-backend/internal/db/models"
 	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/mentions"
 	"drawer-service-backend/internal/middleware"
 	"drawer-service-backend/internal/notifications"
+	"drawer-service-backend/internal/textfilter"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -18,7 +22,6 @@ import (
 
 func HandleAddCommentToSubmission(c *gin.Context) {
 	requester := middleware.GetUser(c)
-	appCtx := requestContext.GetCtx(c)
 
 	submissionID := c.Param("id")
 	if submissionID == "" {
@@ -26,13 +29,64 @@ func HandleAddCommentToSubmission(c *gin.Context) {
 		return
 	}
 
+	screened, ok := bindCommentText(c)
+	if !ok || !checkCanViewSubmission(c, requester.ID, submissionID) || !checkNotSpoiler(c, requester.ID, submissionID) {
+		return
+	}
+
+	addComment(c, submissionID, "", "", screened)
+}
+
+// HandleReplyToComment answers a comment. Replying to a reply adds to the
+// same thread, threads are only one level deep.
+func HandleReplyToComment(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	submissionID := c.Param("id")
+	commentID := c.Param("commentId")
+	if submissionID == "" || commentID == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Submission ID and comment ID are required"})
+		return
+	}
+
+	screened, ok := bindCommentText(c)
+	if !ok || !checkCanViewSubmission(c, requester.ID, submissionID) || !checkNotSpoiler(c, requester.ID, submissionID) {
+		return
+	}
+
+	commentSubmissionID, parentAuthorID, threadID, err := queries.GetReplyTarget(appCtx.DB, c.Request.Context(), commentID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error fetching comment %s to reply to: %v", commentID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to add reply"})
+		return
+	}
+	if err != nil || commentSubmissionID != submissionID {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	addComment(c, submissionID, threadID, parentAuthorID, screened)
+}
+
+func bindCommentText(c *gin.Context) (textfilter.Result, bool) {
 	var body struct {
 		Text string `json:"text" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil || body.Text == "" {
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Text is required"})
-		return
+		return textfilter.Result{}, false
 	}
+	return screenText(c, body.Text)
+}
+
+// addComment stores the comment, as a reply when parentID is set, notifies
+// the submission owner, the author of the comment being answered and the
+// friends it mentions, and responds with the new comment.
+func addComment(c *gin.Context, submissionID string, parentID string, parentAuthorID string, screened textfilter.Result) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	text := screened.Text
 
 	// Get submission owner for notification
 	var submissionOwnerID string
@@ -43,18 +97,24 @@ func HandleAddCommentToSubmission(c *gin.Context) {
 	}
 
 	insertSQL := `
-		INSERT INTO comments (submission_id, user_id, text)
-		VALUES (?, ?, ?)
+		INSERT INTO comments (submission_id, user_id, text, parent_id)
+		VALUES (?, ?, ?, ?)
 		RETURNING id, created_at
 	`
 	var commentID int64
 	var createdAt time.Time
-	err = appCtx.DB.QueryRowContext(c.Request.Context(), insertSQL, submissionID, requester.ID, body.Text).Scan(&commentID, &createdAt)
+	err = appCtx.DB.QueryRowContext(c.Request.Context(), insertSQL, submissionID, requester.ID, text,
+		sql.NullString{String: parentID, Valid: parentID != ""}).Scan(&commentID, &createdAt)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment"})
 		return
 	}
 
+	if screened.Flagged {
+		updateTextFlag(c, screened, queries.ReportContentComment, fmt.Sprintf("%d", commentID), requester.ID)
+	}
+	mentioned, added := storeMentions(c, fmt.Sprintf("%d", commentID), text)
+
 	resp := models.Comment{
 		ID: fmt.Sprintf("%d", commentID),
 		User: models.User{
@@ -63,20 +123,31 @@ func HandleAddCommentToSubmission(c *gin.Context) {
 			Email:     requester.Email,
 			CreatedAt: requester.CreatedAt,
 		},
-		Text:      body.Text,
+		Text:      text,
 		CreatedAt: createdAt,
 		Reactions: []models.Reaction{},
 		Counts:    []models.ReactionCount{},
+		ParentID:  parentID,
+		Mentions:  mentioned,
 	}
 
-	// Send notification to submission owner (in background)
-	if submissionOwnerID != "" && submissionOwnerID != requester.ID {
+	// Send notification to submission owner (in background). An owner whose
+	// own comment was answered only gets the reply notification.
+	if submissionOwnerID != "" && submissionOwnerID != requester.ID && submissionOwnerID != parentAuthorID {
 		go func() {
 			if err := notifications.NotifyUserOfComment(appCtx.DB, requester.ID, requester.Username, submissionOwnerID, submissionID, appCtx.Config); err != nil {
 				log.Printf("Failed to send comment notification: %v", err)
 			}
 		}()
 	}
+	if parentAuthorID != "" && parentAuthorID != requester.ID {
+		go func() {
+			if err := notifications.NotifyUserOfReply(appCtx.DB, requester.ID, requester.Username, parentAuthorID, submissionID, appCtx.Config); err != nil {
+				log.Printf("Failed to send reply notification: %v", err)
+			}
+		}()
+	}
+	notifyMentioned(c, submissionID, added, submissionOwnerID, parentAuthorID)
 
 	go func() {
 		achievementService := achievements.NewAchievementService(appCtx.DB, context.Background(), requester.ID)
@@ -89,13 +160,146 @@ func HandleAddCommentToSubmission(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// storeMentions resolves the @mentions in a comment's text against the
+// requester's friends and stores them. It returns the mentions and the users
+// newly mentioned by this version of the text. Failing to store them doesn't
+// fail the comment, it just goes without.
+func storeMentions(c *gin.Context, commentID string, text string) ([]models.Mention, []string) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	mentioned, err := queries.ResolveMentions(appCtx.DB, c.Request.Context(), requester.ID, mentions.Parse(text))
+	if err != nil {
+		log.Printf("Error resolving mentions in comment %s: %v", commentID, err)
+		return []models.Mention{}, nil
+	}
+	added, err := queries.SetCommentMentions(appCtx.DB, c.Request.Context(), commentID, mentioned)
+	if err != nil {
+		log.Printf("Error storing mentions of comment %s: %v", commentID, err)
+		return []models.Mention{}, nil
+	}
+	return mentioned, added
+}
+
+// notifyMentioned lets mentioned users who can see the submission know, in
+// the background. Users in skip are already notified of the comment another
+// way.
+func notifyMentioned(c *gin.Context, submissionID string, userIDs []string, skip ...string) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	skipped := map[string]bool{requester.ID: true}
+	for _, userID := range skip {
+		skipped[userID] = true
+	}
+	for _, userID := range userIDs {
+		if skipped[userID] {
+			continue
+		}
+		go func(userID string) {
+			visible, err := queries.CanViewSubmission(appCtx.DB, context.Background(), submissionID, userID)
+			if err != nil || !visible {
+				return
+			}
+			if err := notifications.NotifyUserOfMention(appCtx.DB, requester.ID, requester.Username, userID, submissionID, appCtx.Config); err != nil {
+				log.Printf("Failed to send mention notification: %v", err)
+			}
+		}(userID)
+	}
+}
+
+// commentRef looks up the :commentId comment and makes sure it belongs to the
+// :id submission, aborting the request otherwise.
+func commentRef(c *gin.Context) (string, queries.CommentRef, bool) {
+	appCtx := requestContext.GetCtx(c)
+	commentID := c.Param("commentId")
+
+	ref, err := queries.GetCommentRef(appCtx.DB, c.Request.Context(), commentID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error fetching comment %s: %v", commentID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comment"})
+		return "", queries.CommentRef{}, false
+	}
+	if err != nil || ref.SubmissionID != c.Param("id") {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return "", queries.CommentRef{}, false
+	}
+	return commentID, ref, true
+}
+
+func HandleEditComment(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	screened, ok := bindCommentText(c)
+	if !ok {
+		return
+	}
+	text := screened.Text
+	commentID, ref, ok := commentRef(c)
+	if !ok {
+		return
+	}
+	if ref.AuthorID != requester.ID {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You can only edit your own comments"})
+		return
+	}
+
+	editedAt, err := queries.UpdateCommentText(appCtx.DB, c.Request.Context(), commentID, text)
+	if err != nil {
+		log.Printf("Error editing comment %s by user %s: %v", commentID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit comment"})
+		return
+	}
+
+	updateTextFlag(c, screened, queries.ReportContentComment, commentID, requester.ID)
+	mentioned, added := storeMentions(c, commentID, text)
+	notifyMentioned(c, ref.SubmissionID, added)
+
+	c.JSON(http.StatusOK, gin.H{"id": commentID, "text": text, "editedAt": editedAt, "mentions": mentioned})
+}
+
+// HandleDeleteComment lets the comment's author, the owner of the submission
+// it's on and admins remove a comment
+func HandleDeleteComment(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	commentID, ref, ok := commentRef(c)
+	if !ok {
+		return
+	}
+	if ref.AuthorID != requester.ID && ref.SubmissionOwnerID != requester.ID && requester.Role != "admin" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You cannot delete this comment"})
+		return
+	}
+
+	if err := queries.DeleteComment(appCtx.DB, c.Request.Context(), commentID); err != nil {
+		log.Printf("Error deleting comment %s by user %s: %v", commentID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+
+	log.Printf("User %s deleted comment %s", requester.ID, commentID)
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
+
 func HandleCommentToggleReaction(c *gin.Context) {
 	requester := middleware.GetUser(c)
 	appCtx := requestContext.GetCtx(c)
 
-	commentID := c.Param("reactionId")
-	if commentID == "" {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Comment ID is required"})
+	commentID, ref, ok := commentRef(c)
+	if !ok || !checkCanViewSubmission(c, requester.ID, ref.SubmissionID) || !checkNotSpoiler(c, requester.ID, ref.SubmissionID) {
+		return
+	}
+	visible, err := queries.CanViewComment(appCtx.DB, c.Request.Context(), commentID, requester.ID)
+	if err != nil {
+		log.Printf("Error checking visibility of comment %s for %s: %v", commentID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comment"})
+		return
+	}
+	if !visible {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
 		return
 	}
 
@@ -107,20 +311,12 @@ func HandleCommentToggleReaction(c *gin.Context) {
 		return
 	}
 
-	// Validate reaction ID
-	validReactions := map[string]bool{
-		"heart":     true,
-		"cry-laugh": true,
-		"face-meh":  true,
-		"thumbs-up": true,
-	}
-	if !validReactions[body.ReactionID] {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid reaction ID"})
+	if !checkReactionAllowed(c, "comment", commentID, body.ReactionID) {
 		return
 	}
 
 	// Toggle the reaction
-	err := queries.ToggleReaction(appCtx.DB, c.Request.Context(), requester.ID, "comment", commentID, body.ReactionID)
+	err = queries.ToggleReaction(appCtx.DB, c.Request.Context(), requester.ID, "comment", commentID, body.ReactionID)
 	if err != nil {
 		log.Printf("Error toggling reaction for comment %s by user %s: %v", commentID, requester.ID, err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to toggle reaction"})
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"database/sql"
+	requestContext "drawer-service-backend/internal/context"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/middleware"
+	"drawer-service-backend/internal/utils"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetContest returns the current contest week and the votes the
+// requester cast in it
+func HandleGetContest(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	now := time.Now()
+	start, end := queries.ContestWeek(now)
+
+	votes, err := queries.GetContestVotes(appCtx.DB, c.Request.Context(), requester.ID, start, end)
+	if err != nil {
+		log.Printf("Error fetching contest votes of user %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch votes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"week":    start,
+		"weekEnd": end,
+		"today":   utils.GetFormattedDate(now),
+		"votes":   votes,
+	})
+}
+
+// HandleVoteForSubmission casts the requester's vote of the day for a
+// friend's drawing of today's prompt. Voting again the same day moves the
+// vote to the new drawing.
+func HandleVoteForSubmission(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	ctx := c.Request.Context()
+	submissionID := c.Param("id")
+
+	if !checkCanViewSubmission(c, requester.ID, submissionID) || !checkNotSpoiler(c, requester.ID, submissionID) {
+		return
+	}
+
+	target, err := queries.GetVoteTarget(appCtx.DB, ctx, submissionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+			return
+		}
+		log.Printf("Error fetching submission %s to vote for: %v", submissionID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submission"})
+		return
+	}
+
+	if target.UserID == requester.ID {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "You can't vote for your own drawing"})
+		return
+	}
+	if target.Kind != queries.SubmissionKindDaily {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Only daily drawings can be voted for"})
+		return
+	}
+	today := utils.GetFormattedDate(time.Now())
+	if target.Day != today {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "You can only vote for today's drawings"})
+		return
+	}
+
+	friends, err := queries.AreFriends(appCtx.DB, ctx, requester.ID, target.UserID)
+	if err != nil {
+		log.Printf("Error checking friendship between %s and %s: %v", requester.ID, target.UserID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to cast vote"})
+		return
+	}
+	if !friends {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You can only vote for your friends' drawings"})
+		return
+	}
+
+	if err := queries.CastContestVote(appCtx.DB, ctx, requester.ID, today, submissionID); err != nil {
+		log.Printf("Error casting vote of user %s for submission %s: %v", requester.ID, submissionID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to cast vote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vote cast", "day": today, "submissionId": submissionID})
+}
+
+// HandleRemoveVote takes back the requester's vote for a drawing. Like
+// casting one, this is only possible on the day of the drawing, so past
+// days' votes stay as they were counted.
+func HandleRemoveVote(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	ctx := c.Request.Context()
+	submissionID := c.Param("id")
+
+	target, err := queries.GetVoteTarget(appCtx.DB, ctx, submissionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+			return
+		}
+		log.Printf("Error fetching submission %s to remove vote for: %v", submissionID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submission"})
+		return
+	}
+	today := utils.GetFormattedDate(time.Now())
+	if target.Day != today {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "You can only change votes for today's drawings"})
+		return
+	}
+
+	removed, err := queries.RemoveContestVote(appCtx.DB, ctx, requester.ID, today, submissionID)
+	if err != nil {
+		log.Printf("Error removing vote of user %s for submission %s: %v", requester.ID, submissionID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove vote"})
+		return
+	}
+	if !removed {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "You haven't voted for this drawing"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vote removed"})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"drawer-service-backend/internal/achievements"
@@ -9,9 +10,15 @@ import (
 	"drawer-service-backend/internal/db/queries"
 	"drawer-service-backend/internal/middleware"
 	"drawer-service-backend/internal/notifications"
+	"drawer-service-backend/internal/palette"
+	"drawer-service-backend/internal/phash"
 	"drawer-service-backend/internal/storage"
+	"drawer-service-backend/internal/strokes"
 	"drawer-service-backend/internal/utils"
 	"errors"
+	"fmt"
+	"image"
+	"image/png"
 	"log"
 	"net/http"
 	"time"
@@ -19,12 +26,46 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	// Largest flattened drawing a submit form can carry. A PNG of the largest
+	// canvas fits in it several times over.
+	maxSubmissionImageBytes = 4 << 20
+	// Room for the image, the stroke log and the text fields of a submit form
+	maxSubmissionFormBytes = maxSubmissionImageBytes + strokes.MaxDataBytes + 64<<10
+)
+
 type DailyPromptResponse struct {
 	Day         string       `json:"day"`
 	Colors      []string     `json:"colors"`
 	Prompt      string       `json:"prompt"`
+	PaletteMode string       `json:"paletteMode"`
 	IsCompleted bool         `json:"isCompleted"`
 	CreatedBy   *models.User `json:"createdBy"`
+	GroupID     string       `json:"groupId,omitempty"`
+}
+
+// getPromptForContext returns the group's prompt for the day when the user
+// draws in the context of a group that has one, and the daily prompt otherwise
+func getPromptForContext(appCtx *requestContext.AppContext, ctx context.Context, userID string, groupID string, day string) (models.DailyPrompt, error) {
+	if groupID != "" {
+		role, err := queries.GetGroupRole(appCtx.DB, ctx, groupID, userID)
+		if err != nil {
+			return models.DailyPrompt{}, err
+		}
+		if role == "" {
+			return models.DailyPrompt{}, queries.ErrGroupNotFound
+		}
+
+		prompt, err := queries.GetGroupPrompt(appCtx.DB, ctx, groupID, day)
+		if err == nil {
+			return prompt, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return models.DailyPrompt{}, err
+		}
+	}
+
+	return queries.GetDailyPrompt(appCtx.DB, ctx, day)
 }
 
 func HandleGetDailyPrompt(c *gin.Context) {
@@ -43,10 +84,13 @@ func HandleGetDailyPrompt(c *gin.Context) {
 		return
 	}
 
-	prompt, err := queries.GetDailyPrompt(appCtx.DB, c.Request.Context(), today)
+	prompt, err := getPromptForContext(appCtx, c.Request.Context(), userID, c.Query("group"), today)
 
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, queries.ErrGroupNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, ErrorResponse{Message: "Group not found"})
+			return
+		} else if errors.Is(err, sql.ErrNoRows) {
 			log.Printf("No daily prompt found for today (%s)", today)
 			c.AbortWithStatusJSON(http.StatusNoContent, ErrorResponse{Message: "There is no daily prompt for today."})
 			return
@@ -62,7 +106,9 @@ func HandleGetDailyPrompt(c *gin.Context) {
 		Day:         prompt.Day,
 		Colors:      prompt.Colors,
 		Prompt:      prompt.Prompt,
+		PaletteMode: prompt.PaletteMode,
 		CreatedBy:   prompt.CreatedBy,
+		GroupID:     prompt.GroupID,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -74,6 +120,10 @@ func HandleSubmitDailyPrompt(c *gin.Context) {
 	today := utils.GetFormattedDate(time.Now())
 	requester := middleware.GetUser(c)
 
+	if !limitSubmissionForm(c) {
+		return
+	}
+
 	alreadySubmittedToday, err := queries.CheckUserSubmittedToday(appCtx.DB, ctx, requester.ID)
 	if err != nil {
 		log.Printf("Error checking existing submission for user %s, day %s: %v",
@@ -89,25 +139,95 @@ func HandleSubmitDailyPrompt(c *gin.Context) {
 		return
 	}
 
-	file, err := c.FormFile("image")
-	if err != nil {
-		log.Printf("Error getting image file for user %s: %v",
-			utils.MaskEmail(requester.Email), err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, Error("No image file provided"))
+	visibility := c.DefaultPostForm("visibility", queries.SubmissionVisibilityFriends)
+	if !queries.IsValidSubmissionVisibility(visibility) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, Error("Visibility must be one of private, friends or public"))
 		return
 	}
 
-	buf, err := utils.ReadFileToBuffer(file)
-	if err != nil {
-		log.Printf("Error reading image file for user %s: %v",
-			utils.MaskEmail(requester.Email), err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, Error("Failed to read image file"))
+	text, screened, ok := readSubmissionText(c)
+	if !ok {
+		return
+	}
+
+	// The prompt is only needed for the palette, submissions without one still go through
+	prompt, promptErr := getPromptForContext(appCtx, ctx, requester.ID, c.PostForm("groupId"), today)
+	if errors.Is(promptErr, queries.ErrGroupNotFound) {
+		c.AbortWithStatusJSON(http.StatusNotFound, Error("Group not found"))
+		return
+	}
+	hasPrompt := promptErr == nil
+	if promptErr != nil && !errors.Is(promptErr, sql.ErrNoRows) {
+		log.Printf("Error fetching daily prompt for %s: %v", today, promptErr)
+	}
+
+	var groupID *string
+	if hasPrompt && prompt.GroupID != "" {
+		groupID = &prompt.GroupID
+	}
+
+	drawing, ok := readSubmissionImage(c, prompt, promptErr)
+	if !ok {
 		return
 	}
+	var paletteCompliant *bool
+	if hasPrompt && palette.Mode(prompt.PaletteMode) != palette.MODE_OFF {
+		result, err := palette.Analyze(drawing.rendered, prompt.Colors)
+		if err != nil {
+			log.Printf("Error checking palette compliance for user %s: %v", utils.MaskEmail(requester.Email), err)
+		} else {
+			paletteCompliant = &result.Compliant
+		}
+
+		if err == nil && !result.Compliant && palette.Mode(prompt.PaletteMode) == palette.MODE_REJECT {
+			log.Printf("Rejected off-palette submission from user %s (%.1f%% off palette)",
+				utils.MaskEmail(requester.Email), result.OffPaletteRatio*100)
+			c.AbortWithStatusJSON(http.StatusBadRequest, Error("Your drawing uses colors outside of today's palette"))
+			return
+		}
+	}
+
+	var (
+		pHash             *string
+		duplicateOf       *string
+		duplicateDistance *int
+	)
+	hash := phash.Hash(drawing.rendered)
+	formatted := phash.Format(hash)
+	pHash = &formatted
+
+	since := utils.GetFormattedDate(time.Now().AddDate(0, 0, -phash.LookbackDays))
+	recent, err := queries.GetRecentSubmissionHashes(appCtx.DB, ctx, requester.ID, since)
+	if err != nil {
+		log.Printf("Error fetching recent submission hashes for user %s: %v", utils.MaskEmail(requester.Email), err)
+	} else {
+		candidates := make([]string, 0, len(recent))
+		for _, r := range recent {
+			candidates = append(candidates, r.Hash)
+		}
+
+		if index, distance := phash.FindDuplicate(hash, candidates); index >= 0 {
+			if phash.Mode(appCtx.Config.DuplicateMode) == phash.MODE_REJECT {
+				log.Printf("Rejected duplicate submission from user %s, matches %s (distance %d)",
+					utils.MaskEmail(requester.Email), recent[index].SubmissionID, distance)
+				c.AbortWithStatusJSON(http.StatusConflict, Error("This drawing looks the same as one you have already submitted"))
+				return
+			}
+			duplicateOf = &recent[index].SubmissionID
+			duplicateDistance = &distance
+		}
+	}
 
 	submissionID, err := queries.InsertSubmissionRecord(appCtx.DB, ctx, queries.InsertSubmissionRecordParams{
-		UserID: requester.ID,
-		Day:    today,
+		UserID:            requester.ID,
+		Day:               today,
+		PaletteCompliant:  paletteCompliant,
+		PHash:             pHash,
+		DuplicateOf:       duplicateOf,
+		DuplicateDistance: duplicateDistance,
+		GroupID:           groupID,
+		Visibility:        visibility,
+		Text:              text,
 	})
 
 	if err != nil {
@@ -117,31 +237,25 @@ func HandleSubmitDailyPrompt(c *gin.Context) {
 		return
 	}
 
-	var imageURL string
-
-	if appCtx.Config.Env != "development" {
-		storageService := storage.NewStorageService(appCtx.Config)
-
-		url, err := storageService.UploadSubmission(requester.ID, submissionID, buf)
-		if err != nil {
-			log.Printf("Error uploading image to S3 for user %s: %v",
-				utils.MaskEmail(requester.Email), err)
-			c.AbortWithStatusJSON(http.StatusInternalServerError, Error("Error uploading image"))
-			return
-		}
+	if screened.Flagged {
+		updateTextFlag(c, screened, queries.ReportContentSubmission, submissionID, requester.ID)
+	}
 
-		imageURL = url
-	} else {
-		imageURL = "/example.png"
+	imageURL, ok := storeSubmissionImage(c, submissionID, drawing)
+	if !ok {
+		return
 	}
 
 	log.Printf("User %s successfully submitted drawing for %s", utils.MaskEmail(requester.Email), today)
 
-	go func() {
-		if err := notifications.NotifyFriendsOfSubmission(appCtx.DB, requester.ID, requester.Username, submissionID, appCtx.Config); err != nil {
-			log.Printf("Failed to send friend notifications for user %s: %v", utils.MaskEmail(requester.Email), err)
-		}
-	}()
+	// Friends aren't told about drawings they can't open
+	if visibility != queries.SubmissionVisibilityPrivate {
+		go func() {
+			if err := notifications.NotifyFriendsOfSubmission(appCtx.DB, requester.ID, requester.Username, submissionID, appCtx.Config); err != nil {
+				log.Printf("Failed to send friend notifications for user %s: %v", utils.MaskEmail(requester.Email), err)
+			}
+		}()
+	}
 
 	go func() {
 		achievementService := achievements.NewAchievementService(appCtx.DB, context.Background(), requester.ID)
@@ -152,9 +266,161 @@ func HandleSubmitDailyPrompt(c *gin.Context) {
 	}()
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message":  "Drawing submitted successfully",
-		"day":      today,
-		"imageUrl": imageURL,
-		"id":       submissionID,
+		"message":          "Drawing submitted successfully",
+		"day":              today,
+		"imageUrl":         imageURL,
+		"id":               submissionID,
+		"hasStrokes":       drawing.strokeData != nil,
+		"paletteCompliant": paletteCompliant,
+		"visibility":       visibility,
+		"title":            text.Title,
+		"caption":          text.Caption,
+		"altText":          text.AltText,
 	})
 }
+
+// submissionImage is the drawing sent with a submission
+type submissionImage struct {
+	buf        []byte
+	rendered   image.Image
+	strokeData *models.StrokeData
+	thumbnail  []byte
+}
+
+// limitSubmissionForm caps the size of a submit form and reads it, aborting
+// the request when the form is too large
+func limitSubmissionForm(c *gin.Context) bool {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSubmissionFormBytes)
+	err := c.Request.ParseMultipartForm(maxSubmissionFormBytes)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, Error(fmt.Sprintf("Submission can be at most %d bytes", maxSubmissionFormBytes)))
+		return false
+	}
+	// Anything else is left to the checks of the fields themselves
+	return true
+}
+
+// readSubmissionImage reads the drawing from a submit form, rendering it from
+// the stroke log when the client sent one. promptErr is the error from
+// fetching the prompt, strokes can't be checked without its palette. It
+// aborts the request when the drawing is missing or invalid.
+func readSubmissionImage(c *gin.Context, prompt models.DailyPrompt, promptErr error) (submissionImage, bool) {
+	requester := middleware.GetUser(c)
+	var drawing submissionImage
+
+	// Stroke data is optional, older clients only send the flattened image
+	if rawStrokes := c.PostForm("strokes"); rawStrokes != "" {
+		if errors.Is(promptErr, sql.ErrNoRows) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, Error("There is no prompt for this day to check the strokes against"))
+			return drawing, false
+		}
+		if promptErr != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, Error("Failed to validate stroke data"))
+			return drawing, false
+		}
+
+		parsed, err := strokes.Parse(rawStrokes, prompt.Colors)
+		if err != nil {
+			log.Printf("Invalid stroke data from user %s: %v", utils.MaskEmail(requester.Email), err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, Error("Invalid stroke data: "+err.Error()))
+			return drawing, false
+		}
+		drawing.strokeData = &parsed
+
+		// Render the canonical image from the strokes instead of trusting the uploaded PNG
+		drawing.rendered, err = strokes.Render(parsed, prompt.Colors)
+		if err != nil {
+			log.Printf("Error rendering strokes for user %s: %v", utils.MaskEmail(requester.Email), err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, Error("Failed to render stroke data"))
+			return drawing, false
+		}
+
+		drawing.buf, err = strokes.EncodePNG(drawing.rendered)
+		if err != nil {
+			log.Printf("Error encoding rendered image for user %s: %v", utils.MaskEmail(requester.Email), err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, Error("Failed to render stroke data"))
+			return drawing, false
+		}
+	} else {
+		file, err := c.FormFile("image")
+		if err != nil {
+			log.Printf("Error getting image file for user %s: %v",
+				utils.MaskEmail(requester.Email), err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, Error("No image file provided"))
+			return drawing, false
+		}
+
+		drawing.buf, err = utils.ReadFileToBuffer(file)
+		if err != nil {
+			log.Printf("Error reading image file for user %s: %v",
+				utils.MaskEmail(requester.Email), err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, Error("Failed to read image file"))
+			return drawing, false
+		}
+
+		// Check the size before decoding, a small PNG can claim a huge image.
+		// The palette and duplicate checks need the pixels, so an image that
+		// can't be decoded is turned away.
+		header, err := png.DecodeConfig(bytes.NewReader(drawing.buf))
+		if err == nil && (header.Width > strokes.MaxCanvasSize || header.Height > strokes.MaxCanvasSize) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, Error(fmt.Sprintf("Image can be at most %dx%d pixels", strokes.MaxCanvasSize, strokes.MaxCanvasSize)))
+			return drawing, false
+		}
+		if err == nil {
+			drawing.rendered, err = png.Decode(bytes.NewReader(drawing.buf))
+		}
+		if err != nil {
+			log.Printf("Could not decode uploaded image for user %s: %v", utils.MaskEmail(requester.Email), err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, Error("Image must be a valid PNG"))
+			return drawing, false
+		}
+	}
+
+	// Thumbnails are a nice-to-have, the full image is still stored without one
+	thumbnail, err := strokes.EncodePNG(strokes.Thumbnail(drawing.rendered, strokes.ThumbnailSize))
+	if err != nil {
+		log.Printf("Error creating thumbnail for user %s: %v", utils.MaskEmail(requester.Email), err)
+	} else {
+		drawing.thumbnail = thumbnail
+	}
+
+	return drawing, true
+}
+
+// storeSubmissionImage saves the stroke log and uploads the image and
+// thumbnail of a recorded submission, returning the image's URL
+func storeSubmissionImage(c *gin.Context, submissionID string, drawing submissionImage) (string, bool) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	if drawing.strokeData != nil {
+		if err := queries.InsertSubmissionStrokes(appCtx.DB, c.Request.Context(), submissionID, *drawing.strokeData); err != nil {
+			// Keep the submission, it just won't be replayable
+			log.Printf("Failed to save stroke data for submission %s: %v", submissionID, err)
+		}
+	}
+
+	if appCtx.Config.Env == "development" {
+		return "/example.png", true
+	}
+
+	storageService := storage.NewStorageService(appCtx.Config)
+
+	imageURL, err := storageService.UploadSubmission(requester.ID, submissionID, drawing.buf)
+	if err != nil {
+		log.Printf("Error uploading image to S3 for user %s: %v",
+			utils.MaskEmail(requester.Email), err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, Error("Error uploading image"))
+		return "", false
+	}
+
+	if drawing.thumbnail != nil {
+		if _, err := storageService.UploadSubmissionThumbnail(requester.ID, submissionID, drawing.thumbnail); err != nil {
+			log.Printf("Error uploading thumbnail to S3 for user %s: %v",
+				utils.MaskEmail(requester.Email), err)
+		}
+	}
+
+	return imageURL, true
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"database/sql"
+	"drawer-service-backend/internal/contactsheet"
+	requestContext "drawer-service-backend/internal/context"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/imaging"
+	"drawer-service-backend/internal/middleware"
+	"drawer-service-backend/internal/storage"
+	"drawer-service-backend/internal/strokes"
+	"drawer-service-backend/internal/timelapse"
+	"drawer-service-backend/internal/utils"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetUserTimelapse serves an animated GIF of a user's drawings for a
+// month. The first request kicks off generation in the background and gets a
+// 202, later requests get the cached GIF once it's ready.
+func HandleGetUserTimelapse(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	ctx := c.Request.Context()
+
+	userID := c.Param("id")
+	month := c.Param("month")
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Month must be in YYYY-MM format"})
+		return
+	}
+
+	if !checkCanViewDrawings(c, requester.ID, userID) {
+		return
+	}
+
+	end := start.AddDate(0, 1, -1)
+	submissions, err := queries.GetUserSubmissionsBetween(appCtx.DB, ctx, userID, requester.ID, utils.GetFormattedDate(start), utils.GetFormattedDate(end))
+	if err != nil {
+		log.Printf("Error fetching submissions for user %s in %s: %v", userID, month, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timelapse"})
+		return
+	}
+	submissions, ok := hideUnlockedDrawings(c, requester.ID, userID, submissions)
+	if !ok {
+		return
+	}
+	if len(submissions) == 0 {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "No drawings found for this month"})
+		return
+	}
+
+	key := imaging.CacheKey(month, submissions)
+	data, err := timelapse.GetCached(appCtx.Config, userID, key)
+	if err != nil {
+		// Regenerating is cheaper than failing the request
+		log.Printf("Error fetching cached timelapse %s for user %s: %v", key, userID, err)
+	}
+	if data != nil {
+		c.Data(http.StatusOK, "image/gif", data)
+		return
+	}
+
+	timelapse.Start(appCtx.DB, appCtx.Config, userID, key, submissions)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "pending",
+		"message": "Your timelapse is being generated, check back shortly",
+	})
+}
+
+// HandleGetUserContactSheet serves a calendar grid PNG of a user's drawings.
+// The period is either a year (YYYY) or a month (YYYY-MM).
+func HandleGetUserContactSheet(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	ctx := c.Request.Context()
+
+	userID := c.Param("id")
+	period := c.Param("period")
+
+	var (
+		start, end time.Time
+		err        error
+	)
+	isYear := len(period) == 4
+	if isYear {
+		start, err = time.Parse("2006", period)
+		end = start.AddDate(1, 0, -1)
+	} else {
+		start, err = time.Parse("2006-01", period)
+		end = start.AddDate(0, 1, -1)
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Period must be in YYYY or YYYY-MM format"})
+		return
+	}
+
+	if !checkCanViewDrawings(c, requester.ID, userID) {
+		return
+	}
+
+	user, err := queries.GetUserByID(appCtx.DB, ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("Error fetching user %s for contact sheet: %v", userID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contact sheet"})
+		return
+	}
+
+	submissions, err := queries.GetUserSubmissionsBetween(appCtx.DB, ctx, userID, requester.ID, utils.GetFormattedDate(start), utils.GetFormattedDate(end))
+	if err != nil {
+		log.Printf("Error fetching submissions for user %s in %s: %v", userID, period, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contact sheet"})
+		return
+	}
+	submissions, ok := hideUnlockedDrawings(c, requester.ID, userID, submissions)
+	if !ok {
+		return
+	}
+
+	key := imaging.CacheKey(period, submissions)
+	var storageService *storage.StorageService
+	if appCtx.Config.Env != "development" {
+		storageService = storage.NewStorageService(appCtx.Config)
+		data, err := storageService.GetContactSheet(userID, key)
+		if err == nil {
+			c.Data(http.StatusOK, "image/png", data)
+			return
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Error fetching cached contact sheet %s for user %s: %v", key, userID, err)
+		}
+	}
+
+	var sheet image.Image
+	if isYear {
+		sheet = contactsheet.RenderYear(appCtx.DB, ctx, appCtx.Config, fmt.Sprintf("%s - %d", user.Username, start.Year()), start.Year(), submissions)
+	} else {
+		sheet = contactsheet.RenderMonth(appCtx.DB, ctx, appCtx.Config, fmt.Sprintf("%s - %s", user.Username, start.Format("January 2006")), start, submissions)
+	}
+
+	data, err := strokes.EncodePNG(sheet)
+	if err != nil {
+		log.Printf("Error encoding contact sheet %s for user %s: %v", key, userID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate contact sheet"})
+		return
+	}
+
+	if storageService != nil {
+		if _, err := storageService.UploadContactSheet(userID, key, data); err != nil {
+			log.Printf("Error caching contact sheet %s for user %s: %v", key, userID, err)
+		}
+	}
+
+	c.Data(http.StatusOK, "image/png", data)
+}
+
+// hideUnlockedDrawings leaves out the user's drawing of today's prompt until
+// the requester has drawn theirs. The cache key is made from what's left, so
+// a locked viewer never gets an image that has it.
+func hideUnlockedDrawings(c *gin.Context, requesterID string, userID string, submissions []models.UserPromptSubmission) ([]models.UserPromptSubmission, bool) {
+	if requesterID == userID {
+		return submissions, true
+	}
+
+	appCtx := requestContext.GetCtx(c)
+	submitted, err := queries.CheckUserSubmittedToday(appCtx.DB, c.Request.Context(), requesterID)
+	if err != nil {
+		log.Printf("Error checking whether user %s submitted today: %v", requesterID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submissions"})
+		return nil, false
+	}
+	if submitted {
+		return submissions, true
+	}
+
+	today := utils.GetFormattedDate(time.Now())
+	visible := make([]models.UserPromptSubmission, 0, len(submissions))
+	for _, submission := range submissions {
+		if submission.Day != today {
+			visible = append(visible, submission)
+		}
+	}
+	return visible, true
+}
+
+// checkCanViewDrawings aborts the request unless the user's profile
+// visibility lets the requester see their drawings
+func checkCanViewDrawings(c *gin.Context, requesterID string, userID string) bool {
+	appCtx := requestContext.GetCtx(c)
+	privacy, err := queries.GetPrivacySettings(appCtx.DB, c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return false
+		}
+		log.Printf("Error fetching privacy settings for user %s: %v", userID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check profile visibility"})
+		return false
+	}
+
+	canView, err := queries.CanViewProfile(appCtx.DB, c.Request.Context(), userID, requesterID, privacy.ProfileVisibility)
+	if err != nil {
+		log.Printf("Error checking profile visibility of %s for %s: %v", userID, requesterID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check profile visibility"})
+		return false
+	}
+	if !canView {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This user's drawings are private"})
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	requestContext "drawer-service-backend/internal/context"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/middleware"
+	"drawer-service-backend/internal/palette"
+	"drawer-service-backend/internal/strokes"
+	"drawer-service-backend/internal/utils"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxGroupNameLength        = 50
+	maxGroupDescriptionLength = 280
+	groupFeedDays             = 7
+)
+
+type CreateGroupRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+type GroupPromptRequest struct {
+	Prompt string   `json:"prompt" binding:"required"`
+	Colors []string `json:"colors" binding:"required"`
+	// One of off, reward, flag or reject. Defaults to flag
+	PaletteMode string `json:"paletteMode"`
+}
+
+// groupRole looks up the requester's role in the :id group, aborting with a
+// 404 when they aren't a member so groups can't be probed by ID.
+func groupRole(c *gin.Context, requesterID string) (string, string, bool) {
+	appCtx := requestContext.GetCtx(c)
+	groupID := c.Param("id")
+
+	role, err := queries.GetGroupRole(appCtx.DB, c.Request.Context(), groupID, requesterID)
+	if err != nil {
+		log.Printf("Error fetching role in group %s for user %s: %v", groupID, requesterID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group"})
+		return "", "", false
+	}
+	if role == "" {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return "", "", false
+	}
+	return groupID, role, true
+}
+
+// groupOwner is groupRole for actions only the owner may take
+func groupOwner(c *gin.Context, requesterID string) (string, bool) {
+	groupID, role, ok := groupRole(c, requesterID)
+	if !ok {
+		return "", false
+	}
+	if role != queries.GroupRoleOwner {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Only the group owner can do this"})
+		return "", false
+	}
+	return groupID, true
+}
+
+func HandleCreateGroup(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	var req CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	description := strings.TrimSpace(req.Description)
+	if name == "" || utf8.RuneCountInString(name) > maxGroupNameLength {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Group name must be between 1 and 50 characters"})
+		return
+	}
+	if utf8.RuneCountInString(description) > maxGroupDescriptionLength {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Group description cannot be longer than 280 characters"})
+		return
+	}
+
+	group, err := queries.CreateGroup(appCtx.DB, c.Request.Context(), requester.ID, name, description)
+	if err != nil {
+		log.Printf("Error creating group for user %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create group"})
+		return
+	}
+
+	log.Printf("User %s created group %s", requester.ID, group.ID)
+	c.JSON(http.StatusOK, group)
+}
+
+func HandleGetGroups(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	groups, err := queries.GetUserGroups(appCtx.DB, c.Request.Context(), requester.ID)
+	if err != nil {
+		log.Printf("Error fetching groups for user %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch groups"})
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+func HandleGetGroup(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	groupID := c.Param("id")
+
+	group, err := queries.GetGroup(appCtx.DB, c.Request.Context(), groupID, requester.ID)
+	if err != nil {
+		if errors.Is(err, queries.ErrGroupNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+			return
+		}
+		log.Printf("Error fetching group %s for user %s: %v", groupID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group"})
+		return
+	}
+
+	// Only the owner gets to see and share the invite code
+	if group.Role != queries.GroupRoleOwner {
+		group.InviteCode = ""
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+func HandleJoinGroup(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	code := strings.ToUpper(c.Param("code"))
+
+	groupID, err := queries.JoinGroup(appCtx.DB, c.Request.Context(), code, requester.ID)
+	if err != nil {
+		if errors.Is(err, queries.ErrGroupNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+			return
+		}
+		if errors.Is(err, queries.ErrAlreadyInGroup) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "You are already in this group"})
+			return
+		}
+		log.Printf("Error joining group with code %s for user %s: %v", code, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to join group"})
+		return
+	}
+
+	log.Printf("User %s joined group %s", requester.ID, groupID)
+	c.JSON(http.StatusOK, gin.H{"message": "Joined group", "groupId": groupID})
+}
+
+func HandleLeaveGroup(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	groupID, role, ok := groupRole(c, requester.ID)
+	if !ok {
+		return
+	}
+	if role == queries.GroupRoleOwner {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The owner cannot leave the group, delete it instead"})
+		return
+	}
+
+	if _, err := queries.RemoveGroupMember(appCtx.DB, c.Request.Context(), groupID, requester.ID); err != nil {
+		log.Printf("Error leaving group %s for user %s: %v", groupID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave group"})
+		return
+	}
+
+	log.Printf("User %s left group %s", requester.ID, groupID)
+	c.JSON(http.StatusOK, gin.H{"message": "Left group"})
+}
+
+func HandleRemoveGroupMember(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	groupID, ok := groupOwner(c, requester.ID)
+	if !ok {
+		return
+	}
+	memberID := c.Param("userId")
+
+	removed, err := queries.RemoveGroupMember(appCtx.DB, c.Request.Context(), groupID, memberID)
+	if err != nil {
+		log.Printf("Error removing %s from group %s: %v", memberID, groupID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	if !removed {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	log.Printf("User %s removed %s from group %s", requester.ID, memberID, groupID)
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+func HandleDeleteGroup(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	groupID, ok := groupOwner(c, requester.ID)
+	if !ok {
+		return
+	}
+
+	if err := queries.DeleteGroup(appCtx.DB, c.Request.Context(), groupID); err != nil {
+		log.Printf("Error deleting group %s: %v", groupID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete group"})
+		return
+	}
+
+	log.Printf("User %s deleted group %s", requester.ID, groupID)
+	c.JSON(http.StatusOK, gin.H{"message": "Group deleted"})
+}
+
+func HandleResetGroupInviteCode(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	groupID, ok := groupOwner(c, requester.ID)
+	if !ok {
+		return
+	}
+
+	code, err := queries.ResetGroupInviteCode(appCtx.DB, c.Request.Context(), groupID)
+	if err != nil {
+		log.Printf("Error resetting invite code for group %s: %v", groupID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset invite code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"inviteCode": code})
+}
+
+// HandleGetGroupFeed returns a week of the members' submissions, ending at
+// ?before (exclusive) or today when it isn't given
+func HandleGetGroupFeed(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	groupID, _, ok := groupRole(c, requester.ID)
+	if !ok {
+		return
+	}
+
+	end := time.Now().AddDate(0, 0, 1)
+	if before := c.Query("before"); before != "" {
+		parsed, err := time.Parse(utils.DateFormat, before)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+		end = parsed
+	}
+	toDay := utils.GetFormattedDate(end.AddDate(0, 0, -1))
+	fromDay := utils.GetFormattedDate(end.AddDate(0, 0, -groupFeedDays))
+
+	feed, err := queries.GetGroupFeed(appCtx.DB, c.Request.Context(), appCtx.Config, groupID, requester.ID, fromDay, toDay)
+	if err != nil {
+		log.Printf("Error fetching feed for group %s: %v", groupID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, feed)
+}
+
+// HandleGetGroupPrompts lists the group's prompts from today onwards
+func HandleGetGroupPrompts(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	groupID, _, ok := groupRole(c, requester.ID)
+	if !ok {
+		return
+	}
+
+	prompts, err := queries.GetGroupPrompts(appCtx.DB, c.Request.Context(), groupID, utils.GetFormattedDate(time.Now()))
+	if err != nil {
+		log.Printf("Error fetching prompts for group %s: %v", groupID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group prompts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, prompts)
+}
+
+// editableGroupPromptDay reads the :day param and makes sure the group's
+// prompt for it can still be changed: it can't be in the past or already
+// have been drawn
+func editableGroupPromptDay(c *gin.Context, groupID string) (string, bool) {
+	appCtx := requestContext.GetCtx(c)
+	day := c.Param("day")
+
+	if _, err := time.Parse(utils.DateFormat, day); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return "", false
+	}
+	if day < utils.GetFormattedDate(time.Now()) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Prompts for past days cannot be changed"})
+		return "", false
+	}
+
+	hasSubmissions, err := queries.GroupPromptHasSubmissions(appCtx.DB, c.Request.Context(), groupID, day)
+	if err != nil {
+		log.Printf("Error checking submissions for group %s prompt on %s: %v", groupID, day, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check group prompt"})
+		return "", false
+	}
+	if hasSubmissions {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Members have already drawn this prompt"})
+		return "", false
+	}
+	return day, true
+}
+
+func HandleSetGroupPrompt(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	groupID, ok := groupOwner(c, requester.ID)
+	if !ok {
+		return
+	}
+	day, ok := editableGroupPromptDay(c, groupID)
+	if !ok {
+		return
+	}
+
+	var req GroupPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if len(req.Colors) == 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "At least one color is required"})
+		return
+	}
+	for _, color := range req.Colors {
+		if _, err := strokes.ParseHexColor(color); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid color format. Colors must be hex format (e.g., #FF0000)"})
+			return
+		}
+	}
+
+	if req.PaletteMode == "" {
+		req.PaletteMode = string(palette.MODE_FLAG)
+	}
+	if !palette.IsValidMode(req.PaletteMode) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid palette mode. Must be one of off, reward, flag or reject"})
+		return
+	}
+
+	err := queries.SetGroupPrompt(appCtx.DB, c.Request.Context(), groupID, day, req.Prompt, req.Colors, req.PaletteMode, requester.ID)
+	if errors.Is(err, queries.ErrNoDailyPrompt) {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "There is no daily prompt for this day yet"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error setting prompt for group %s on %s: %v", groupID, day, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to save group prompt"})
+		return
+	}
+
+	log.Printf("User %s set the prompt for group %s on %s", requester.ID, groupID, day)
+	c.JSON(http.StatusOK, gin.H{"message": "Group prompt saved"})
+}
+
+func HandleDeleteGroupPrompt(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	groupID, ok := groupOwner(c, requester.ID)
+	if !ok {
+		return
+	}
+	day, ok := editableGroupPromptDay(c, groupID)
+	if !ok {
+		return
+	}
+
+	deleted, err := queries.DeleteGroupPrompt(appCtx.DB, c.Request.Context(), groupID, day)
+	if err != nil {
+		log.Printf("Error deleting prompt for group %s on %s: %v", groupID, day, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete group prompt"})
+		return
+	}
+	if !deleted {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Group prompt not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Group prompt deleted"})
+}
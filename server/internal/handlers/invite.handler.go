@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"drawer-service-backend/internal/achievements"
+	requestContext "drawer-service-backend/internal/context"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/middleware"
+	"drawer-service-backend/internal/stats"
+	"drawer-service-backend/internal/utils"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxInviteExpiryDays = 365
+	maxInviteUses       = 1000
+)
+
+type CreateInviteCodeRequest struct {
+	// Both are optional, leaving them out makes a code that never runs out
+	ExpiresInDays *int `json:"expiresInDays"`
+	MaxUses       *int `json:"maxUses"`
+}
+
+func HandleCreateInviteCode(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	var req CreateInviteCodeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		if *req.ExpiresInDays < 1 || *req.ExpiresInDays > maxInviteExpiryDays {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Expiry must be between 1 and 365 days"})
+			return
+		}
+		expiry := time.Now().UTC().AddDate(0, 0, *req.ExpiresInDays)
+		expiresAt = &expiry
+	}
+	if req.MaxUses != nil && (*req.MaxUses < 1 || *req.MaxUses > maxInviteUses) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Max uses must be between 1 and 1000"})
+		return
+	}
+
+	invite, err := queries.CreateInviteCode(appCtx.DB, c.Request.Context(), requester.ID, expiresAt, req.MaxUses)
+	if err != nil {
+		log.Printf("Error creating invite code for user %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invite code"})
+		return
+	}
+	invite.URL = utils.GetInviteUrl(appCtx.Config, invite.Code)
+
+	c.JSON(http.StatusOK, invite)
+}
+
+func HandleGetInviteCodes(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	invites, err := queries.GetInviteCodes(appCtx.DB, c.Request.Context(), requester.ID)
+	if err != nil {
+		log.Printf("Error fetching invite codes for user %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invite codes"})
+		return
+	}
+	for i := range invites {
+		invites[i].URL = utils.GetInviteUrl(appCtx.Config, invites[i].Code)
+	}
+
+	c.JSON(http.StatusOK, invites)
+}
+
+func HandleDeleteInviteCode(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	code := strings.ToUpper(c.Param("code"))
+
+	deleted, err := queries.DeleteInviteCode(appCtx.DB, c.Request.Context(), requester.ID, code)
+	if err != nil {
+		log.Printf("Error deleting invite code %s for user %s: %v", code, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete invite code"})
+		return
+	}
+	if !deleted {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Invite code not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invite code deleted"})
+}
+
+// HandleGetInviteCode is public so the invite link can show who sent it
+// before the visitor has an account
+func HandleGetInviteCode(c *gin.Context) {
+	appCtx := requestContext.GetCtx(c)
+	code := strings.ToUpper(c.Param("code"))
+
+	inviter, err := queries.GetInviteCodeInviter(appCtx.DB, c.Request.Context(), code)
+	if err != nil {
+		status, message := inviteCodeError(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error fetching invite code %s: %v", code, err)
+		}
+		c.AbortWithStatusJSON(status, gin.H{"error": message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"inviter": models.PublicUser{
+			ID:         inviter.ID,
+			Username:   inviter.Username,
+			CreatedAt:  inviter.CreatedAt,
+			AvatarType: inviter.AvatarType,
+			AvatarURL:  inviter.AvatarURL,
+		},
+	})
+}
+
+// HandleRedeemInviteCode lets someone who already has an account, or who
+// logged in after following an invite link, use the code
+func HandleRedeemInviteCode(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	code := strings.ToUpper(c.Param("code"))
+
+	if err := redeemInviteCode(appCtx, c.Request.Context(), code, requester.ID, false); err != nil {
+		status, message := inviteCodeError(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error redeeming invite code %s for user %s: %v", code, requester.ID, err)
+		}
+		c.AbortWithStatusJSON(status, gin.H{"error": message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invite accepted"})
+}
+
+// redeemInviteCode befriends the user with the code's creator and updates
+// both users' friend stats, plus the creator's referrals if the user just
+// signed up with the code
+func redeemInviteCode(appCtx *requestContext.AppContext, ctx context.Context, code string, userID string, newUser bool) error {
+	inviterID, referred, err := queries.RedeemInviteCode(appCtx.DB, ctx, code, userID, newUser)
+	if err != nil {
+		return err
+	}
+	log.Printf("User %s redeemed invite code %s from %s (referral: %t)", userID, code, inviterID, referred)
+
+	go func() {
+		for _, id := range []string{userID, inviterID} {
+			if _, err := stats.CalculateFriendTotal(appCtx.DB, context.Background(), id, 0); err != nil {
+				log.Printf("Error recalculating friend total for %s: %v", id, err)
+			}
+			achievementService := achievements.NewAchievementService(appCtx.DB, context.Background(), id)
+			if err := achievementService.UpdateFriendAchievements(id); err != nil {
+				log.Printf("Error updating friend achievements for %s: %v", id, err)
+			}
+		}
+
+		if !referred {
+			return
+		}
+		if _, err := stats.CalculateReferralTotal(appCtx.DB, context.Background(), inviterID, 0); err != nil {
+			log.Printf("Error recalculating referral total for %s: %v", inviterID, err)
+		}
+		achievementService := achievements.NewAchievementService(appCtx.DB, context.Background(), inviterID)
+		if err := achievementService.UpdateReferralAchievements(inviterID); err != nil {
+			log.Printf("Error updating referral achievements for %s: %v", inviterID, err)
+		}
+	}()
+
+	return nil
+}
+
+func inviteCodeError(err error) (int, string) {
+	switch {
+	case errors.Is(err, queries.ErrInviteCodeNotFound):
+		return http.StatusNotFound, "Invite code not found"
+	case errors.Is(err, queries.ErrInviteCodeExpired):
+		return http.StatusGone, "This invite has expired"
+	case errors.Is(err, queries.ErrInviteCodeOwn):
+		return http.StatusBadRequest, "You cannot use your own invite"
+	case errors.Is(err, queries.ErrInviteBlocked):
+		return http.StatusForbidden, "You cannot use this invite"
+	case errors.Is(err, queries.ErrAlreadyFriends):
+		return http.StatusConflict, "You are already friends"
+	}
+	return http.StatusInternalServerError, "Failed to redeem invite code"
+}
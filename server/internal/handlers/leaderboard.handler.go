@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	requestContext "drawer-service-backend/internal/context"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/middleware"
+	"drawer-service-backend/internal/stats"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetLeaderboard ranks the requester and their friends on the :metric
+// over the window query parameter, the last week by default. Friends who hide
+// their profile aren't ranked, and neither are friends who hide their streak
+// on streak leaderboards.
+func HandleGetLeaderboard(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	metric := c.Param("metric")
+	if !stats.IsValidLeaderboardMetric(metric) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Leaderboard must be one of current-streak, max-streak, drawings, reactions-received or comments"})
+		return
+	}
+	window := c.DefaultQuery("window", string(stats.WINDOW_WEEK))
+	if !stats.IsValidLeaderboardWindow(window) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Window must be one of week, month or all-time"})
+		return
+	}
+
+	members, err := queries.GetLeaderboardMembers(appCtx.DB, c.Request.Context(), requester.ID, stats.LeaderboardMetric(metric).IsStreak())
+	if err != nil {
+		log.Printf("Error fetching leaderboard members for user %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard"})
+		return
+	}
+
+	entries, err := stats.RankLeaderboard(appCtx.DB, c.Request.Context(), members, stats.LeaderboardMetric(metric), stats.LeaderboardWindow(window))
+	if err != nil {
+		log.Printf("Error ranking %s leaderboard for user %s: %v", metric, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"metric":  metric,
+		"window":  window,
+		"since":   stats.LeaderboardWindow(window).Since(time.Now()),
+		"entries": entries,
+	})
+}
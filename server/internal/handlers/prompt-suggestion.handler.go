@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	requestContext "drawer-service-backend/internal/context"
 	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/db/queries"
 	"drawer-service-backend/internal/middleware"
 	"log"
 	"net/http"
@@ -25,6 +26,10 @@ func SuggestPrompt(c *gin.Context) {
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Prompt text is required"})
 		return
 	}
+	screened, ok := screenText(c, body.Prompt)
+	if !ok {
+		return
+	}
 
 	var suggestionsToday int
 	checkQuery := `
@@ -46,13 +51,17 @@ func SuggestPrompt(c *gin.Context) {
         VALUES (?, ?, ?)
     `
 
-	_, err = appCtx.DB.ExecContext(c.Request.Context(), query, uuid.New().String(), userID, body.Prompt)
+	suggestionID := uuid.New().String()
+	_, err = appCtx.DB.ExecContext(c.Request.Context(), query, suggestionID, userID, screened.Text)
 
 	if err != nil {
 		log.Printf("Failed to save suggestion %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit suggestion"})
 		return
 	}
+	if screened.Flagged {
+		updateTextFlag(c, screened, queries.ReportContentPromptSuggestion, suggestionID, userID)
+	}
 
 	c.JSON(http.StatusCreated, gin.H{"message": "Successfully submitted prompt suggestion"})
 }
@@ -64,6 +73,7 @@ func GetAllPromptSuggestions(c *gin.Context) {
         SELECT p.id, p.prompt, p.created_at, u.id, u.username, u.email, u.role, u.created_at, u.avatar_type, u.avatar_url
         FROM prompt_suggestions p
         LEFT JOIN users u ON u.id = p.user_id
+        WHERE p.hidden_at IS NULL
         ORDER BY p.created_at DESC
     `
 
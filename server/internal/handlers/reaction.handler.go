@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"database/sql"
+	requestContext "drawer-service-backend/internal/context"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/middleware"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReactionTypeRequest struct {
+	ID       string `json:"id"`
+	Emoji    string `json:"emoji"`
+	AssetURL string `json:"assetUrl"`
+	// One of submission, comment or both. Defaults to both
+	AppliesTo string `json:"appliesTo"`
+	// Reward that has to be unlocked to use the reaction, empty for none
+	RewardID  *string `json:"rewardId"`
+	Active    *bool   `json:"active"`
+	SortOrder *int    `json:"sortOrder"`
+}
+
+// HandleGetReactionTypes lists the reactions users can currently pick from,
+// marking the ones the requester hasn't unlocked yet
+func HandleGetReactionTypes(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	reactionTypes, err := queries.GetReactionTypes(appCtx.DB, c.Request.Context(), requester.ID, false)
+	if err != nil {
+		log.Printf("Error fetching reaction types: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reactions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reactions": reactionTypes})
+}
+
+// HandleGetAllReactionTypes lists the whole reaction catalog for admins,
+// including retired reactions
+func HandleGetAllReactionTypes(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	reactionTypes, err := queries.GetReactionTypes(appCtx.DB, c.Request.Context(), requester.ID, true)
+	if err != nil {
+		log.Printf("Error fetching reaction types: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reactions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reactions": reactionTypes})
+}
+
+// HandleCreateReactionType adds a reaction to the catalog, such as a
+// seasonal one that gets deactivated again later
+func HandleCreateReactionType(c *gin.Context) {
+	adminUser := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	var req ReactionTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Invalid create reaction request body: %v", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	req.ID = strings.TrimSpace(req.ID)
+	if req.ID == "" || strings.ContainsAny(req.ID, " /") {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Reaction ID is required and cannot contain spaces or slashes"})
+		return
+	}
+
+	reactionType := models.ReactionType{
+		ID:        req.ID,
+		AppliesTo: queries.ReactionAppliesToBoth,
+		Active:    true,
+	}
+	if !applyReactionTypeRequest(c, &reactionType, req) {
+		return
+	}
+
+	if _, err := queries.GetReactionType(appCtx.DB, c.Request.Context(), req.ID, adminUser.ID); err == nil {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Reaction already exists"})
+		return
+	}
+	if err := queries.CreateReactionType(appCtx.DB, c.Request.Context(), reactionType); err != nil {
+		log.Printf("Error creating reaction %s: %v", req.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reaction"})
+		return
+	}
+
+	log.Printf("Admin user %s created reaction %s", adminUser.ID, req.ID)
+	respondWithReactionType(c, http.StatusCreated, reactionType.ID)
+}
+
+// HandleUpdateReactionType changes a catalog entry. Fields left out of the
+// body keep their value, so retiring a reaction is just {"active": false}.
+func HandleUpdateReactionType(c *gin.Context) {
+	adminUser := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	reactionID := c.Param("id")
+
+	var req ReactionTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Invalid update reaction request body: %v", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	reactionType, err := queries.GetReactionType(appCtx.DB, c.Request.Context(), reactionID, adminUser.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Reaction not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching reaction %s: %v", reactionID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reaction"})
+		return
+	}
+	if !applyReactionTypeRequest(c, &reactionType, req) {
+		return
+	}
+
+	if err := queries.UpdateReactionType(appCtx.DB, c.Request.Context(), reactionType); err != nil {
+		log.Printf("Error updating reaction %s: %v", reactionID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reaction"})
+		return
+	}
+
+	log.Printf("Admin user %s updated reaction %s", adminUser.ID, reactionID)
+	respondWithReactionType(c, http.StatusOK, reactionID)
+}
+
+// respondWithReactionType responds with the stored catalog entry, so Unlocked
+// reflects the admin's own rewards
+func respondWithReactionType(c *gin.Context, status int, reactionID string) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	reactionType, err := queries.GetReactionType(appCtx.DB, c.Request.Context(), reactionID, requester.ID)
+	if err != nil {
+		log.Printf("Error fetching reaction %s: %v", reactionID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reaction"})
+		return
+	}
+	c.JSON(status, reactionType)
+}
+
+// applyReactionTypeRequest copies the fields set in req onto reactionType,
+// aborting the request when one is invalid
+func applyReactionTypeRequest(c *gin.Context, reactionType *models.ReactionType, req ReactionTypeRequest) bool {
+	appCtx := requestContext.GetCtx(c)
+
+	if req.Emoji != "" {
+		reactionType.Emoji = req.Emoji
+	}
+	if req.AssetURL != "" {
+		reactionType.AssetURL = req.AssetURL
+	}
+	if req.AppliesTo != "" {
+		if !queries.IsValidReactionAppliesTo(req.AppliesTo) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid appliesTo. Must be one of submission, comment or both"})
+			return false
+		}
+		reactionType.AppliesTo = req.AppliesTo
+	}
+	if req.RewardID != nil {
+		if *req.RewardID != "" {
+			exists, err := queries.RewardUnlockExists(appCtx.DB, c.Request.Context(), *req.RewardID)
+			if err != nil {
+				log.Printf("Error checking reward %s: %v", *req.RewardID, err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check reward"})
+				return false
+			}
+			if !exists {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Reward not found"})
+				return false
+			}
+		}
+		reactionType.RewardID = *req.RewardID
+	}
+	if req.Active != nil {
+		reactionType.Active = *req.Active
+	}
+	if req.SortOrder != nil {
+		reactionType.SortOrder = *req.SortOrder
+	}
+
+	if reactionType.Emoji == "" && reactionType.AssetURL == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "An emoji or asset URL is required"})
+		return false
+	}
+	return true
+}
+
+// checkReactionAllowed makes sure the requester may leave reactionID on the
+// content: it has to be an active catalog reaction for that kind of content
+// whose reward they've unlocked. Taking back a reaction they already left is
+// always allowed, even once it's been retired.
+func checkReactionAllowed(c *gin.Context, contentType string, contentID string, reactionID string) bool {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	reacted, err := queries.HasReacted(appCtx.DB, c.Request.Context(), requester.ID, contentType, contentID, reactionID)
+	if err != nil {
+		log.Printf("Error checking reaction %s on %s %s by user %s: %v", reactionID, contentType, contentID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to toggle reaction"})
+		return false
+	}
+	if reacted {
+		return true
+	}
+
+	reactionType, err := queries.GetReactionType(appCtx.DB, c.Request.Context(), reactionID, requester.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error fetching reaction %s: %v", reactionID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to toggle reaction"})
+		return false
+	}
+	if err != nil || !reactionType.Active || !queries.ReactionAppliesTo(reactionType, contentType) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid reaction ID"})
+		return false
+	}
+	if !reactionType.Unlocked {
+		log.Printf("Required reward %s not unlocked for user %s", reactionType.RewardID, requester.ID)
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("You have not unlocked the required %s reward", reactionType.RewardID)})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	requestContext "drawer-service-backend/internal/context"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/middleware"
+	"drawer-service-backend/internal/notifications"
+	"drawer-service-backend/internal/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleRemixSubmission answers the :id submission with a drawing of the
+// requester's own. Remixes don't take up the requester's daily drawing, are
+// drawn with the palette of the drawing they answer and let its artist know.
+// The form is the same as for daily submissions.
+func HandleRemixSubmission(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	ctx := c.Request.Context()
+	parentID := c.Param("id")
+
+	if !limitSubmissionForm(c) {
+		return
+	}
+
+	if !checkCanViewSubmission(c, requester.ID, parentID) || !checkNotSpoiler(c, requester.ID, parentID) {
+		return
+	}
+
+	target, err := queries.GetRemixTarget(appCtx.DB, ctx, parentID)
+	if err != nil {
+		log.Printf("Error fetching submission %s to remix: %v", parentID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, Error("Failed to fetch submission"))
+		return
+	}
+
+	blocked, err := queries.IsBlockedEitherWay(appCtx.DB, ctx, requester.ID, target.UserID)
+	if err != nil {
+		log.Printf("Error checking blocks between %s and %s: %v", requester.ID, target.UserID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, Error("Failed to fetch submission"))
+		return
+	}
+	if blocked {
+		c.AbortWithStatusJSON(http.StatusForbidden, Error("You can't remix this drawing"))
+		return
+	}
+
+	remixesToday, err := queries.CountRemixesOnDay(appCtx.DB, ctx, requester.ID, utils.GetFormattedDate(time.Now()))
+	if err != nil {
+		log.Printf("Error counting remixes of user %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, Error("Server error checking submission status"))
+		return
+	}
+	if remixesToday >= queries.MaxRemixesPerDay {
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, Error(fmt.Sprintf("You can only make %d remixes per day", queries.MaxRemixesPerDay)))
+		return
+	}
+
+	visibility := c.DefaultPostForm("visibility", queries.SubmissionVisibilityFriends)
+	if !queries.IsValidSubmissionVisibility(visibility) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, Error("Visibility must be one of private, friends or public"))
+		return
+	}
+
+	text, screened, ok := readSubmissionText(c)
+	if !ok {
+		return
+	}
+
+	prompt, groupID, promptErr := getRemixPrompt(appCtx, ctx, requester.ID, target)
+	if promptErr != nil && !errors.Is(promptErr, sql.ErrNoRows) {
+		log.Printf("Error fetching prompt of submission %s: %v", parentID, promptErr)
+	}
+
+	drawing, ok := readSubmissionImage(c, prompt, promptErr)
+	if !ok {
+		return
+	}
+
+	remixID, err := queries.InsertSubmissionRecord(appCtx.DB, ctx, queries.InsertSubmissionRecordParams{
+		UserID:     requester.ID,
+		Day:        target.Day,
+		GroupID:    groupID,
+		Visibility: visibility,
+		RemixOf:    &target.ID,
+		Text:       text,
+	})
+	if err != nil {
+		log.Printf("Failed to insert remix of %s for user %s: %v", parentID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, Error("Failed to record submission"))
+		return
+	}
+
+	if screened.Flagged {
+		updateTextFlag(c, screened, queries.ReportContentSubmission, remixID, requester.ID)
+	}
+
+	imageURL, ok := storeSubmissionImage(c, remixID, drawing)
+	if !ok {
+		return
+	}
+
+	log.Printf("User %s remixed submission %s as %s", requester.ID, parentID, remixID)
+
+	// The artist isn't told about remixes they can't open
+	go func() {
+		visible, err := queries.CanViewSubmission(appCtx.DB, context.Background(), remixID, target.UserID)
+		if err != nil || !visible {
+			return
+		}
+		if err := notifications.NotifyUserOfRemix(appCtx.DB, requester.ID, requester.Username, target.UserID, remixID, appCtx.Config); err != nil {
+			log.Printf("Failed to send remix notification to %s: %v", target.UserID, err)
+		}
+	}()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Remix submitted successfully",
+		"day":        target.Day,
+		"imageUrl":   imageURL,
+		"id":         remixID,
+		"remixOf":    target.ID,
+		"hasStrokes": drawing.strokeData != nil,
+		"visibility": visibility,
+		"title":      text.Title,
+		"caption":    text.Caption,
+		"altText":    text.AltText,
+	})
+}
+
+// getRemixPrompt returns the prompt the remixed drawing answered, so the
+// remix is drawn with the same palette. The remix only joins the drawing's
+// group when the requester is a member, otherwise it's shown with the daily
+// prompt of that day.
+func getRemixPrompt(appCtx *requestContext.AppContext, ctx context.Context, userID string, target queries.RemixTarget) (models.DailyPrompt, *string, error) {
+	if target.GroupID != nil {
+		role, err := queries.GetGroupRole(appCtx.DB, ctx, *target.GroupID, userID)
+		if err != nil {
+			return models.DailyPrompt{}, nil, err
+		}
+		if role != "" {
+			prompt, err := getPromptForContext(appCtx, ctx, userID, *target.GroupID, target.Day)
+			return prompt, target.GroupID, err
+		}
+	}
+
+	prompt, err := queries.GetDailyPrompt(appCtx.DB, ctx, target.Day)
+	return prompt, nil, err
+}
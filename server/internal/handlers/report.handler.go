@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"database/sql"
+	requestContext "drawer-service-backend/internal/context"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/middleware"
+	"drawer-service-backend/internal/notifications"
+	"drawer-service-backend/internal/storage"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxReportDetailsLength = 500
+
+type ReportRequest struct {
+	// One of spam, harassment, hate, inappropriate or other
+	Reason  string `json:"reason" binding:"required"`
+	Details string `json:"details"`
+}
+
+type ModerationRequest struct {
+	// One of dismiss, hide, delete, warn or suspend
+	Action string `json:"action" binding:"required"`
+	// How long a suspension lasts, defaults to a week
+	Days int `json:"days"`
+}
+
+func HandleReportSubmission(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	submissionID := c.Param("id")
+
+	if !checkCanViewSubmission(c, requester.ID, submissionID) {
+		return
+	}
+	fileReport(c, queries.ReportContentSubmission, submissionID)
+}
+
+func HandleReportComment(c *gin.Context) {
+	requester := middleware.GetUser(c)
+
+	commentID, ref, ok := commentRef(c)
+	if !ok || !checkCanViewSubmission(c, requester.ID, ref.SubmissionID) {
+		return
+	}
+	fileReport(c, queries.ReportContentComment, commentID)
+}
+
+func HandleReportUser(c *gin.Context) {
+	fileReport(c, queries.ReportContentUser, c.Param("id"))
+}
+
+func HandleReportPromptSuggestion(c *gin.Context) {
+	fileReport(c, queries.ReportContentPromptSuggestion, c.Param("id"))
+}
+
+// fileReport records the requester's report of a piece of content. Content
+// reported by enough different users is hidden until a moderator gets to it.
+func fileReport(c *gin.Context, contentType string, contentID string) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	var req ReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !queries.IsValidReportReason(req.Reason) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid reason. Must be one of spam, harassment, hate, inappropriate or other"})
+		return
+	}
+	if len(req.Details) > maxReportDetailsLength {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Details max length is 500 characters"})
+		return
+	}
+
+	ownerID, err := queries.GetReportedContentOwner(appCtx.DB, c.Request.Context(), contentType, contentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Content not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching reported %s %s: %v", contentType, contentID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to report content"})
+		return
+	}
+	if ownerID == requester.ID {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "You cannot report your own content"})
+		return
+	}
+
+	created, err := queries.CreateReport(appCtx.DB, c.Request.Context(), requester.ID, contentType, contentID, ownerID, req.Reason, req.Details)
+	if err != nil {
+		log.Printf("Error reporting %s %s by user %s: %v", contentType, contentID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to report content"})
+		return
+	}
+	if !created {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "You already reported this"})
+		return
+	}
+	log.Printf("User %s reported %s %s for %s", requester.ID, contentType, contentID, req.Reason)
+
+	threshold := appCtx.Config.ReportHideThreshold
+	if threshold > 0 && queries.IsHideableContent(contentType) {
+		count, err := queries.CountOpenReports(appCtx.DB, c.Request.Context(), contentType, contentID)
+		if err != nil {
+			log.Printf("Error counting reports of %s %s: %v", contentType, contentID, err)
+		} else if count >= threshold {
+			if hidden, err := queries.SetContentHidden(appCtx.DB, c.Request.Context(), contentType, contentID, true); err != nil {
+				log.Printf("Error hiding %s %s: %v", contentType, contentID, err)
+			} else if hidden {
+				log.Printf("Hid %s %s after %d reports", contentType, contentID, count)
+			}
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Thanks, a moderator will take a look"})
+}
+
+// HandleGetReportQueue lists reported content for moderators. It defaults to
+// open reports and takes status, type and reason filters.
+func HandleGetReportQueue(c *gin.Context) {
+	appCtx := requestContext.GetCtx(c)
+
+	filter := queries.ReportFilter{
+		Status:      c.DefaultQuery("status", queries.ReportStatusOpen),
+		ContentType: c.Query("type"),
+		Reason:      c.Query("reason"),
+	}
+	if filter.Status == "all" {
+		filter.Status = ""
+	}
+	if filter.Status != "" && filter.Status != queries.ReportStatusOpen && filter.Status != queries.ReportStatusResolved {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid status. Must be one of open, resolved or all"})
+		return
+	}
+	if filter.ContentType != "" && !queries.IsValidReportContentType(filter.ContentType) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid type. Must be one of submission, comment, user or prompt_suggestion"})
+		return
+	}
+	if filter.Reason != "" && !queries.IsValidReportReason(filter.Reason) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid reason"})
+		return
+	}
+
+	queue, err := queries.GetReportQueue(appCtx.DB, c.Request.Context(), filter, appCtx.Config)
+	if err != nil {
+		log.Printf("Error fetching report queue: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reports": queue})
+}
+
+// HandleGetContentReports lists every report of one piece of content
+func HandleGetContentReports(c *gin.Context) {
+	appCtx := requestContext.GetCtx(c)
+	contentType := c.Param("type")
+	contentID := c.Param("id")
+
+	if !queries.IsValidReportContentType(contentType) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid content type"})
+		return
+	}
+
+	reports, err := queries.GetReports(appCtx.DB, c.Request.Context(), contentType, contentID)
+	if err != nil {
+		log.Printf("Error fetching reports of %s %s: %v", contentType, contentID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		return
+	}
+	if len(reports) == 0 {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "No reports for this content"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reports": reports})
+}
+
+// HandleModerateContent acts on reported content, or text the profanity
+// filter flagged, and resolves its open reports and flag. Dismissing shows
+// auto-hidden content again, deleting a username resets it, and warnings and
+// suspensions go to whoever posted the content.
+func HandleModerateContent(c *gin.Context) {
+	adminUser := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	ctx := c.Request.Context()
+	contentType := c.Param("type")
+	contentID := c.Param("id")
+
+	var req ModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !queries.IsValidReportAction(req.Action) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid action. Must be one of dismiss, hide, delete, warn or suspend"})
+		return
+	}
+	if !queries.IsValidReportContentType(contentType) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid content type"})
+		return
+	}
+
+	reportCount, ownerID, err := queries.GetReportedOwner(appCtx.DB, ctx, contentType, contentID)
+	if err != nil {
+		log.Printf("Error fetching reports of %s %s: %v", contentType, contentID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate content"})
+		return
+	}
+	if reportCount == 0 {
+		// Text the profanity filter flagged is moderated the same way
+		ownerID, err = queries.GetTextFlagOwner(appCtx.DB, ctx, contentType, contentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "No reports for this content"})
+			return
+		}
+		if err != nil {
+			log.Printf("Error fetching text flag of %s %s: %v", contentType, contentID, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate content"})
+			return
+		}
+	}
+
+	switch req.Action {
+	case queries.ReportActionDismiss:
+		if queries.IsHideableContent(contentType) {
+			_, err = queries.SetContentHidden(appCtx.DB, ctx, contentType, contentID, false)
+		}
+
+	case queries.ReportActionHide:
+		if !queries.IsHideableContent(contentType) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Users can't be hidden, delete resets their username"})
+			return
+		}
+		_, err = queries.SetContentHidden(appCtx.DB, ctx, contentType, contentID, true)
+
+	case queries.ReportActionDelete:
+		if _, lookupErr := queries.GetReportedContentOwner(appCtx.DB, ctx, contentType, contentID); errors.Is(lookupErr, sql.ErrNoRows) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Content was already deleted"})
+			return
+		}
+		err = deleteReportedContent(c, contentType, contentID, ownerID)
+
+	case queries.ReportActionWarn, queries.ReportActionSuspend:
+		if ownerID == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The content has no author to act on"})
+			return
+		}
+		if req.Action == queries.ReportActionWarn {
+			err = queries.WarnUser(appCtx.DB, ctx, ownerID)
+			if err == nil {
+				go func() {
+					if err := notifications.NotifyUserOfWarning(appCtx.DB, ownerID, contentType, appCtx.Config); err != nil {
+						log.Printf("Failed to send warning notification to %s: %v", ownerID, err)
+					}
+				}()
+			}
+			break
+		}
+
+		owner, lookupErr := queries.GetUserFromDB(appCtx.DB, ctx, ownerID)
+		if lookupErr == nil && owner.Role == "admin" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Admins can't be suspended"})
+			return
+		}
+		if req.Days == 0 {
+			req.Days = 7
+		}
+		if req.Days < 1 || req.Days > 365 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Suspensions last between 1 and 365 days"})
+			return
+		}
+		err = queries.SuspendUser(appCtx.DB, ctx, ownerID, time.Now().AddDate(0, 0, req.Days))
+	}
+	if err != nil {
+		log.Printf("Error applying %s to %s %s: %v", req.Action, contentType, contentID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate content"})
+		return
+	}
+
+	resolved, err := queries.ResolveReports(appCtx.DB, ctx, contentType, contentID, req.Action, adminUser.ID)
+	if err != nil {
+		log.Printf("Error resolving reports of %s %s: %v", contentType, contentID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve reports"})
+		return
+	}
+
+	flagResolved, err := queries.ResolveTextFlag(appCtx.DB, ctx, contentType, contentID, adminUser.ID)
+	if err != nil {
+		log.Printf("Error resolving text flag of %s %s: %v", contentType, contentID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve reports"})
+		return
+	}
+
+	log.Printf("Admin user %s applied %s to %s %s, resolving %d reports", adminUser.ID, req.Action, contentType, contentID, resolved)
+	c.JSON(http.StatusOK, gin.H{"message": "Reports resolved", "resolved": resolved, "flagResolved": flagResolved})
+}
+
+// deleteReportedContent removes a reported piece of content. Usernames can't
+// be removed so they're reset instead.
+func deleteReportedContent(c *gin.Context, contentType string, contentID string, ownerID string) error {
+	appCtx := requestContext.GetCtx(c)
+	ctx := c.Request.Context()
+
+	switch contentType {
+	case queries.ReportContentSubmission:
+		if err := queries.DeleteSubmission(appCtx.DB, ctx, contentID); err != nil {
+			return err
+		}
+		if appCtx.Config.Env != "development" {
+			go func() {
+				if err := storage.NewStorageService(appCtx.Config).DeleteSubmission(ownerID, contentID); err != nil {
+					log.Printf("Error deleting images of submission %s: %v", contentID, err)
+				}
+			}()
+		}
+		return nil
+	case queries.ReportContentComment:
+		return queries.DeleteComment(appCtx.DB, ctx, contentID)
+	case queries.ReportContentPromptSuggestion:
+		return queries.DeletePromptSuggestion(appCtx.DB, ctx, contentID)
+	case queries.ReportContentUser:
+		username, err := queries.ResetUsername(appCtx.DB, ctx, contentID)
+		if err == nil {
+			log.Printf("Reset username of user %s to %s", contentID, username)
+		}
+		return err
+	}
+	return nil
+}
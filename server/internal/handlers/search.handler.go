@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	requestContext "drawer-service-backend/internal/context"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/middleware"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+)
+
+// HandleSearchSubmissions looks for drawings the requester can see by their
+// prompt, title, caption, alt text and comments. Pages are picked with
+// ?limit and ?offset, the response says which offset comes next.
+func HandleSearchSubmissions(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	search := strings.TrimSpace(c.Query("q"))
+	if search == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSearchLimit)))
+	if err != nil || limit < 1 || limit > maxSearchLimit {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Limit must be between 1 and %d", maxSearchLimit)})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Offset must be a positive number"})
+		return
+	}
+
+	response, err := queries.SearchSubmissions(appCtx.DB, c.Request.Context(), appCtx.Config, requester.ID, search, limit, offset)
+	if errors.Is(err, queries.ErrEmptySearch) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Search for at least one word"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error searching submissions for %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to search submissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
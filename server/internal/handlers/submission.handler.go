@@ -9,10 +9,14 @@ import (
 	"drawer-service-backend/internal/db/queries"
 	"drawer-service-backend/internal/middleware"
 	"drawer-service-backend/internal/notifications"
+	"drawer-service-backend/internal/replay"
+	"drawer-service-backend/internal/strokes"
 	"drawer-service-backend/internal/utils"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -33,25 +37,34 @@ func HandleGetSubmissionByID(c *gin.Context) {
 		SELECT
 			us.id,
 			us.day,
-			dp.colors,
-			dp.prompt,
+			COALESCE(gp.colors, dp.colors),
+			COALESCE(gp.prompt, dp.prompt),
 			u.id as user_id,
 			u.username,
 			u.email,
 			u.created_at,
 			u.avatar_type,
 			u.avatar_url,
-			us.created_at as submission_created_at
+			us.created_at as submission_created_at,
+			us.visibility,
+			us.title,
+			us.caption,
+			us.alt_text,
+			us.kind,
+			COALESCE(us.remix_of, '')
 		FROM user_submissions us
 		JOIN daily_prompts dp ON us.day = dp.day
+		LEFT JOIN group_prompts gp ON gp.group_id = us.group_id AND gp.day = us.day
 		JOIN users u ON us.user_id = u.id
-		WHERE us.id = ?
+		WHERE us.id = ? AND ` + queries.SubmissionVisibleTo("us") + `
 	`
 	var (
-		subID, day, colorsJSON, prompt, userID, username, email, userAvatarType, userAvatarURL string
-		userCreatedAt, submissionCreatedAt                                                     sql.NullTime
+		subID, day, colorsJSON, prompt, userID, username, email, userAvatarType, userAvatarURL, visibility, title, caption, altText, kind, remixOf string
+		userCreatedAt, submissionCreatedAt                                                                                                         sql.NullTime
 	)
-	err := appCtx.DB.QueryRowContext(c.Request.Context(), query, submissionID).Scan(
+	// Submissions hidden from the requester look like they don't exist
+	args := append([]interface{}{submissionID}, queries.SubmissionVisibleToArgs(requester.ID)...)
+	err := appCtx.DB.QueryRowContext(c.Request.Context(), query, args...).Scan(
 		&subID,
 		&day,
 		&colorsJSON,
@@ -63,6 +76,12 @@ func HandleGetSubmissionByID(c *gin.Context) {
 		&userAvatarType,
 		&userAvatarURL,
 		&submissionCreatedAt,
+		&visibility,
+		&title,
+		&caption,
+		&altText,
+		&kind,
+		&remixOf,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -80,12 +99,13 @@ func HandleGetSubmissionByID(c *gin.Context) {
 
 	// Query comments for this submission
 	commentsQuery := `
-		SELECT c.id, c.text, u.id, u.username, u.email, u.created_at, u.avatar_type, u.avatar_url, c.created_at
+		SELECT c.id, c.text, u.id, u.username, u.email, u.created_at, u.avatar_type, u.avatar_url, c.created_at, c.parent_id, c.edited_at, c.deleted_at IS NOT NULL
 		FROM comments c
 		JOIN users u ON c.user_id = u.id
-		WHERE c.submission_id = ?
+		JOIN user_submissions us ON us.id = c.submission_id
+		WHERE c.submission_id = ? AND ` + queries.NotBlockedBy("us.user_id", "c.user_id") + ` AND ` + queries.CommentVisibleTo("c") + `
 		ORDER BY c.created_at ASC`
-	rows, err := appCtx.DB.QueryContext(c.Request.Context(), commentsQuery, submissionID)
+	rows, err := appCtx.DB.QueryContext(c.Request.Context(), commentsQuery, submissionID, requester.ID)
 	if err != nil {
 		log.Printf("Error fetching comments for submission %s: %v", submissionID, err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
@@ -95,13 +115,15 @@ func HandleGetSubmissionByID(c *gin.Context) {
 	comments := []models.Comment{}
 	for rows.Next() {
 		var commentID, commentText, commentUserID, commentUsername, commentUserEmail, commentUserAvatarType, commentUserAvatarURL string
-		var commentUserCreatedAt, commentCreatedAt sql.NullTime
-		err := rows.Scan(&commentID, &commentText, &commentUserID, &commentUsername, &commentUserEmail, &commentUserCreatedAt, &commentUserAvatarType, &commentUserAvatarURL, &commentCreatedAt)
+		var commentUserCreatedAt, commentCreatedAt, commentEditedAt sql.NullTime
+		var commentParentID sql.NullString
+		var commentDeleted bool
+		err := rows.Scan(&commentID, &commentText, &commentUserID, &commentUsername, &commentUserEmail, &commentUserCreatedAt, &commentUserAvatarType, &commentUserAvatarURL, &commentCreatedAt, &commentParentID, &commentEditedAt, &commentDeleted)
 		if err != nil {
 			log.Printf("Error scanning comment row: %v", err)
 			continue
 		}
-		comments = append(comments, models.Comment{
+		comment := models.Comment{
 			ID: commentID,
 			User: models.User{
 				ID:         commentUserID,
@@ -113,7 +135,13 @@ func HandleGetSubmissionByID(c *gin.Context) {
 			},
 			Text:      commentText,
 			CreatedAt: commentCreatedAt.Time,
-		})
+			ParentID:  commentParentID.String,
+			Deleted:   commentDeleted,
+		}
+		if commentEditedAt.Valid {
+			comment.EditedAt = &commentEditedAt.Time
+		}
+		comments = append(comments, comment)
 	}
 
 	imageUrl := utils.GetImageUrl(appCtx.Config, utils.GetSubmissionFilename(userID, subID))
@@ -151,6 +179,14 @@ func HandleGetSubmissionByID(c *gin.Context) {
 		}
 		comments[i].Counts = commentCounts
 	}
+	commentRefs := make([]*models.Comment, len(comments))
+	for i := range comments {
+		commentRefs[i] = &comments[i]
+	}
+	if err := queries.AttachMentions(appCtx.DB, c.Request.Context(), commentRefs); err != nil {
+		log.Printf("Error fetching mentions for submission %s: %v", subID, err)
+	}
+	comments = queries.NestComments(comments)
 
 	resp := models.UserPromptSubmission{
 		ID:     subID,
@@ -165,11 +201,41 @@ func HandleGetSubmissionByID(c *gin.Context) {
 			AvatarType: userAvatarType,
 			AvatarURL:  userAvatarURL,
 		},
-		ImageUrl:  imageUrl,
-		Comments:  comments,
-		Reactions: submissionReactions,
-		Counts:    submissionCounts,
-		CreatedAt: submissionCreatedAt.Time,
+		ImageUrl:   imageUrl,
+		Comments:   comments,
+		Reactions:  submissionReactions,
+		Counts:     submissionCounts,
+		CreatedAt:  submissionCreatedAt.Time,
+		Visibility: visibility,
+		Title:      title,
+		Caption:    caption,
+		AltText:    altText,
+		Kind:       kind,
+		RemixOf:    remixOf,
+	}
+
+	hasStrokes, err := queries.HasSubmissionStrokes(appCtx.DB, c.Request.Context(), subID)
+	if err != nil {
+		log.Printf("Error checking stroke data for submission %s: %v", subID, err)
+	}
+	resp.HasStrokes = hasStrokes
+
+	// The remix chain is extra, the submission is still shown without it
+	if remixOf != "" {
+		resp.Lineage, err = queries.GetRemixLineage(appCtx.DB, c.Request.Context(), appCtx.Config, subID, requester.ID)
+		if err != nil {
+			log.Printf("Error fetching lineage of submission %s: %v", subID, err)
+		}
+	}
+	resp.Remixes, err = queries.GetRemixes(appCtx.DB, c.Request.Context(), appCtx.Config, subID, requester.ID)
+	if err != nil {
+		log.Printf("Error fetching remixes of submission %s: %v", subID, err)
+	}
+
+	if err := queries.LockSpoilers(appCtx.DB, c.Request.Context(), requester.ID, []*models.UserPromptSubmission{&resp}); err != nil {
+		log.Printf("Error checking whether submission %s is locked for %s: %v", subID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submission"})
+		return
 	}
 
 	// Set isFavorite if the requester is the owner and the submission is favorited
@@ -184,6 +250,146 @@ func HandleGetSubmissionByID(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// checkCanViewSubmission aborts the request with a 404 unless the submission
+// exists and the requester is allowed to see it.
+func checkCanViewSubmission(c *gin.Context, requesterID string, submissionID string) bool {
+	appCtx := requestContext.GetCtx(c)
+	visible, err := queries.CanViewSubmission(appCtx.DB, c.Request.Context(), submissionID, requesterID)
+	if err != nil {
+		log.Printf("Error checking visibility of submission %s for %s: %v", submissionID, requesterID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submission"})
+		return false
+	}
+	if !visible {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return false
+	}
+	return true
+}
+
+// checkNotSpoiler aborts the request when the submission is a drawing of
+// today's prompt and the requester hasn't drawn theirs yet.
+func checkNotSpoiler(c *gin.Context, requesterID string, submissionID string) bool {
+	appCtx := requestContext.GetCtx(c)
+	spoiler, err := queries.IsSpoiler(appCtx.DB, c.Request.Context(), submissionID, requesterID)
+	if err != nil {
+		log.Printf("Error checking whether submission %s is locked for %s: %v", submissionID, requesterID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submission"})
+		return false
+	}
+	if spoiler {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Draw today's prompt to see this drawing"})
+		return false
+	}
+	return true
+}
+
+// HandleGetSubmissionStrokes returns the stroke log used to replay a submission
+func HandleGetSubmissionStrokes(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	submissionID := c.Param("id")
+	if submissionID == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Submission ID is required"})
+		return
+	}
+	if !checkCanViewSubmission(c, requester.ID, submissionID) || !checkNotSpoiler(c, requester.ID, submissionID) {
+		return
+	}
+
+	submissionStrokes, err := queries.GetSubmissionStrokes(appCtx.DB, c.Request.Context(), submissionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "No stroke data for this submission"})
+			return
+		}
+		log.Printf("Error fetching strokes for submission %s: %v", submissionID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stroke data"})
+		return
+	}
+
+	c.JSON(http.StatusOK, submissionStrokes)
+}
+
+// HandleGetSubmissionReplayFrame renders one frame of a submission's replay as a PNG
+func HandleGetSubmissionReplayFrame(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	submissionID := c.Param("id")
+	if submissionID == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Submission ID is required"})
+		return
+	}
+
+	frame, err := strconv.Atoi(c.Param("frame"))
+	if err != nil || frame < 0 || frame >= strokes.ReplayFrames {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Frame must be between 0 and %d", strokes.ReplayFrames-1)})
+		return
+	}
+	if !checkCanViewSubmission(c, requester.ID, submissionID) || !checkNotSpoiler(c, requester.ID, submissionID) {
+		return
+	}
+
+	data, err := replay.GetCached(appCtx.Config, submissionID, frame)
+	if err != nil {
+		// Rendering again is cheaper than failing the request
+		log.Printf("Error fetching cached frame %d for submission %s: %v", frame, submissionID, err)
+	}
+	if data != nil {
+		c.Data(http.StatusOK, "image/png", data)
+		return
+	}
+
+	submissionStrokes, err := queries.GetSubmissionStrokes(appCtx.DB, c.Request.Context(), submissionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "No stroke data for this submission"})
+			return
+		}
+		log.Printf("Error fetching strokes for submission %s: %v", submissionID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stroke data"})
+		return
+	}
+
+	data, err = replay.Render(appCtx.Config, submissionStrokes, frame)
+	if err != nil {
+		log.Printf("Error rendering frame %d for submission %s: %v", frame, submissionID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to render replay frame"})
+		return
+	}
+
+	c.Data(http.StatusOK, "image/png", data)
+}
+
+func HandleUpdateSubmissionVisibility(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	submissionID := c.Param("id")
+
+	var body struct {
+		Visibility string `json:"visibility" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || !queries.IsValidSubmissionVisibility(body.Visibility) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Visibility must be one of private, friends or public"})
+		return
+	}
+
+	updated, err := queries.SetSubmissionVisibility(appCtx.DB, c.Request.Context(), submissionID, requester.ID, body.Visibility)
+	if err != nil {
+		log.Printf("Error updating visibility of submission %s for user %s: %v", submissionID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to update visibility"})
+		return
+	}
+	if !updated {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"visibility": body.Visibility})
+}
+
 func HandleSubmissionToggleFavorite(c *gin.Context) {
 	appCtx := requestContext.GetCtx(c)
 	userID := middleware.GetUserID(c)
@@ -217,16 +423,11 @@ func HandleSubmissionToggleReaction(c *gin.Context) {
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Reaction ID is required"})
 		return
 	}
-
-	// Validate reaction ID
-	validReactions := map[string]bool{
-		"heart":     true,
-		"cry-laugh": true,
-		"face-meh":  true,
-		"fire":      true,
+	if !checkCanViewSubmission(c, requester.ID, submissionID) || !checkNotSpoiler(c, requester.ID, submissionID) {
+		return
 	}
-	if !validReactions[body.ReactionID] {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid reaction ID"})
+
+	if !checkReactionAllowed(c, "submission", submissionID, body.ReactionID) {
 		return
 	}
 
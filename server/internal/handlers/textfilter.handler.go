@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	requestContext "drawer-service-backend/internal/context"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/textfilter"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// screenText runs user text through the profanity filter, aborting the
+// request when it's refused. The result's Text is what should be stored.
+func screenText(c *gin.Context, text string) (textfilter.Result, bool) {
+	appCtx := requestContext.GetCtx(c)
+	return checkScreened(c, appCtx.TextFilter.Check(text), "Text contains language that isn't allowed")
+}
+
+// screenUsername is screenText for usernames, which are refused rather than
+// masked
+func screenUsername(c *gin.Context, username string) (textfilter.Result, bool) {
+	appCtx := requestContext.GetCtx(c)
+	return checkScreened(c, appCtx.TextFilter.CheckName(username), "Username contains language that isn't allowed")
+}
+
+func checkScreened(c *gin.Context, result textfilter.Result, message string) (textfilter.Result, bool) {
+	if result.Rejected {
+		log.Printf("Refused text matching %v", result.Terms())
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": message})
+		return result, false
+	}
+	return result, true
+}
+
+// updateTextFlag flags stored text for review when the filter asked for it,
+// or clears an earlier flag when the text was changed to something clean.
+// Failing to do so doesn't fail the request.
+func updateTextFlag(c *gin.Context, result textfilter.Result, contentType string, contentID string, userID string) {
+	appCtx := requestContext.GetCtx(c)
+
+	var err error
+	if result.Flagged {
+		err = queries.FlagText(appCtx.DB, c.Request.Context(), contentType, contentID, userID, result.Text, result.Terms())
+	} else {
+		err = queries.ClearTextFlag(appCtx.DB, c.Request.Context(), contentType, contentID)
+	}
+	if err != nil {
+		log.Printf("Error updating text flag of %s %s: %v", contentType, contentID, err)
+	}
+}
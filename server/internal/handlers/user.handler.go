@@ -8,8 +8,10 @@ import (
 	"drawer-service-backend/internal/db/models"
 	"drawer-service-backend/internal/db/queries"
 	"drawer-service-backend/internal/middleware"
+	"drawer-service-backend/internal/stats"
 	"drawer-service-backend/internal/storage"
 	"drawer-service-backend/internal/utils"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -43,6 +45,43 @@ func HandleGetUserByID(c *gin.Context) {
 		return
 	}
 
+	// Blocks hide the profile both ways like the feeds do. Users who blocked
+	// the requester look like they don't exist, users the requester blocked
+	// only show who they are so they can be unblocked.
+	blockedBy, err := queries.HasBlocked(appCtx.DB, c.Request.Context(), userID, requester.ID)
+	if err != nil {
+		log.Printf("Error checking block of %s by %s: %v", requester.ID, userID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	if blockedBy {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	blocking, err := queries.HasBlocked(appCtx.DB, c.Request.Context(), requester.ID, userID)
+	if err != nil {
+		log.Printf("Error checking block of %s by %s: %v", userID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	privacy, err := queries.GetPrivacySettings(appCtx.DB, c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("Error fetching privacy settings for user %s: %v", userID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	canView, err := queries.CanViewProfile(appCtx.DB, c.Request.Context(), userID, requester.ID, privacy.ProfileVisibility)
+	if err != nil {
+		log.Printf("Error checking profile visibility of %s for %s: %v", userID, requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
 	// Query the user from the database
 	user, err := queries.GetUserProfileFromDB(appCtx.DB, c.Request.Context(), userID, requester.ID, appCtx.Config)
 
@@ -53,6 +92,27 @@ func HandleGetUserByID(c *gin.Context) {
 		return
 	}
 
+	// A hidden profile still shows who the user is so they can be invited
+	if !canView || blocking {
+		user = models.GetMeResponse{
+			User:        user.User,
+			Prompts:     []*models.UserPromptSubmission{},
+			Feed:        []*models.UserPromptSubmission{},
+			Friends:     []models.User{},
+			Favorites:   []*models.FavoriteSubmission{},
+			ContestWins: []models.ContestWin{},
+			Invitation:  user.Invitation,
+			Restricted:  true,
+		}
+	} else if userID != requester.ID && !privacy.ShowStreak {
+		user.Stats.CurrentStreak = nil
+	}
+
+	user.Blocked = blocking
+	if user.Muted, err = queries.IsMuted(appCtx.DB, c.Request.Context(), requester.ID, userID); err != nil {
+		log.Printf("Error checking mute of %s by %s: %v", userID, requester.ID, err)
+	}
+
 	c.JSON(http.StatusOK, user)
 }
 
@@ -70,6 +130,67 @@ func HandleGetUserProfile(c *gin.Context) {
 	c.JSON(http.StatusCreated, response)
 }
 
+func HandleGetPrivacySettings(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	settings, err := queries.GetPrivacySettings(appCtx.DB, c.Request.Context(), requester.ID)
+	if err != nil {
+		log.Printf("Error fetching privacy settings for user %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch privacy settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdatePrivacySettingsRequest changes only the settings that are present
+type UpdatePrivacySettingsRequest struct {
+	ProfileVisibility *string `json:"profileVisibility"`
+	ShowStreak        *bool   `json:"showStreak"`
+	ShowAchievements  *bool   `json:"showAchievements"`
+}
+
+func HandleUpdatePrivacySettings(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	var req UpdatePrivacySettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	settings, err := queries.GetPrivacySettings(appCtx.DB, c.Request.Context(), requester.ID)
+	if err != nil {
+		log.Printf("Error fetching privacy settings for user %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch privacy settings"})
+		return
+	}
+
+	if req.ProfileVisibility != nil {
+		if !queries.IsValidProfileVisibility(*req.ProfileVisibility) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Profile visibility must be one of everyone, friends or nobody"})
+			return
+		}
+		settings.ProfileVisibility = *req.ProfileVisibility
+	}
+	if req.ShowStreak != nil {
+		settings.ShowStreak = *req.ShowStreak
+	}
+	if req.ShowAchievements != nil {
+		settings.ShowAchievements = *req.ShowAchievements
+	}
+
+	if err := queries.UpdatePrivacySettings(appCtx.DB, c.Request.Context(), requester.ID, settings); err != nil {
+		log.Printf("Error updating privacy settings for user %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to update privacy settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
 func HandleUpdateUsername(c *gin.Context) {
 	requester := middleware.GetUser(c)
 	appCtx := requestContext.GetCtx(c)
@@ -100,6 +221,10 @@ func HandleUpdateUsername(c *gin.Context) {
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Username cannot contain spaces"})
 		return
 	}
+	screened, ok := screenUsername(c, body.Username)
+	if !ok {
+		return
+	}
 
 	// Check if username is already taken
 	var existingUserID string
@@ -124,6 +249,7 @@ func HandleUpdateUsername(c *gin.Context) {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to update username"})
 		return
 	}
+	updateTextFlag(c, screened, queries.ReportContentUser, requester.ID, requester.ID)
 
 	log.Printf("Successfully updated username for user %s (email: %s) to '%s'",
 		requester.ID, utils.MaskEmail(requester.Email), body.Username)
@@ -154,9 +280,21 @@ func HandleInviteFriend(c *gin.Context) {
 		user1, user2 = user2, user1
 	}
 
+	blocked, err := queries.IsBlockedEitherWay(appCtx.DB, c.Request.Context(), requester.ID, friendID)
+	if err != nil {
+		log.Printf("Error checking blocks between users %s and %s: %v", requester.ID, friendID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check friendship status"})
+		return
+	}
+	if blocked {
+		log.Printf("User %s attempted to invite %s across a block", requester.ID, friendID)
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You cannot invite this user"})
+		return
+	}
+
 	// Check if friendship already exists (pending or accepted)
 	var exists bool
-	err := appCtx.DB.QueryRowContext(c.Request.Context(),
+	err = appCtx.DB.QueryRowContext(c.Request.Context(),
 		"SELECT EXISTS(SELECT 1 FROM friendships WHERE user1 = ? AND user2 = ?)", user1, user2).Scan(&exists)
 	if err != nil {
 		log.Printf("Error checking existing friendship between users %s and %s: %v", user1, user2, err)
@@ -182,6 +320,49 @@ func HandleInviteFriend(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Friend invitation sent"})
 }
 
+const (
+	userSearchLimit       = 20
+	friendSuggestionLimit = 10
+)
+
+// HandleSearchUsers looks users up by username prefix so people can find
+// friends without knowing their user ID
+func HandleSearchUsers(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	prefix := strings.TrimSpace(c.Query("q"))
+	if prefix == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+
+	results, err := queries.SearchUsers(appCtx.DB, c.Request.Context(), requester.ID, prefix, userSearchLimit)
+	if err != nil {
+		log.Printf("Error searching users for %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to search users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// HandleGetFriendSuggestions returns friends of friends ranked by mutual
+// friend count
+func HandleGetFriendSuggestions(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+
+	suggestions, err := queries.GetFriendSuggestions(appCtx.DB, c.Request.Context(), requester.ID, friendSuggestionLimit)
+	if err != nil {
+		log.Printf("Error fetching friend suggestions for %s: %v", requester.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch friend suggestions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
+
 // Handler to get all pending invitations for the current user
 func HandleGetInvitations(c *gin.Context) {
 	requester := middleware.GetUser(c)
@@ -308,6 +489,47 @@ func HandleDenyInvitation(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Invitation denied"})
 }
 
+// Handler to end a friendship, or to retract an invitation the requester sent
+func HandleRemoveFriend(c *gin.Context) {
+	requester := middleware.GetUser(c)
+	appCtx := requestContext.GetCtx(c)
+	otherID := c.Param("id")
+	if otherID == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		return
+	}
+
+	state, err := queries.RemoveFriend(appCtx.DB, c.Request.Context(), requester.ID, otherID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "No friendship or sent invitation found"})
+			return
+		}
+		log.Printf("Error removing friendship between %s and %s: %v", requester.ID, otherID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove friend"})
+		return
+	}
+
+	if state == "pending" {
+		log.Printf("User %s retracted their invitation to %s", requester.ID, otherID)
+		c.JSON(http.StatusOK, gin.H{"message": "Invitation cancelled"})
+		return
+	}
+
+	// The feed and activity only ever include accepted friends, so the only
+	// thing left to update is the friend count of both users
+	go func() {
+		for _, userID := range []string{requester.ID, otherID} {
+			if _, err := stats.CalculateFriendTotal(appCtx.DB, context.Background(), userID, 0); err != nil {
+				log.Printf("Error recalculating friend total for %s: %v", userID, err)
+			}
+		}
+	}()
+
+	log.Printf("User %s removed %s as a friend", requester.ID, otherID)
+	c.JSON(http.StatusOK, gin.H{"message": "Friend removed"})
+}
+
 func HandleUpdateAvatarUrl(c *gin.Context) {
 	requester := middleware.GetUser(c)
 	appCtx := requestContext.GetCtx(c)
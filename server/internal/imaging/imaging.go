@@ -0,0 +1,65 @@
+package imaging
+
+import (
+	"drawer-service-backend/internal/strokes"
+	"image"
+	"image/color"
+	stdPalette "image/color/palette"
+	"image/draw"
+)
+
+// Fill paints the whole image with a single color.
+func Fill(img *image.NRGBA, c color.NRGBA) {
+	FillRect(img, img.Bounds(), c)
+}
+
+// DrawFitted scales src to fit inside rect, keeping its aspect ratio, and
+// draws it centered over whatever is already in dst.
+func DrawFitted(dst *image.NRGBA, rect image.Rectangle, src image.Image) {
+	size := min(rect.Dx(), rect.Dy())
+	if size <= 0 {
+		return
+	}
+
+	scaled := strokes.Thumbnail(src, size)
+	offset := image.Pt(
+		rect.Min.X+(rect.Dx()-scaled.Bounds().Dx())/2,
+		rect.Min.Y+(rect.Dy()-scaled.Bounds().Dy())/2,
+	)
+	draw.Draw(dst, scaled.Bounds().Add(offset), scaled, image.Point{}, draw.Over)
+}
+
+// Paletted converts an image for GIF encoding. Drawings only use a handful
+// of colors so an exact palette usually fits, otherwise it falls back to a
+// dithered fixed palette.
+func Paletted(img image.Image) *image.Paletted {
+	bounds := img.Bounds()
+	colors := color.Palette{}
+	index := map[color.Color]bool{}
+	exact := true
+
+	for y := bounds.Min.Y; y < bounds.Max.Y && exact; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y))
+			if index[c] {
+				continue
+			}
+			if len(colors) == 256 {
+				exact = false
+				break
+			}
+			index[c] = true
+			colors = append(colors, c)
+		}
+	}
+
+	if !exact {
+		out := image.NewPaletted(bounds, stdPalette.Plan9)
+		draw.FloydSteinberg.Draw(out, bounds, img, bounds.Min)
+		return out
+	}
+
+	out := image.NewPaletted(bounds, colors)
+	draw.Draw(out, bounds, img, bounds.Min, draw.Src)
+	return out
+}
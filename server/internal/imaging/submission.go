@@ -0,0 +1,91 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/config"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/storage"
+	"drawer-service-backend/internal/strokes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/png"
+	"log"
+)
+
+// CacheKey identifies an image generated from a set of submissions by its
+// period and by the exact drawings in it, so it's regenerated once a new
+// drawing comes in.
+func CacheKey(period string, submissions []models.UserPromptSubmission) string {
+	h := fnv.New32a()
+	for _, submission := range submissions {
+		h.Write([]byte(submission.ID))
+	}
+	return fmt.Sprintf("%s-%08x", period, h.Sum32())
+}
+
+// LoadSubmission prefers the stored image and falls back to re-rendering the
+// stroke log. It returns nil when neither is available.
+func LoadSubmission(repo *sql.DB, ctx context.Context, cfg *config.Config, submission models.UserPromptSubmission) image.Image {
+	if cfg.Env != "development" {
+		data, err := storage.NewStorageService(cfg).GetSubmission(submission.User.ID, submission.ID)
+		if img := decodeStored(submission.ID, data, err); img != nil {
+			return img
+		}
+	}
+
+	return renderStrokes(repo, ctx, submission.ID)
+}
+
+// LoadSubmissionThumbnail is LoadSubmission for small previews, it tries the
+// stored thumbnail before the full size image.
+func LoadSubmissionThumbnail(repo *sql.DB, ctx context.Context, cfg *config.Config, submission models.UserPromptSubmission) image.Image {
+	if cfg.Env != "development" {
+		data, err := storage.NewStorageService(cfg).GetSubmissionThumbnail(submission.User.ID, submission.ID)
+		if img := decodeStored(submission.ID, data, err); img != nil {
+			return img
+		}
+	}
+
+	if img := LoadSubmission(repo, ctx, cfg, submission); img != nil {
+		return strokes.Thumbnail(img, strokes.ThumbnailSize)
+	}
+	return nil
+}
+
+func decodeStored(submissionID string, data []byte, err error) image.Image {
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Error fetching stored image for submission %s: %v", submissionID, err)
+		}
+		return nil
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		log.Printf("Could not decode stored image for submission %s: %v", submissionID, err)
+		return nil
+	}
+	return img
+}
+
+func renderStrokes(repo *sql.DB, ctx context.Context, submissionID string) image.Image {
+	submissionStrokes, err := queries.GetSubmissionStrokes(repo, ctx, submissionID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error fetching strokes for submission %s: %v", submissionID, err)
+		}
+		return nil
+	}
+
+	img, err := strokes.Render(submissionStrokes.Strokes, submissionStrokes.Colors)
+	if err != nil {
+		log.Printf("Error rendering strokes for submission %s: %v", submissionID, err)
+		return nil
+	}
+	return img
+}
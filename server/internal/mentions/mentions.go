@@ -0,0 +1,57 @@
+package mentions
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Max users mentioned per comment, the rest are left as plain text
+const MaxPerComment = 10
+
+// Usernames can hold anything but spaces
+var mentionPattern = regexp.MustCompile(`@[^\s@]+`)
+
+// Punctuation trimmed off the end of a mention, as in "thanks @bob!"
+const trailingPunctuation = ".,!?;:)]}'\""
+
+type Mention struct {
+	Username string
+	// Offsets are in UTF-16 code units, like string indexes in the frontend
+	Start  int
+	Length int
+}
+
+// Parse finds the @username mentions in text, in order
+func Parse(text string) []Mention {
+	found := []Mention{}
+	for _, match := range mentionPattern.FindAllStringIndex(text, -1) {
+		start, end := match[0], match[1]
+		// An @ in the middle of a word, like in an email address, isn't a mention
+		if previous, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWordRune(previous) {
+			continue
+		}
+		token := strings.TrimRight(text[start:end], trailingPunctuation)
+		username := token[1:]
+		if username == "" {
+			continue
+		}
+
+		found = append(found, Mention{
+			Username: username,
+			Start:    utf16Len(text[:start]),
+			Length:   utf16Len(token),
+		})
+	}
+	return found
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
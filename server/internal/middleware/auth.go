@@ -7,6 +7,7 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -41,6 +42,15 @@ func AuthRequired(repo *sql.DB) gin.HandlerFunc {
 			return
 		}
 
+		if user.SuspendedUntil != nil && user.SuspendedUntil.After(time.Now()) {
+			log.Printf("Auth failed: User '%s' is suspended until %s", userID, user.SuspendedUntil.Format(time.RFC3339))
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":          "Your account has been suspended",
+				"suspendedUntil": user.SuspendedUntil,
+			})
+			return
+		}
+
 		// Add user info to the context
 		c.Set(UserIDContextKey, user.ID)
 		c.Set(UserContextKey, user)
@@ -4,17 +4,24 @@ import (
 	"database/sql"
 	"drawer-service-backend/internal/config"
 	"drawer-service-backend/internal/context"
+	"drawer-service-backend/internal/textfilter"
+	"log"
 
 	"github.com/gin-gonic/gin"
 )
 
-
-
 func ContextMiddleware(cfg *config.Config, db *sql.DB) gin.HandlerFunc {
+	wordList, err := textfilter.LoadWordList(cfg.TextFilterWordList)
+	if err != nil {
+		log.Fatalf("Failed to load text filter word list %s: %v", cfg.TextFilterWordList, err)
+	}
+	textFilter := textfilter.New(textfilter.Mode(cfg.TextFilterMode), wordList)
+
 	return func(c *gin.Context) {
 		appCtx := &context.AppContext{
-			DB:      db,
-			Config:  cfg,
+			DB:         db,
+			Config:     cfg,
+			TextFilter: textFilter,
 		}
 		// Store the AppContext in the context
 		c.Set(string(context.AppContextKey), appCtx)
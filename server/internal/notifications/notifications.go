@@ -73,8 +73,19 @@ func NotifyFriendsOfSubmission(repo *sql.DB, userID, username, submissionID stri
 		Action:   "submitted",
 	}
 
+	// Friends who haven't drawn today's prompt are told without being sent to
+	// a drawing they can't open yet
+	locked := data
+	locked.Body = fmt.Sprintf("%s just posted their daily drawing! Draw yours to see it", username)
+	locked.URL = "/draw"
+	locked.Locked = true
+
 	for _, friendID := range friends {
-		if err := SendNotificationToUser(repo, friendID, data, cfg); err != nil {
+		payload := data
+		if hasSubmitted, err := queries.CheckUserSubmittedToday(repo, context.Background(), friendID); err != nil || !hasSubmitted {
+			payload = locked
+		}
+		if err := SendNotificationToUser(repo, friendID, payload, cfg); err != nil {
 			// Log error but continue with other friends
 			fmt.Printf("Failed to notify friend %s: %v\n", friendID, err)
 		}
@@ -88,6 +99,10 @@ func NotifyUserOfReaction(repo *sql.DB, reactorID, reactorUsername, contentOwner
 	if reactorID == contentOwnerID {
 		return nil
 	}
+	// Blocked users' reactions are hidden from the owner, so are their notifications
+	if blocked, err := queries.HasBlocked(repo, context.Background(), contentOwnerID, reactorID); err != nil || blocked {
+		return err
+	}
 
 	var title, body string
 	if contentType == "submission" {
@@ -117,6 +132,9 @@ func NotifyUserOfComment(repo *sql.DB, commenterID, commenterUsername, submissio
 	if commenterID == submissionOwnerID {
 		return nil
 	}
+	if blocked, err := queries.HasBlocked(repo, context.Background(), submissionOwnerID, commenterID); err != nil || blocked {
+		return err
+	}
 
 	data := models.NotificationData{
 		Type:     models.NotificationTypeComment,
@@ -130,3 +148,107 @@ func NotifyUserOfComment(repo *sql.DB, commenterID, commenterUsername, submissio
 
 	return SendNotificationToUser(repo, submissionOwnerID, data, cfg)
 }
+
+// NotifyUserOfReply sends notification when someone replies to user's comment
+func NotifyUserOfReply(repo *sql.DB, replierID, replierUsername, commentAuthorID, submissionID string, cfg *config.Config) error {
+	if replierID == commentAuthorID {
+		return nil
+	}
+	if blocked, err := queries.HasBlocked(repo, context.Background(), commentAuthorID, replierID); err != nil || blocked {
+		return err
+	}
+
+	data := models.NotificationData{
+		Type:     models.NotificationTypeReply,
+		Title:    "New Reply",
+		Body:     fmt.Sprintf("%s replied to your comment", replierUsername),
+		URL:      fmt.Sprintf("/draw/submission/%s", submissionID),
+		UserID:   replierID,
+		Username: replierUsername,
+		Action:   "replied",
+	}
+
+	return SendNotificationToUser(repo, commentAuthorID, data, cfg)
+}
+
+// NotifyUserOfMention sends notification when someone mentions user in a comment
+func NotifyUserOfMention(repo *sql.DB, mentionerID, mentionerUsername, mentionedID, submissionID string, cfg *config.Config) error {
+	if mentionerID == mentionedID {
+		return nil
+	}
+	if blocked, err := queries.HasBlocked(repo, context.Background(), mentionedID, mentionerID); err != nil || blocked {
+		return err
+	}
+
+	data := models.NotificationData{
+		Type:     models.NotificationTypeMention,
+		Title:    "New Mention",
+		Body:     fmt.Sprintf("%s mentioned you in a comment", mentionerUsername),
+		URL:      fmt.Sprintf("/draw/submission/%s", submissionID),
+		UserID:   mentionerID,
+		Username: mentionerUsername,
+		Action:   "mentioned",
+	}
+
+	return SendNotificationToUser(repo, mentionedID, data, cfg)
+}
+
+// NotifyUserOfRemix sends notification when someone remixes user's drawing
+func NotifyUserOfRemix(repo *sql.DB, remixerID, remixerUsername, artistID, remixID string, cfg *config.Config) error {
+	if remixerID == artistID {
+		return nil
+	}
+	if blocked, err := queries.HasBlocked(repo, context.Background(), artistID, remixerID); err != nil || blocked {
+		return err
+	}
+
+	data := models.NotificationData{
+		Type:     models.NotificationTypeRemix,
+		Title:    "New Remix",
+		Body:     fmt.Sprintf("%s remixed your drawing", remixerUsername),
+		URL:      fmt.Sprintf("/draw/submission/%s", remixID),
+		UserID:   remixerID,
+		Username: remixerUsername,
+		Action:   "remixed",
+	}
+
+	return SendNotificationToUser(repo, artistID, data, cfg)
+}
+
+// NotifyUserOfWarning lets a user know a moderator warned them about
+// something they posted
+func NotifyUserOfWarning(repo *sql.DB, userID, contentType string, cfg *config.Config) error {
+	what := "Something you posted"
+	switch contentType {
+	case "submission":
+		what = "One of your drawings"
+	case "comment":
+		what = "One of your comments"
+	case "user":
+		what = "Your username"
+	case "prompt_suggestion":
+		what = "One of your prompt suggestions"
+	}
+
+	data := models.NotificationData{
+		Type:  models.NotificationTypeWarning,
+		Title: "Warning from the moderators",
+		Body:  fmt.Sprintf("%s was reported and goes against the community guidelines", what),
+		URL:   "/draw",
+	}
+
+	return SendNotificationToUser(repo, userID, data, cfg)
+}
+
+// NotifyUserOfContestWin lets a user know their drawing won the weekly vote
+// among their friends
+func NotifyUserOfContestWin(repo *sql.DB, userID string, cfg *config.Config) error {
+	data := models.NotificationData{
+		Type:  models.NotificationTypeContestWin,
+		Title: "You won the weekly vote!",
+		Body:  "Your drawing got the most votes from your friends last week",
+		URL:   "/draw",
+	}
+
+	return SendNotificationToUser(repo, userID, data, cfg)
+}
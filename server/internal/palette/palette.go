@@ -0,0 +1,136 @@
+package palette
+
+import (
+	"drawer-service-backend/internal/strokes"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+type Mode string
+
+var (
+	// Skip the check entirely
+	MODE_OFF Mode = "off"
+	// Only reward compliant submissions with the palette purist stat
+	MODE_REWARD Mode = "reward"
+	// Accept non-compliant submissions but flag them for admins
+	MODE_FLAG Mode = "flag"
+	// Refuse non-compliant submissions
+	MODE_REJECT Mode = "reject"
+)
+
+const (
+	// Matches the frontend ERASER_COLOR that drawings are flattened onto
+	DefaultBackground = "#f5f4f0"
+
+	// Max RGB distance for a pixel to count as a given color
+	ColorTolerance = 24.0
+	// Pixels more transparent than this are ignored
+	MinAlpha = 32
+	// Share of drawn pixels allowed to be off palette before failing
+	MaxOffPaletteRatio = 0.02
+)
+
+func IsValidMode(mode string) bool {
+	switch Mode(mode) {
+	case MODE_OFF, MODE_REWARD, MODE_FLAG, MODE_REJECT:
+		return true
+	}
+	return false
+}
+
+type Result struct {
+	DrawnPixels      int     `json:"drawnPixels"`
+	OffPalettePixels int     `json:"offPalettePixels"`
+	OffPaletteRatio  float64 `json:"offPaletteRatio"`
+	Compliant        bool    `json:"compliant"`
+}
+
+// Analyze checks that the visible pixels of an image were drawn with the
+// prompt palette. Antialiased edges produce blends of two allowed colors, so a
+// pixel also passes when it lies close to the line between any two of them.
+func Analyze(img image.Image, palette []string) (Result, error) {
+	colors, err := strokes.ParsePalette(append([]string{DefaultBackground}, palette...))
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid palette: %w", err)
+	}
+
+	allowed := make([][3]float64, 0, len(colors))
+	for _, c := range colors {
+		allowed = append(allowed, [3]float64{float64(c.R), float64(c.G), float64(c.B)})
+	}
+
+	// Cache by color since drawings are made of few distinct colors
+	seen := map[color.NRGBA]bool{}
+	result := Result{}
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A < MinAlpha {
+				continue
+			}
+			result.DrawnPixels++
+
+			c.A = 255
+			ok, cached := seen[c]
+			if !cached {
+				ok = matches([3]float64{float64(c.R), float64(c.G), float64(c.B)}, allowed)
+				seen[c] = ok
+			}
+			if !ok {
+				result.OffPalettePixels++
+			}
+		}
+	}
+
+	if result.DrawnPixels > 0 {
+		result.OffPaletteRatio = float64(result.OffPalettePixels) / float64(result.DrawnPixels)
+	}
+	result.Compliant = result.OffPaletteRatio <= MaxOffPaletteRatio
+
+	return result, nil
+}
+
+func matches(pixel [3]float64, allowed [][3]float64) bool {
+	for _, c := range allowed {
+		if distance(pixel, c) <= ColorTolerance {
+			return true
+		}
+	}
+
+	for i := range allowed {
+		for j := i + 1; j < len(allowed); j++ {
+			if segmentDistance(pixel, allowed[i], allowed[j]) <= ColorTolerance {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func distance(a, b [3]float64) float64 {
+	return math.Sqrt((a[0]-b[0])*(a[0]-b[0]) + (a[1]-b[1])*(a[1]-b[1]) + (a[2]-b[2])*(a[2]-b[2]))
+}
+
+// segmentDistance is the distance from p to the closest blend of a and b
+func segmentDistance(p, a, b [3]float64) float64 {
+	var ab, ap [3]float64
+	lengthSq := 0.0
+	for i := 0; i < 3; i++ {
+		ab[i] = b[i] - a[i]
+		ap[i] = p[i] - a[i]
+		lengthSq += ab[i] * ab[i]
+	}
+	if lengthSq == 0 {
+		return distance(p, a)
+	}
+
+	t := (ap[0]*ab[0] + ap[1]*ab[1] + ap[2]*ab[2]) / lengthSq
+	t = math.Max(0, math.Min(1, t))
+	closest := [3]float64{a[0] + t*ab[0], a[1] + t*ab[1], a[2] + t*ab[2]}
+	return distance(p, closest)
+}
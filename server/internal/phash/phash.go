@@ -0,0 +1,155 @@
+package phash
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+)
+
+type Mode string
+
+var (
+	// Accept duplicates but flag them for admins
+	MODE_FLAG Mode = "flag"
+	// Refuse duplicates
+	MODE_REJECT Mode = "reject"
+)
+
+const (
+	// Hashes differing by this many bits or fewer are considered the same drawing
+	DuplicateThreshold = 6
+	// How far back to compare a user's new drawing against their old ones
+	LookbackDays = 30
+
+	sampleSize        = 32
+	hashSize          = 8
+	coefficientMargin = 1e-6
+)
+
+func IsValidMode(mode string) bool {
+	switch Mode(mode) {
+	case MODE_FLAG, MODE_REJECT:
+		return true
+	}
+	return false
+}
+
+// Hash computes a 64 bit DCT perceptual hash. The image is flattened onto
+// white, shrunk to 32x32 grayscale and each bit records whether one of the
+// 8x8 lowest frequencies is above their median.
+func Hash(img image.Image) uint64 {
+	gray := sample(img)
+
+	var coefficients [hashSize * hashSize]float64
+	for v := 0; v < hashSize; v++ {
+		for u := 0; u < hashSize; u++ {
+			sum := 0.0
+			for y := 0; y < sampleSize; y++ {
+				for x := 0; x < sampleSize; x++ {
+					sum += gray[y][x] *
+						math.Cos(float64(2*x+1)*float64(u)*math.Pi/(2*sampleSize)) *
+						math.Cos(float64(2*y+1)*float64(v)*math.Pi/(2*sampleSize))
+				}
+			}
+			coefficients[v*hashSize+u] = sum
+		}
+	}
+
+	// Skip the DC term, it only encodes overall brightness
+	median := medianOf(coefficients[1:])
+
+	// Flat drawings leave many coefficients at zero, the margin keeps float
+	// noise from flipping their bits
+	var hash uint64
+	for i, c := range coefficients {
+		if c > median+coefficientMargin {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// Distance is the number of differing bits between two hashes.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Format encodes a hash as fixed width hex, which sorts and stores safely
+// where a signed 64 bit integer would not.
+func Format(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+func Parse(value string) (uint64, error) {
+	return strconv.ParseUint(value, 16, 64)
+}
+
+// sample averages the image down to a sampleSize square of luminance values.
+func sample(img image.Image) [sampleSize][sampleSize]float64 {
+	var out [sampleSize][sampleSize]float64
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return out
+	}
+
+	for y := 0; y < sampleSize; y++ {
+		y0 := bounds.Min.Y + y*h/sampleSize
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*h/sampleSize)
+		for x := 0; x < sampleSize; x++ {
+			x0 := bounds.Min.X + x*w/sampleSize
+			x1 := max(x0+1, bounds.Min.X+(x+1)*w/sampleSize)
+
+			sum, n := 0.0, 0
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					r, g, b, a := img.At(sx, sy).RGBA()
+					// Premultiplied values, so adding the missing alpha flattens onto white
+					white := float64(0xffff - a)
+					luma := 0.299*(float64(r)+white) + 0.587*(float64(g)+white) + 0.114*(float64(b)+white)
+					sum += luma / 0xffff
+					n++
+				}
+			}
+			out[y][x] = sum / float64(n)
+		}
+	}
+
+	return out
+}
+
+func medianOf(values []float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// FindDuplicate returns the index of the closest of the given hex hashes that
+// is within DuplicateThreshold of hash, or -1 when none are. Hashes that fail to
+// parse are skipped.
+func FindDuplicate(hash uint64, candidates []string) (int, int) {
+	best, bestDistance := -1, DuplicateThreshold+1
+	for i, candidate := range candidates {
+		other, err := Parse(candidate)
+		if err != nil {
+			continue
+		}
+		if d := Distance(hash, other); d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+
+	if best == -1 {
+		return -1, 0
+	}
+	return best, bestDistance
+}
@@ -0,0 +1,64 @@
+package replay
+
+import (
+	"drawer-service-backend/internal/config"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/storage"
+	"drawer-service-backend/internal/strokes"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// A stroke log never changes once it's stored, so every replay frame is
+// rendered once and cached. In development, frames are kept here since
+// there's no bucket to cache them in.
+var memory = struct {
+	sync.Mutex
+	frames map[string][]byte
+}{
+	frames: map[string][]byte{},
+}
+
+// GetCached returns a frame rendered earlier, or nil if there isn't one yet
+func GetCached(cfg *config.Config, submissionID string, frame int) ([]byte, error) {
+	if cfg.Env == "development" {
+		memory.Lock()
+		defer memory.Unlock()
+		return memory.frames[frameKey(submissionID, frame)], nil
+	}
+
+	data, err := storage.NewStorageService(cfg).GetReplayFrame(submissionID, frame)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Render renders a frame of the replay as a PNG and caches it. Failing to
+// cache it doesn't fail the render.
+func Render(cfg *config.Config, submission models.SubmissionStrokes, frame int) ([]byte, error) {
+	img, err := strokes.RenderFrame(submission.Strokes, submission.Colors, frame, strokes.ReplayFrames)
+	if err != nil {
+		return nil, err
+	}
+	data, err := strokes.EncodePNG(img)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Env == "development" {
+		memory.Lock()
+		memory.frames[frameKey(submission.SubmissionID, frame)] = data
+		memory.Unlock()
+	} else if _, err := storage.NewStorageService(cfg).UploadReplayFrame(submission.SubmissionID, frame, data); err != nil {
+		log.Printf("Error caching replay frame %d of submission %s: %v", frame, submission.SubmissionID, err)
+	}
+
+	return data, nil
+}
+
+func frameKey(submissionID string, frame int) string {
+	return fmt.Sprintf("%s/%d", submissionID, frame)
+}
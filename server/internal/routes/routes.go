@@ -54,6 +54,8 @@ func InitRouter(cfg *config.Config, repo *sql.DB) *gin.Engine {
 		apiGroup.POST("/auth/register", handlers.HandleRegister)
 		apiGroup.POST("/auth/login", handlers.HandleLogin)
 		apiGroup.GET("/auth/verify", handlers.HandleVerifyEmail)
+		apiGroup.GET("/invite/:code", handlers.HandleGetInviteCode)
+		apiGroup.GET("/collection/:code", handlers.HandleGetSharedCollection)
 
 		// Authenticated routes
 		authGroup := apiGroup.Group("/")
@@ -65,6 +67,12 @@ func InitRouter(cfg *config.Config, repo *sql.DB) *gin.Engine {
 			userGroup.GET("/profile", handlers.HandleGetUserProfile)
 			userGroup.GET("/achievements", handlers.HandlerGetMyAchievements)
 			userGroup.GET("/invitations", handlers.HandleGetInvitations)
+			userGroup.GET("/search", handlers.HandleSearchUsers)
+			userGroup.GET("/suggestions", handlers.HandleGetFriendSuggestions)
+			userGroup.GET("/blocks", handlers.HandleGetBlockedUsers)
+			userGroup.GET("/mutes", handlers.HandleGetMutedUsers)
+			userGroup.GET("/privacy", handlers.HandleGetPrivacySettings)
+			userGroup.PUT("/privacy", handlers.HandleUpdatePrivacySettings)
 			userGroup.PUT("/username", handlers.HandleUpdateUsername)
 			userGroup.POST("/profile-pic",
 				middleware.RewardUnlockRequired(repo, achievements.CUSTOM_PROFILE_PIC),
@@ -75,28 +83,87 @@ func InitRouter(cfg *config.Config, repo *sql.DB) *gin.Engine {
 
 			userGroup.GET("/:id/profile", handlers.HandleGetUserByID)
 			userGroup.GET("/:id/achievements", handlers.HandlerGetUserAchievements)
+			userGroup.GET("/:id/timelapse/:month", handlers.HandleGetUserTimelapse)
+			userGroup.GET("/:id/contact-sheet/:period", handlers.HandleGetUserContactSheet)
 			userGroup.POST("/:id/invite", handlers.HandleInviteFriend)
 			userGroup.POST("/:id/accept-invitation", handlers.HandleAcceptInvitation)
 			userGroup.POST("/:id/deny-invitation", handlers.HandleDenyInvitation)
+			userGroup.DELETE("/:id/friend", handlers.HandleRemoveFriend)
+			userGroup.POST("/:id/block", handlers.HandleBlockUser)
+			userGroup.DELETE("/:id/block", handlers.HandleUnblockUser)
+			userGroup.POST("/:id/mute", handlers.HandleMuteUser)
+			userGroup.DELETE("/:id/mute", handlers.HandleUnmuteUser)
+			userGroup.POST("/:id/report", handlers.HandleReportUser)
+
+			inviteGroup := authGroup.Group("/invite")
+
+			inviteGroup.GET("", handlers.HandleGetInviteCodes)
+			inviteGroup.POST("", handlers.HandleCreateInviteCode)
+			inviteGroup.DELETE("/:code", handlers.HandleDeleteInviteCode)
+			inviteGroup.POST("/:code/redeem", handlers.HandleRedeemInviteCode)
+
+			groupGroup := authGroup.Group("/group")
+
+			groupGroup.GET("", handlers.HandleGetGroups)
+			groupGroup.POST("", handlers.HandleCreateGroup)
+			groupGroup.POST("/join/:code", handlers.HandleJoinGroup)
+			groupGroup.GET("/:id", handlers.HandleGetGroup)
+			groupGroup.DELETE("/:id", handlers.HandleDeleteGroup)
+			groupGroup.POST("/:id/leave", handlers.HandleLeaveGroup)
+			groupGroup.POST("/:id/invite-code", handlers.HandleResetGroupInviteCode)
+			groupGroup.DELETE("/:id/member/:userId", handlers.HandleRemoveGroupMember)
+			groupGroup.GET("/:id/feed", handlers.HandleGetGroupFeed)
+			groupGroup.GET("/:id/prompts", handlers.HandleGetGroupPrompts)
+			groupGroup.PUT("/:id/prompt/:day", handlers.HandleSetGroupPrompt)
+			groupGroup.DELETE("/:id/prompt/:day", handlers.HandleDeleteGroupPrompt)
 
 			submissionGroup := authGroup.Group("/submission")
 
 			submissionGroup.GET("/daily", handlers.HandleGetDailyPrompt)
 			submissionGroup.POST("/daily", handlers.HandleSubmitDailyPrompt)
+			submissionGroup.GET("/search", handlers.HandleSearchSubmissions)
 			submissionGroup.GET("/:id", handlers.HandleGetSubmissionByID)
+			submissionGroup.PATCH("/:id", handlers.HandleUpdateSubmissionText)
+			submissionGroup.GET("/:id/strokes", handlers.HandleGetSubmissionStrokes)
+			submissionGroup.GET("/:id/strokes/frame/:frame", handlers.HandleGetSubmissionReplayFrame)
+			submissionGroup.PUT("/:id/visibility", handlers.HandleUpdateSubmissionVisibility)
 			submissionGroup.POST("/:id/comment", handlers.HandleAddCommentToSubmission)
 			submissionGroup.POST("/:id/reaction", handlers.HandleSubmissionToggleReaction)
 			submissionGroup.POST("/:id/favorite", handlers.HandleSubmissionToggleFavorite)
-			submissionGroup.POST("/:id/comment/:reactionId/reaction", handlers.HandleCommentToggleReaction)
+			submissionGroup.POST("/:id/report", handlers.HandleReportSubmission)
+			submissionGroup.POST("/:id/remix", handlers.HandleRemixSubmission)
+			submissionGroup.POST("/:id/vote", handlers.HandleVoteForSubmission)
+			submissionGroup.DELETE("/:id/vote", handlers.HandleRemoveVote)
+			submissionGroup.PATCH("/:id/comment/:commentId", handlers.HandleEditComment)
+			submissionGroup.DELETE("/:id/comment/:commentId", handlers.HandleDeleteComment)
+			submissionGroup.POST("/:id/comment/:commentId/reaction", handlers.HandleCommentToggleReaction)
+			submissionGroup.POST("/:id/comment/:commentId/reply", handlers.HandleReplyToComment)
+			submissionGroup.POST("/:id/comment/:commentId/report", handlers.HandleReportComment)
+
+			authGroup.GET("/reactions", handlers.HandleGetReactionTypes)
+
+			authGroup.GET("/contest", handlers.HandleGetContest)
+			authGroup.GET("/leaderboard/:metric", handlers.HandleGetLeaderboard)
 
 			authGroup.GET("/activity", handlers.HandleGetActivity)
 			authGroup.POST("/activity/view", handlers.HandlePostActivity)
 			authGroup.POST("/favorite/swap", handlers.HandleSwapFavoriteOrder)
+			authGroup.PUT("/favorite/order", handlers.HandleReorderFavorites)
+
+			collectionGroup := authGroup.Group("/favorite/collection")
+
+			collectionGroup.GET("", handlers.HandleGetCollections)
+			collectionGroup.POST("", handlers.HandleCreateCollection)
+			collectionGroup.GET("/:id", handlers.HandleGetCollection)
+			collectionGroup.PATCH("/:id", handlers.HandleUpdateCollection)
+			collectionGroup.DELETE("/:id", handlers.HandleDeleteCollection)
+			collectionGroup.PUT("/:id/items", handlers.HandleSetCollectionItems)
 
 			authGroup.POST("/notifications/subscribe", handlers.HandleSubscribePush)
 			authGroup.POST("/notifications/unsubscribe", handlers.HandleUnsubscribePush)
 
 			authGroup.POST("/prompt/suggest", handlers.SuggestPrompt)
+			authGroup.POST("/prompt/suggestion/:id/report", handlers.HandleReportPromptSuggestion)
 
 			// Development-only debug endpoints for push notifications
 			log.Printf("🔔 Current environment: %s", cfg.Env)
@@ -181,6 +248,15 @@ func InitRouter(cfg *config.Config, repo *sql.DB) *gin.Engine {
 				adminGroup.POST("/prompt", handlers.HandleCreatePrompt)
 				adminGroup.GET("/action-stats", handlers.HandleGetAdminActionStats)
 				adminGroup.GET("/prompt-suggestions", handlers.GetAllPromptSuggestions)
+				adminGroup.GET("/palette-flags", handlers.HandleGetPaletteFlags)
+				adminGroup.GET("/duplicate-flags", handlers.HandleGetDuplicateFlags)
+				adminGroup.GET("/text-flags", handlers.HandleGetTextFlags)
+				adminGroup.GET("/reactions", handlers.HandleGetAllReactionTypes)
+				adminGroup.POST("/reactions", handlers.HandleCreateReactionType)
+				adminGroup.PUT("/reactions/:id", handlers.HandleUpdateReactionType)
+				adminGroup.GET("/reports", handlers.HandleGetReportQueue)
+				adminGroup.GET("/reports/:type/:id", handlers.HandleGetContentReports)
+				adminGroup.POST("/reports/:type/:id/action", handlers.HandleModerateContent)
 			}
 		}
 	}
@@ -0,0 +1,233 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/db/queries"
+	"drawer-service-backend/internal/utils"
+	"log"
+	"sort"
+	"time"
+)
+
+type LeaderboardMetric string
+
+var (
+	LEADERBOARD_CURRENT_STREAK     LeaderboardMetric = "current-streak"
+	LEADERBOARD_MAX_STREAK         LeaderboardMetric = "max-streak"
+	LEADERBOARD_DRAWINGS           LeaderboardMetric = "drawings"
+	LEADERBOARD_REACTIONS_RECEIVED LeaderboardMetric = "reactions-received"
+	LEADERBOARD_COMMENTS           LeaderboardMetric = "comments"
+)
+
+type LeaderboardWindow string
+
+var (
+	// The last 7 days, today included
+	WINDOW_WEEK LeaderboardWindow = "week"
+	// The last 30 days, today included
+	WINDOW_MONTH LeaderboardWindow = "month"
+	WINDOW_ALL   LeaderboardWindow = "all-time"
+)
+
+// All-time stats saved in user_stat_calculations are reused for this long
+// before they're calculated again
+const leaderboardStatMaxAge = 15 * time.Minute
+
+// The stat saved in user_stat_calculations for each all-time metric that has
+// one
+var leaderboardStatFields = map[LeaderboardMetric]StatsField{
+	LEADERBOARD_CURRENT_STREAK: SUBMISSION_ACTIVE_STREAK,
+	LEADERBOARD_MAX_STREAK:     SUBMISSION_STREAK,
+	LEADERBOARD_DRAWINGS:       SUBMISSION_TOTAL,
+	LEADERBOARD_COMMENTS:       COMMENT_TOTAL,
+}
+
+func IsValidLeaderboardMetric(metric string) bool {
+	switch LeaderboardMetric(metric) {
+	case LEADERBOARD_CURRENT_STREAK, LEADERBOARD_MAX_STREAK, LEADERBOARD_DRAWINGS, LEADERBOARD_REACTIONS_RECEIVED, LEADERBOARD_COMMENTS:
+		return true
+	}
+	return false
+}
+
+func IsValidLeaderboardWindow(window string) bool {
+	switch LeaderboardWindow(window) {
+	case WINDOW_WEEK, WINDOW_MONTH, WINDOW_ALL:
+		return true
+	}
+	return false
+}
+
+// IsStreak reports whether the metric ranks streaks, which users can hide
+func (m LeaderboardMetric) IsStreak() bool {
+	return m == LEADERBOARD_CURRENT_STREAK || m == LEADERBOARD_MAX_STREAK
+}
+
+// Since returns the first day the window covers, empty for all-time
+func (w LeaderboardWindow) Since(now time.Time) string {
+	switch w {
+	case WINDOW_WEEK:
+		return utils.GetFormattedDate(now.AddDate(0, 0, -6))
+	case WINDOW_MONTH:
+		return utils.GetFormattedDate(now.AddDate(0, 0, -29))
+	}
+	return ""
+}
+
+// RankLeaderboard scores the users on the metric over the window and sorts
+// them best first. Users with the same score share a rank.
+func RankLeaderboard(repo *sql.DB, ctx context.Context, users []models.User, metric LeaderboardMetric, window LeaderboardWindow) ([]models.LeaderboardEntry, error) {
+	userIDs := make([]string, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+
+	scores, err := getLeaderboardScores(repo, ctx, userIDs, metric, window)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.LeaderboardEntry, len(users))
+	for i, user := range users {
+		entries[i] = models.LeaderboardEntry{User: user, Score: scores[user.ID]}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score > entries[j].Score
+		}
+		return entries[i].User.Username < entries[j].User.Username
+	})
+	for i := range entries {
+		if i > 0 && entries[i].Score == entries[i-1].Score {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+
+	return entries, nil
+}
+
+func getLeaderboardScores(repo *sql.DB, ctx context.Context, userIDs []string, metric LeaderboardMetric, window LeaderboardWindow) (map[string]int, error) {
+	if len(userIDs) == 0 {
+		return map[string]int{}, nil
+	}
+
+	if window == WINDOW_ALL {
+		if field, ok := leaderboardStatFields[metric]; ok {
+			return getAllTimeScores(repo, ctx, userIDs, field)
+		}
+	}
+
+	now := time.Now()
+	since := window.Since(now)
+
+	switch metric {
+	case LEADERBOARD_DRAWINGS:
+		return queries.CountDrawingsSince(repo, ctx, userIDs, since)
+	case LEADERBOARD_COMMENTS:
+		return queries.CountCommentsSince(repo, ctx, userIDs, since)
+	case LEADERBOARD_REACTIONS_RECEIVED:
+		return queries.CountReactionsReceivedSince(repo, ctx, userIDs, since)
+	}
+
+	days, err := queries.GetDrawingDaysSince(repo, ctx, userIDs, since)
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[string]int)
+	for userID, userDays := range days {
+		if metric == LEADERBOARD_CURRENT_STREAK {
+			scores[userID] = currentStreak(userDays, now)
+		} else {
+			scores[userID] = maxStreak(userDays)
+		}
+	}
+	return scores, nil
+}
+
+// getAllTimeScores reads the stat saved for each user, calculating and saving
+// it again for those whose saved value is missing or too old
+func getAllTimeScores(repo *sql.DB, ctx context.Context, userIDs []string, field StatsField) (map[string]int, error) {
+	scores, err := queries.GetFreshCalculatedStats(repo, ctx, userIDs, string(field), leaderboardStatMaxAge)
+	if err != nil {
+		return nil, err
+	}
+
+	calculated := make(map[string]int)
+	for _, userID := range userIDs {
+		if _, ok := scores[userID]; ok {
+			continue
+		}
+
+		var value int
+		switch field {
+		case SUBMISSION_ACTIVE_STREAK:
+			value, err = queries.CalculateSubmissionActiveStreak(repo, ctx, userID)
+		case SUBMISSION_STREAK:
+			value, err = queries.CalculateSubmissionMaxStreak(repo, ctx, userID)
+		case SUBMISSION_TOTAL:
+			value, err = queries.CalculateSubmissionCount(repo, ctx, userID)
+		case COMMENT_TOTAL:
+			value, err = queries.CalculateCommentCount(repo, ctx, userID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		scores[userID] = value
+		calculated[userID] = value
+	}
+
+	go func() {
+		for userID, value := range calculated {
+			err := queries.InsertCalculatedStat(repo, context.Background(), userID, string(field), value)
+			if err != nil {
+				log.Printf("Failed to save %s for user %s: %v", field, userID, err)
+			}
+		}
+	}()
+
+	return scores, nil
+}
+
+// currentStreak counts the consecutive days up to today, or up to yesterday
+// when the user hasn't drawn yet today. Days are sorted oldest first.
+func currentStreak(days []string, now time.Time) int {
+	expected := utils.GetFormattedDate(now)
+	if len(days) > 0 && days[len(days)-1] != expected {
+		expected = utils.GetFormattedDate(now.AddDate(0, 0, -1))
+	}
+
+	streak := 0
+	for i := len(days) - 1; i >= 0 && days[i] == expected; i-- {
+		streak++
+		day, _ := time.Parse(utils.DateFormat, expected)
+		expected = utils.GetFormattedDate(day.AddDate(0, 0, -1))
+	}
+	return streak
+}
+
+// maxStreak finds the longest run of consecutive days. Days are sorted oldest
+// first.
+func maxStreak(days []string) int {
+	longest, streak := 0, 0
+	var previous time.Time
+	for i, dayStr := range days {
+		day, err := time.Parse(utils.DateFormat, dayStr)
+		if err != nil {
+			continue
+		}
+		if i > 0 && day.Equal(previous.AddDate(0, 0, 1)) {
+			streak++
+		} else if i == 0 || !day.Equal(previous) {
+			streak = 1
+		}
+		previous = day
+		if streak > longest {
+			longest = streak
+		}
+	}
+	return longest
+}
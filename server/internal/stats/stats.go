@@ -19,6 +19,9 @@ var (
 	REACTION_SUBMISSION_TOTAL StatsField = "REACTION_SUBMISSION_TOTAL"
 	REACTION_COMMENT_TOTAL    StatsField = "REACTION_COMMENT_TOTAL"
 	FRIEND_TOTAL              StatsField = "FRIEND_TOTAL"
+	PALETTE_PURIST_TOTAL      StatsField = "PALETTE_PURIST_TOTAL"
+	REFERRAL_TOTAL            StatsField = "REFERRAL_TOTAL"
+	CONTEST_WIN_TOTAL         StatsField = "CONTEST_WIN_TOTAL"
 )
 
 type StatsService struct {
@@ -36,6 +39,9 @@ type UserStats struct {
 	ReactionCommentTotal    *int
 	ReactionSubmissionTotal *int
 	FriendTotal             *int
+	PalettePuristTotal      *int
+	ReferralTotal           *int
+	ContestWinTotal         *int
 }
 
 func NewStatsService(db *sql.DB, ctx context.Context, userId string) *StatsService {
@@ -86,6 +92,18 @@ func getAllCalculatedStats(repo *sql.DB, ctx context.Context, userId string, sta
 		stats.FriendTotal = &reactionFriendTotal
 	}
 
+	if palettePuristTotal, ok := calculatedStats[string(PALETTE_PURIST_TOTAL)]; ok {
+		stats.PalettePuristTotal = &palettePuristTotal
+	}
+
+	if referralTotal, ok := calculatedStats[string(REFERRAL_TOTAL)]; ok {
+		stats.ReferralTotal = &referralTotal
+	}
+
+	if contestWinTotal, ok := calculatedStats[string(CONTEST_WIN_TOTAL)]; ok {
+		stats.ContestWinTotal = &contestWinTotal
+	}
+
 	return stats, nil
 }
 
@@ -141,6 +159,24 @@ func (ss *StatsService) CheckStatCompletion(userId string, achievement models.Ac
 			return 0, false, err
 		}
 		count = val
+	case string(PALETTE_PURIST_TOTAL):
+		val, err := ss.GetPalettePuristTotal(ss.DB, ss.Ctx, userId)
+		if err != nil {
+			return 0, false, err
+		}
+		count = val
+	case string(REFERRAL_TOTAL):
+		val, err := ss.GetReferralTotal(ss.DB, ss.Ctx, userId)
+		if err != nil {
+			return 0, false, err
+		}
+		count = val
+	case string(CONTEST_WIN_TOTAL):
+		val, err := ss.GetContestWinTotal(ss.DB, ss.Ctx, userId)
+		if err != nil {
+			return 0, false, err
+		}
+		count = val
 	default:
 		log.Printf("No applicable condition for achievement %v", achievement)
 	}
@@ -292,6 +328,60 @@ func (ss *StatsService) GetFriendTotal(repo *sql.DB, ctx context.Context, userId
 	return *friendCount, nil
 }
 
+func (ss *StatsService) GetPalettePuristTotal(repo *sql.DB, ctx context.Context, userId string) (int, error) {
+	puristCount := ss.Stats.PalettePuristTotal
+
+	// If stats is not already calculated, calculate it
+	if puristCount == nil {
+		count, err := queries.CalculatePalettePuristCount(repo, ctx, userId)
+
+		if err != nil {
+			log.Printf("Error calculating palette purist total for user %s: %v", userId, err)
+			return 0, err
+		}
+
+		puristCount = &count
+	}
+
+	return *puristCount, nil
+}
+
+func (ss *StatsService) GetReferralTotal(repo *sql.DB, ctx context.Context, userId string) (int, error) {
+	referralCount := ss.Stats.ReferralTotal
+
+	// If stats is not already calculated, calculate it
+	if referralCount == nil {
+		count, err := queries.CalculateReferralCount(repo, ctx, userId)
+
+		if err != nil {
+			log.Printf("Error calculating referral total for user %s: %v", userId, err)
+			return 0, err
+		}
+
+		referralCount = &count
+	}
+
+	return *referralCount, nil
+}
+
+func (ss *StatsService) GetContestWinTotal(repo *sql.DB, ctx context.Context, userId string) (int, error) {
+	winCount := ss.Stats.ContestWinTotal
+
+	// If stats is not already calculated, calculate it
+	if winCount == nil {
+		count, err := queries.CalculateContestWinCount(repo, ctx, userId)
+
+		if err != nil {
+			log.Printf("Error calculating contest win total for user %s: %v", userId, err)
+			return 0, err
+		}
+
+		winCount = &count
+	}
+
+	return *winCount, nil
+}
+
 // HERE
 func CalculateSubmissionActiveStreak(repo *sql.DB, ctx context.Context, userId string, passingCount int) (bool, error) {
 	activeStreak, err := queries.CalculateSubmissionActiveStreak(repo, ctx, userId)
@@ -428,3 +518,54 @@ func CalculateFriendTotal(repo *sql.DB, ctx context.Context, userId string, pass
 
 	return count >= passingCount, nil
 }
+
+func CalculatePalettePuristTotal(repo *sql.DB, ctx context.Context, userId string, passingCount int) (bool, error) {
+	count, err := queries.CalculatePalettePuristCount(repo, ctx, userId)
+
+	if err != nil {
+		return false, err
+	}
+
+	go func() {
+		err := queries.InsertCalculatedStat(repo, context.Background(), userId, string(PALETTE_PURIST_TOTAL), count)
+		if err != nil {
+			log.Printf("Failed to save palette purist total: %v", err)
+		}
+	}()
+
+	return count >= passingCount, nil
+}
+
+func CalculateReferralTotal(repo *sql.DB, ctx context.Context, userId string, passingCount int) (bool, error) {
+	count, err := queries.CalculateReferralCount(repo, ctx, userId)
+
+	if err != nil {
+		return false, err
+	}
+
+	go func() {
+		err := queries.InsertCalculatedStat(repo, context.Background(), userId, string(REFERRAL_TOTAL), count)
+		if err != nil {
+			log.Printf("Failed to save referral total: %v", err)
+		}
+	}()
+
+	return count >= passingCount, nil
+}
+
+func CalculateContestWinTotal(repo *sql.DB, ctx context.Context, userId string, passingCount int) (bool, error) {
+	count, err := queries.CalculateContestWinCount(repo, ctx, userId)
+
+	if err != nil {
+		return false, err
+	}
+
+	go func() {
+		err := queries.InsertCalculatedStat(repo, context.Background(), userId, string(CONTEST_WIN_TOTAL), count)
+		if err != nil {
+			log.Printf("Failed to save contest win total: %v", err)
+		}
+	}()
+
+	return count >= passingCount, nil
+}
@@ -4,14 +4,20 @@ import (
 	"bytes"
 	"context"
 	"drawer-service-backend/internal/config"
+	"drawer-service-backend/internal/strokes"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	s3Config "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
+var ErrNotFound = errors.New("object not found")
+
 type StorageService struct {
 	client     *s3.Client
 	bucketName string
@@ -54,17 +60,80 @@ func (s *StorageService) UploadSubmission(userId string, submissionId string, im
 	return s.uploadImage(filename, imageData)
 }
 
+func (s *StorageService) UploadSubmissionThumbnail(userId string, submissionId string, imageData []byte) (string, error) {
+	filename := getSubmissionThumbnailFilename(userId, submissionId)
+	return s.uploadImage(filename, imageData)
+}
+
 func (s *StorageService) UploadProfilePicture(userId string, imageData []byte) (string, error) {
 	filename := getProfileImageFilename(userId)
 	return s.uploadImage(filename, imageData)
 }
 
+func (s *StorageService) UploadTimelapse(userId string, key string, gifData []byte) (string, error) {
+	filename := getTimelapseFilename(userId, key)
+	return s.upload(filename, gifData, "image/gif")
+}
+
+func (s *StorageService) GetSubmission(userId string, submissionId string) ([]byte, error) {
+	return s.download(getSubmissionImageFilename(userId, submissionId))
+}
+
+func (s *StorageService) GetSubmissionThumbnail(userId string, submissionId string) ([]byte, error) {
+	return s.download(getSubmissionThumbnailFilename(userId, submissionId))
+}
+
+func (s *StorageService) UploadContactSheet(userId string, key string, imageData []byte) (string, error) {
+	filename := getContactSheetFilename(userId, key)
+	return s.uploadImage(filename, imageData)
+}
+
+func (s *StorageService) GetContactSheet(userId string, key string) ([]byte, error) {
+	return s.download(getContactSheetFilename(userId, key))
+}
+
+func (s *StorageService) GetTimelapse(userId string, key string) ([]byte, error) {
+	return s.download(getTimelapseFilename(userId, key))
+}
+
+func (s *StorageService) UploadReplayFrame(submissionId string, frame int, imageData []byte) (string, error) {
+	return s.uploadImage(getReplayFrameFilename(submissionId, frame), imageData)
+}
+
+func (s *StorageService) GetReplayFrame(submissionId string, frame int) ([]byte, error) {
+	return s.download(getReplayFrameFilename(submissionId, frame))
+}
+
+// DeleteSubmission removes a submission's image, thumbnail and cached replay
+// frames
+func (s *StorageService) DeleteSubmission(userId string, submissionId string) error {
+	filenames := []string{getSubmissionImageFilename(userId, submissionId), getSubmissionThumbnailFilename(userId, submissionId)}
+	for frame := 0; frame < strokes.ReplayFrames; frame++ {
+		filenames = append(filenames, getReplayFrameFilename(submissionId, frame))
+	}
+	for _, filename := range filenames {
+		_, err := s.client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+			Bucket: aws.String(s.bucketName),
+			Key:    aws.String(filename),
+		})
+		if err != nil {
+			log.Printf("Failed to delete %s from s3: %v", filename, err)
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *StorageService) uploadImage(filename string, imageData []byte) (string, error) {
+	return s.upload(filename, imageData, "image/png")
+}
+
+func (s *StorageService) upload(filename string, data []byte, contentType string) (string, error) {
 	_, err := s.client.PutObject(context.TODO(), &s3.PutObjectInput{
 		Bucket:      aws.String(s.bucketName),
 		Key:         aws.String(filename),
-		Body:        bytes.NewReader(imageData),
-		ContentType: aws.String("image/png"),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
 	})
 
 	if err != nil {
@@ -75,6 +144,25 @@ func (s *StorageService) uploadImage(filename string, imageData []byte) (string,
 	return s.getUploadURL(filename), nil
 }
 
+// download fetches an object, returning ErrNotFound when it doesn't exist
+func (s *StorageService) download(filename string) ([]byte, error) {
+	out, err := s.client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(filename),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrNotFound
+		}
+		log.Printf("Failed to download %s from s3: %v", filename, err)
+		return nil, err
+	}
+	defer out.Body.Close()
+
+	return io.ReadAll(out.Body)
+}
+
 func (s *StorageService) getUploadURL(imageName string) string {
 	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucketName, s.region, imageName)
 }
@@ -83,6 +171,22 @@ func getSubmissionImageFilename(userId string, submissionId string) string {
 	return fmt.Sprintf("%s/%s.png", userId, submissionId)
 }
 
+func getSubmissionThumbnailFilename(userId string, submissionId string) string {
+	return fmt.Sprintf("%s/%s-thumb.png", userId, submissionId)
+}
+
 func getProfileImageFilename(userId string) string {
 	return fmt.Sprintf("%s/profile-pic.png", userId)
 }
+
+func getTimelapseFilename(userId string, key string) string {
+	return fmt.Sprintf("%s/timelapse-%s.gif", userId, key)
+}
+
+func getReplayFrameFilename(submissionId string, frame int) string {
+	return fmt.Sprintf("replays/%s/%d.png", submissionId, frame)
+}
+
+func getContactSheetFilename(userId string, key string) string {
+	return fmt.Sprintf("%s/contact-sheet-%s.png", userId, key)
+}
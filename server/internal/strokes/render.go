@@ -0,0 +1,315 @@
+package strokes
+
+import (
+	"bytes"
+	"drawer-service-backend/internal/db/models"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Canonical canvas size, matches the frontend drawing canvas
+	CanvasWidth  = 370
+	CanvasHeight = 370
+
+	ThumbnailSize = 128
+	ReplayFrames  = 30
+)
+
+// Render rasterizes the full stroke log onto a transparent canvas of the
+// canonical size using the prompt palette.
+func Render(data models.StrokeData, palette []string) (*image.NRGBA, error) {
+	return RenderUntil(data, palette, math.MaxInt)
+}
+
+// RenderUntil rasterizes only the points drawn at or before elapsed
+// milliseconds, which is what a replay shows at that moment.
+func RenderUntil(data models.StrokeData, palette []string, elapsed int) (*image.NRGBA, error) {
+	colors, err := ParsePalette(palette)
+	if err != nil {
+		return nil, err
+	}
+	if data.Width <= 0 || data.Height <= 0 {
+		return nil, fmt.Errorf("invalid canvas size %dx%d", data.Width, data.Height)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, CanvasWidth, CanvasHeight))
+	scaleX := float64(CanvasWidth) / float64(data.Width)
+	scaleY := float64(CanvasHeight) / float64(data.Height)
+	scale := math.Min(scaleX, scaleY)
+
+	for _, stroke := range data.Strokes {
+		points := make([][2]float64, 0, len(stroke.Points))
+		for _, p := range stroke.Points {
+			if p[2] > elapsed {
+				break
+			}
+			points = append(points, [2]float64{float64(p[0]) * scaleX, float64(p[1]) * scaleY})
+		}
+		if len(points) == 0 {
+			continue
+		}
+
+		radius := float64(stroke.Size) * scale / 2
+		if stroke.Brush == BrushEraser {
+			drawStroke(img, points, radius, color.NRGBA{}, true)
+			continue
+		}
+		if stroke.ColorIndex < 0 || stroke.ColorIndex >= len(colors) {
+			return nil, fmt.Errorf("unknown color index %d", stroke.ColorIndex)
+		}
+		drawStroke(img, points, radius, colors[stroke.ColorIndex], false)
+	}
+
+	return img, nil
+}
+
+// RenderFrames renders count evenly spaced snapshots of the drawing, the last
+// one being the finished image.
+func RenderFrames(data models.StrokeData, palette []string, count int) ([]*image.NRGBA, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("invalid frame count %d", count)
+	}
+
+	frames := make([]*image.NRGBA, 0, count)
+	for i := 0; i < count; i++ {
+		frame, err := RenderFrame(data, palette, i, count)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+
+	return frames, nil
+}
+
+// RenderFrame renders a single replay snapshot out of count.
+func RenderFrame(data models.StrokeData, palette []string, index int, count int) (*image.NRGBA, error) {
+	if index < 0 || index >= count {
+		return nil, fmt.Errorf("frame %d out of range", index)
+	}
+	if index == count-1 {
+		return Render(data, palette)
+	}
+
+	duration := Duration(data)
+	elapsed := int(float64(duration) * float64(index+1) / float64(count))
+	return RenderUntil(data, palette, elapsed)
+}
+
+// Duration returns the timestamp of the last recorded point in milliseconds.
+func Duration(data models.StrokeData) int {
+	duration := 0
+	for _, stroke := range data.Strokes {
+		if len(stroke.Points) == 0 {
+			continue
+		}
+		if t := stroke.Points[len(stroke.Points)-1][2]; t > duration {
+			duration = t
+		}
+	}
+	return duration
+}
+
+// Thumbnail downscales an image to fit in a size x size square by averaging
+// the source pixels that fall into each destination pixel.
+func Thumbnail(src image.Image, size int) *image.NRGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dstW, dstH := size, size
+	if srcW > srcH {
+		dstH = max(1, size*srcH/srcW)
+	} else if srcH > srcW {
+		dstW = max(1, size*srcW/srcH)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := bounds.Min.Y + y*srcH/dstH
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcH/dstH)
+		for x := 0; x < dstW; x++ {
+			x0 := bounds.Min.X + x*srcW/dstW
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcW/dstW)
+
+			// Average in premultiplied space so transparent pixels don't darken edges
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
+
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ParsePalette converts "#RRGGBB" prompt colors into opaque colors.
+func ParsePalette(palette []string) ([]color.NRGBA, error) {
+	colors := make([]color.NRGBA, 0, len(palette))
+	for _, hex := range palette {
+		c, err := ParseHexColor(hex)
+		if err != nil {
+			return nil, err
+		}
+		colors = append(colors, c)
+	}
+	return colors, nil
+}
+
+func ParseHexColor(hex string) (color.NRGBA, error) {
+	if len(hex) != 7 || hex[0] != '#' {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q", hex)
+	}
+	value, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q", hex)
+	}
+	return color.NRGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 255}, nil
+}
+
+// RenderArea estimates the work of rendering the stroke log as the number of
+// pixels segmentCoverage visits on the canonical canvas, summed over every
+// segment.
+func RenderArea(data models.StrokeData) int {
+	if data.Width <= 0 || data.Height <= 0 {
+		return 0
+	}
+	scaleX := float64(CanvasWidth) / float64(data.Width)
+	scaleY := float64(CanvasHeight) / float64(data.Height)
+	scale := math.Min(scaleX, scaleY)
+
+	area := 0
+	for _, stroke := range data.Strokes {
+		pad := float64(stroke.Size)*scale + 2
+		for i := range stroke.Points {
+			// A single tap is a segment from the point to itself
+			if i == 0 && len(stroke.Points) > 1 {
+				continue
+			}
+			a, b := stroke.Points[max(i-1, 0)], stroke.Points[i]
+			w := math.Min(math.Abs(float64(b[0]-a[0]))*scaleX+pad, CanvasWidth)
+			h := math.Min(math.Abs(float64(b[1]-a[1]))*scaleY+pad, CanvasHeight)
+			area += int(w * h)
+		}
+	}
+	return area
+}
+
+// drawStroke paints a round-capped, round-joined polyline. Coverage is
+// accumulated for the whole stroke before compositing so overlapping segments
+// don't blend twice, matching how the browser strokes a single path.
+func drawStroke(img *image.NRGBA, points [][2]float64, radius float64, c color.NRGBA, erase bool) {
+	minX, minY := points[0][0], points[0][1]
+	maxX, maxY := minX, minY
+	for _, p := range points[1:] {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+
+	box := image.Rect(
+		int(math.Floor(minX-radius-1)), int(math.Floor(minY-radius-1)),
+		int(math.Ceil(maxX+radius+1)), int(math.Ceil(maxY+radius+1)),
+	).Intersect(img.Bounds())
+	if box.Empty() {
+		return
+	}
+
+	coverage := make([]float64, box.Dx()*box.Dy())
+	if len(points) == 1 {
+		// A single tap draws a dot
+		segmentCoverage(coverage, box, points[0], points[0], radius)
+	}
+	for i := 0; i+1 < len(points); i++ {
+		segmentCoverage(coverage, box, points[i], points[i+1], radius)
+	}
+
+	for y := box.Min.Y; y < box.Max.Y; y++ {
+		for x := box.Min.X; x < box.Max.X; x++ {
+			cov := coverage[(y-box.Min.Y)*box.Dx()+(x-box.Min.X)]
+			if cov <= 0 {
+				continue
+			}
+			dst := img.NRGBAAt(x, y)
+			if erase {
+				dst.A = uint8(float64(dst.A)*(1-cov) + 0.5)
+			} else {
+				dst = blend(dst, c, cov)
+			}
+			img.SetNRGBA(x, y, dst)
+		}
+	}
+}
+
+func segmentCoverage(coverage []float64, box image.Rectangle, a, b [2]float64, radius float64) {
+	segBox := image.Rect(
+		int(math.Floor(math.Min(a[0], b[0])-radius-1)), int(math.Floor(math.Min(a[1], b[1])-radius-1)),
+		int(math.Ceil(math.Max(a[0], b[0])+radius+1)), int(math.Ceil(math.Max(a[1], b[1])+radius+1)),
+	).Intersect(box)
+
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	lengthSq := dx*dx + dy*dy
+
+	for y := segBox.Min.Y; y < segBox.Max.Y; y++ {
+		for x := segBox.Min.X; x < segBox.Max.X; x++ {
+			// Distance from the pixel center to the closest point on the segment
+			px, py := float64(x)+0.5, float64(y)+0.5
+			t := 0.0
+			if lengthSq > 0 {
+				t = math.Max(0, math.Min(1, ((px-a[0])*dx+(py-a[1])*dy)/lengthSq))
+			}
+			cx, cy := a[0]+t*dx, a[1]+t*dy
+			dist := math.Hypot(px-cx, py-cy)
+
+			cov := math.Max(0, math.Min(1, radius+0.5-dist))
+			idx := (y-box.Min.Y)*box.Dx() + (x - box.Min.X)
+			if cov > coverage[idx] {
+				coverage[idx] = cov
+			}
+		}
+	}
+}
+
+// blend composites src over dst with the given coverage (source-over).
+func blend(dst, src color.NRGBA, cov float64) color.NRGBA {
+	srcA := float64(src.A) / 255 * cov
+	dstA := float64(dst.A) / 255
+	outA := srcA + dstA*(1-srcA)
+	if outA == 0 {
+		return color.NRGBA{}
+	}
+
+	mix := func(s, d uint8) uint8 {
+		return uint8((float64(s)*srcA+float64(d)*dstA*(1-srcA))/outA + 0.5)
+	}
+	return color.NRGBA{
+		R: mix(src.R, dst.R),
+		G: mix(src.G, dst.G),
+		B: mix(src.B, dst.B),
+		A: uint8(outA*255 + 0.5),
+	}
+}
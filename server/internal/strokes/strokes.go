@@ -0,0 +1,101 @@
+package strokes
+
+import (
+	"drawer-service-backend/internal/db/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	BrushPen    = "pen"
+	BrushEraser = "eraser"
+
+	// Limits keep a single stroke log small enough to store and replay. The
+	// client canvas is CanvasWidth wide, twice that leaves room for high
+	// density screens.
+	MaxCanvasSize = 2 * CanvasWidth
+	MaxBrushSize  = 60
+	MaxStrokes    = 2000
+	MaxPoints     = 50000
+	MaxDataBytes  = 1 << 20
+	// Pixels rendering a stroke log may visit, see RenderArea. Rendering
+	// happens inside the submit request, so this bounds how long it takes.
+	MaxRenderArea = 20_000_000
+)
+
+// Parse decodes a client-submitted stroke log and validates it against the
+// palette of the prompt it was drawn for.
+func Parse(raw string, palette []string) (models.StrokeData, error) {
+	if len(raw) > MaxDataBytes {
+		return models.StrokeData{}, fmt.Errorf("stroke data exceeds %d bytes", MaxDataBytes)
+	}
+
+	var data models.StrokeData
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return models.StrokeData{}, fmt.Errorf("malformed stroke data: %w", err)
+	}
+
+	if err := Validate(data, palette); err != nil {
+		return models.StrokeData{}, err
+	}
+
+	return data, nil
+}
+
+// Validate checks that every stroke fits on the canvas, uses a known brush and
+// references a color that exists in the palette.
+func Validate(data models.StrokeData, palette []string) error {
+	if data.Width <= 0 || data.Height <= 0 || data.Width > MaxCanvasSize || data.Height > MaxCanvasSize {
+		return fmt.Errorf("invalid canvas size %dx%d", data.Width, data.Height)
+	}
+	if len(data.Strokes) == 0 {
+		return errors.New("stroke data contains no strokes")
+	}
+	if len(data.Strokes) > MaxStrokes {
+		return fmt.Errorf("stroke data exceeds %d strokes", MaxStrokes)
+	}
+
+	totalPoints := 0
+	for i, stroke := range data.Strokes {
+		switch stroke.Brush {
+		case BrushPen:
+			if stroke.ColorIndex < 0 || stroke.ColorIndex >= len(palette) {
+				return fmt.Errorf("stroke %d uses unknown color %d", i, stroke.ColorIndex)
+			}
+		case BrushEraser:
+		default:
+			return fmt.Errorf("stroke %d uses unknown brush %q", i, stroke.Brush)
+		}
+
+		if stroke.Size <= 0 || stroke.Size > MaxBrushSize {
+			return fmt.Errorf("stroke %d has invalid size %d", i, stroke.Size)
+		}
+		if len(stroke.Points) == 0 {
+			return fmt.Errorf("stroke %d has no points", i)
+		}
+
+		totalPoints += len(stroke.Points)
+		if totalPoints > MaxPoints {
+			return fmt.Errorf("stroke data exceeds %d points", MaxPoints)
+		}
+
+		lastTime := 0
+		for _, point := range stroke.Points {
+			x, y, t := point[0], point[1], point[2]
+			if x < 0 || y < 0 || x > data.Width || y > data.Height {
+				return fmt.Errorf("stroke %d has a point outside the canvas", i)
+			}
+			if t < lastTime {
+				return fmt.Errorf("stroke %d has out of order timestamps", i)
+			}
+			lastTime = t
+		}
+	}
+
+	if area := RenderArea(data); area > MaxRenderArea {
+		return fmt.Errorf("stroke data is too costly to render (%d pixels, max %d)", area, MaxRenderArea)
+	}
+
+	return nil
+}
@@ -0,0 +1,406 @@
+package textfilter
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+type Mode string
+
+var (
+	// Skip screening entirely
+	MODE_OFF Mode = "off"
+	// Accept the text as written but flag it for admins
+	MODE_FLAG Mode = "flag"
+	// Accept the text with the offending words masked out
+	MODE_MASK Mode = "mask"
+	// Refuse the text
+	MODE_REJECT Mode = "reject"
+)
+
+const maskRune = '*'
+
+//go:embed words.txt
+var defaultWords string
+
+func IsValidMode(mode string) bool {
+	switch Mode(mode) {
+	case MODE_OFF, MODE_FLAG, MODE_MASK, MODE_REJECT:
+		return true
+	}
+	return false
+}
+
+// Match is an offending word, as byte offsets into the screened text
+type Match struct {
+	Start int
+	End   int
+	// List entry the word matched
+	Term string
+}
+
+// Screener finds offending words in user text. WordList is the built-in one,
+// anything else (an external moderation API, say) can be plugged into a Filter.
+type Screener interface {
+	Screen(text string) []Match
+}
+
+type Filter struct {
+	Mode     Mode
+	Screener Screener
+}
+
+func New(mode Mode, screener Screener) *Filter {
+	return &Filter{Mode: mode, Screener: screener}
+}
+
+// Result is the outcome of screening a piece of text
+type Result struct {
+	// What to store, masked in MODE_MASK
+	Text    string
+	Matches []Match
+	// The text has to be refused
+	Rejected bool
+	// The text was accepted but admins should look at it
+	Flagged bool
+}
+
+// Terms lists the distinct list entries the text matched
+func (r Result) Terms() []string {
+	seen := make(map[string]bool)
+	terms := []string{}
+	for _, match := range r.Matches {
+		if !seen[match.Term] {
+			seen[match.Term] = true
+			terms = append(terms, match.Term)
+		}
+	}
+	return terms
+}
+
+// Check screens text according to the filter's mode
+func (f *Filter) Check(text string) Result {
+	result := Result{Text: text}
+	if f == nil || f.Screener == nil || f.Mode == MODE_OFF {
+		return result
+	}
+
+	result.Matches = f.Screener.Screen(text)
+	if len(result.Matches) == 0 {
+		return result
+	}
+
+	switch f.Mode {
+	case MODE_REJECT:
+		result.Rejected = true
+	case MODE_MASK:
+		result.Text = Mask(text, result.Matches)
+	default:
+		result.Flagged = true
+	}
+	return result
+}
+
+// CheckName screens a name that can't sensibly be masked, like a username,
+// so MODE_MASK refuses it instead
+func (f *Filter) CheckName(text string) Result {
+	result := f.Check(text)
+	if f != nil && f.Mode == MODE_MASK && len(result.Matches) > 0 {
+		result.Text = text
+		result.Rejected = true
+	}
+	return result
+}
+
+// Mask replaces every rune of the matched words with *
+func Mask(text string, matches []Match) string {
+	masked := []rune{}
+	for i, r := range text {
+		for _, match := range matches {
+			if i >= match.Start && i < match.End {
+				r = maskRune
+				break
+			}
+		}
+		masked = append(masked, r)
+	}
+	return string(masked)
+}
+
+// WordList screens text against a list of words. Entries ending in * match
+// any word starting with the rest of the entry.
+type WordList struct {
+	words    map[string]string
+	prefixes []string
+	// Entries with repeated letters squashed, to catch stretched out words
+	// like "fuuuuck" without "as" matching "ass"
+	collapsedWords    map[string]string
+	collapsedPrefixes []string
+}
+
+// DefaultWordList is the list shipped with the server
+func DefaultWordList() *WordList {
+	list, _ := ParseWordList(strings.NewReader(defaultWords))
+	return list
+}
+
+// LoadWordList reads a word list file, falling back to the default list
+// when path is empty
+func LoadWordList(path string) (*WordList, error) {
+	if path == "" {
+		return DefaultWordList(), nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening word list: %w", err)
+	}
+	defer file.Close()
+
+	return ParseWordList(file)
+}
+
+// ParseWordList reads one entry per line, skipping blank lines and # comments.
+// Entries are normalized the same way screened text is, so "sh1t" and "shit"
+// are the same entry.
+func ParseWordList(r io.Reader) (*WordList, error) {
+	list := &WordList{words: make(map[string]string), collapsedWords: make(map[string]string)}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		prefix := strings.HasSuffix(entry, "*")
+		word := normalizeWord(strings.TrimSuffix(entry, "*"))
+		if word == "" {
+			continue
+		}
+		if prefix {
+			list.prefixes = append(list.prefixes, word)
+			list.collapsedPrefixes = append(list.collapsedPrefixes, collapseRepeats(word))
+		} else {
+			list.words[word] = word
+			list.collapsedWords[collapseRepeats(word)] = word
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading word list: %w", err)
+	}
+	return list, nil
+}
+
+func (w *WordList) Screen(text string) []Match {
+	matches := []Match{}
+	for _, word := range splitWords(text) {
+		if term, ok := w.match(word.text); ok {
+			matches = append(matches, Match{Start: word.start, End: word.end, Term: term})
+		}
+	}
+	return matches
+}
+
+func (w *WordList) match(word string) (string, bool) {
+	if term, ok := w.words[word]; ok {
+		return term, true
+	}
+	for _, prefix := range w.prefixes {
+		if strings.HasPrefix(word, prefix) {
+			return prefix + "*", true
+		}
+	}
+
+	if !isStretched(word) {
+		return "", false
+	}
+	collapsed := collapseRepeats(word)
+	if term, ok := w.collapsedWords[collapsed]; ok {
+		return term, true
+	}
+	for i, prefix := range w.collapsedPrefixes {
+		if strings.HasPrefix(collapsed, prefix) {
+			return w.prefixes[i] + "*", true
+		}
+	}
+	return "", false
+}
+
+// Lookalikes that normalization doesn't fold, mostly Cyrillic and Greek
+// letters that render the same as latin ones
+var confusables = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ї': 'i', 'ј': 'j',
+	'ѕ': 's', 'һ': 'h', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'ү': 'y', 'ս': 'u',
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w', 'ς': 's',
+	'ı': 'i', 'ł': 'l', 'ø': 'o', 'đ': 'd', 'ß': 's',
+}
+
+var leetDigits = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b', '9': 'g',
+}
+
+// Symbols only count as letters inside a word, so trailing punctuation and
+// @mentions aren't read as part of one
+var leetSymbols = map[rune]rune{
+	'@': 'a', '$': 's', '!': 'i', '|': 'l', '+': 't', '€': 'e',
+}
+
+// foldRune folds r to the plain lowercase letters it looks like, dropping
+// accents and compatibility forms such as fullwidth or mathematical letters.
+// Anything that isn't a letter comes back unchanged.
+func foldRune(r rune) string {
+	folded := []rune{}
+	for _, d := range norm.NFKD.String(string(r)) {
+		if unicode.Is(unicode.Mn, d) {
+			continue
+		}
+		d = unicode.ToLower(d)
+		if c, ok := confusables[d]; ok {
+			d = c
+		}
+		folded = append(folded, d)
+	}
+	return string(folded)
+}
+
+func isLetters(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+type word struct {
+	text  string
+	start int
+	end   int
+}
+
+// splitWords breaks text into normalized words, keeping the byte range each
+// one covers in the original text. Digits and symbols are read as leetspeak
+// when they're mixed in with letters, so "5h1t" and "$hit" are one word but
+// "2024" isn't.
+func splitWords(text string) []word {
+	type folded struct {
+		text   string
+		start  int
+		end    int
+		letter bool
+		leet   bool
+	}
+
+	runes := []folded{}
+	for i, r := range text {
+		f := folded{text: foldRune(r), start: i, end: i + utf8.RuneLen(r)}
+		if isLetters(f.text) {
+			f.letter = true
+		} else if l, ok := leetDigits[r]; ok {
+			f.text, f.leet = string(l), true
+		} else if l, ok := leetSymbols[r]; ok {
+			f.text, f.leet = string(l), true
+		}
+		runes = append(runes, f)
+	}
+
+	// Leet runes join a word only when a real letter comes after them, with
+	// only leet runes in between, and symbols also need something before them
+	inWord := make([]bool, len(runes))
+	letterAhead := false
+	for i := len(runes) - 1; i >= 0; i-- {
+		switch {
+		case runes[i].letter:
+			inWord[i], letterAhead = true, true
+		case runes[i].leet:
+			inWord[i] = letterAhead
+		default:
+			letterAhead = false
+		}
+	}
+	for i, r := range runes {
+		if r.leet && inWord[i] && r.text == "a" && text[r.start] == '@' && (i == 0 || !inWord[i-1]) {
+			inWord[i] = false
+		}
+	}
+
+	words := []word{}
+	var current *word
+	var builder strings.Builder
+	for i, r := range runes {
+		if !inWord[i] {
+			if current != nil {
+				current.text = builder.String()
+				words = append(words, *current)
+				current = nil
+			}
+			continue
+		}
+		if current == nil {
+			current = &word{start: r.start}
+			builder.Reset()
+		}
+		builder.WriteString(r.text)
+		current.end = r.end
+	}
+	if current != nil {
+		current.text = builder.String()
+		words = append(words, *current)
+	}
+	return words
+}
+
+// normalizeWord folds a whole word list entry
+func normalizeWord(entry string) string {
+	var builder strings.Builder
+	for _, w := range splitWords(entry) {
+		builder.WriteString(w.text)
+	}
+	return builder.String()
+}
+
+// isStretched reports whether the word repeats a letter three times or more,
+// which normal spelling doesn't
+func isStretched(word string) bool {
+	run := 0
+	var last rune = -1
+	for _, r := range word {
+		if r == last {
+			run++
+			if run >= 3 {
+				return true
+			}
+		} else {
+			run = 1
+		}
+		last = r
+	}
+	return false
+}
+
+// collapseRepeats squashes runs of the same letter, so "fuuuuck" reads "fuck"
+func collapseRepeats(word string) string {
+	var builder strings.Builder
+	var last rune = -1
+	for _, r := range word {
+		if r != last {
+			builder.WriteRune(r)
+		}
+		last = r
+	}
+	return builder.String()
+}
@@ -0,0 +1,162 @@
+package timelapse
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"drawer-service-backend/internal/config"
+	"drawer-service-backend/internal/db/models"
+	"drawer-service-backend/internal/imaging"
+	"drawer-service-backend/internal/palette"
+	"drawer-service-backend/internal/storage"
+	"drawer-service-backend/internal/strokes"
+	"drawer-service-backend/internal/utils"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	FrameSize     = strokes.CanvasWidth
+	CaptionHeight = 28
+	CaptionScale  = 2
+	// Hundredths of a second each drawing stays on screen
+	FrameDelay = 100
+	// The last drawing lingers before the loop restarts
+	FinalFrameDelay = 250
+)
+
+const missingDrawingText = "drawing unavailable"
+
+var (
+	captionColor   = color.NRGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff}
+	captionBgColor = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+)
+
+// jobs tracks generations in flight so repeated requests don't start
+// duplicate work. In development, finished GIFs are kept here too since
+// there's no bucket to cache them in.
+var jobs = struct {
+	sync.Mutex
+	pending map[string]bool
+	memory  map[string][]byte
+}{
+	pending: map[string]bool{},
+	memory:  map[string][]byte{},
+}
+
+// GetCached returns a previously generated timelapse, or nil if there isn't
+// one yet.
+func GetCached(cfg *config.Config, userID string, key string) ([]byte, error) {
+	if cfg.Env == "development" {
+		jobs.Lock()
+		defer jobs.Unlock()
+		return jobs.memory[userID+"/"+key], nil
+	}
+
+	data, err := storage.NewStorageService(cfg).GetTimelapse(userID, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Start generates and caches a timelapse in the background. It does nothing
+// if the same timelapse is already being generated.
+func Start(repo *sql.DB, cfg *config.Config, userID string, key string, submissions []models.UserPromptSubmission) {
+	id := userID + "/" + key
+
+	jobs.Lock()
+	if jobs.pending[id] {
+		jobs.Unlock()
+		return
+	}
+	jobs.pending[id] = true
+	jobs.Unlock()
+
+	go func() {
+		defer func() {
+			jobs.Lock()
+			delete(jobs.pending, id)
+			jobs.Unlock()
+		}()
+
+		started := time.Now()
+		data, err := Generate(repo, context.Background(), cfg, submissions)
+		if err != nil {
+			log.Printf("Error generating timelapse %s: %v", id, err)
+			return
+		}
+
+		if cfg.Env == "development" {
+			jobs.Lock()
+			jobs.memory[id] = data
+			jobs.Unlock()
+		} else if _, err := storage.NewStorageService(cfg).UploadTimelapse(userID, key, data); err != nil {
+			log.Printf("Error uploading timelapse %s: %v", id, err)
+			return
+		}
+
+		log.Printf("Generated timelapse %s with %d frames in %s", id, len(submissions), time.Since(started))
+	}()
+}
+
+// Generate renders one captioned frame per submission and encodes them as a
+// looping GIF.
+func Generate(repo *sql.DB, ctx context.Context, cfg *config.Config, submissions []models.UserPromptSubmission) ([]byte, error) {
+	if len(submissions) == 0 {
+		return nil, errors.New("no submissions to animate")
+	}
+
+	animation := &gif.GIF{}
+	for i, submission := range submissions {
+		frame := renderFrame(submission, imaging.LoadSubmission(repo, ctx, cfg, submission))
+
+		delay := FrameDelay
+		if i == len(submissions)-1 {
+			delay = FinalFrameDelay
+		}
+		animation.Image = append(animation.Image, imaging.Paletted(frame))
+		animation.Delay = append(animation.Delay, delay)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, animation); err != nil {
+		return nil, fmt.Errorf("error encoding timelapse: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func renderFrame(submission models.UserPromptSubmission, drawing image.Image) *image.NRGBA {
+	frame := image.NewNRGBA(image.Rect(0, 0, FrameSize, FrameSize+CaptionHeight))
+	background, _ := strokes.ParseHexColor(palette.DefaultBackground)
+	imaging.Fill(frame, background)
+
+	canvas := image.Rect(0, 0, FrameSize, FrameSize)
+	if drawing != nil {
+		imaging.DrawFitted(frame, canvas, drawing)
+	} else {
+		width := imaging.TextWidth(missingDrawingText, CaptionScale)
+		imaging.DrawText(frame, (FrameSize-width)/2, FrameSize/2, missingDrawingText, captionColor, CaptionScale)
+	}
+
+	imaging.FillRect(frame, image.Rect(0, FrameSize, FrameSize, FrameSize+CaptionHeight), captionBgColor)
+	text := imaging.TruncateText(Caption(submission), FrameSize-16, CaptionScale)
+	imaging.DrawText(frame, 8, FrameSize+(CaptionHeight-imaging.GlyphHeight*CaptionScale)/2, text, captionColor, CaptionScale)
+
+	return frame
+}
+
+// Caption is the line shown under each drawing, e.g. "Oct 3: A prehistoric city".
+func Caption(submission models.UserPromptSubmission) string {
+	day, err := time.Parse(utils.DateFormat, submission.Day)
+	if err != nil {
+		return submission.Prompt
+	}
+	return fmt.Sprintf("%s: %s", day.Format("Jan 2"), submission.Prompt)
+}
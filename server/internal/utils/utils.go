@@ -39,6 +39,16 @@ func GetImageUrl(cfg *config.Config, imageFilename string) string {
 	return "/example.png"
 }
 
+// GetInviteUrl is the link that redeems an invite code
+func GetInviteUrl(cfg *config.Config, code string) string {
+	return fmt.Sprintf("%s/draw/invite/%s", cfg.BaseURL, code)
+}
+
+// GetCollectionUrl is the public link to a shared favorites collection
+func GetCollectionUrl(cfg *config.Config, code string) string {
+	return fmt.Sprintf("%s/draw/collection/%s", cfg.BaseURL, code)
+}
+
 func GetSubmissionFilename(userId string, submissionId string) string {
 	return fmt.Sprintf("%s/%s.png", userId, submissionId)
 }
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS submission_strokes;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS submission_strokes (
+    submission_id TEXT PRIMARY KEY,
+    stroke_data TEXT NOT NULL, -- Store as JSON string containing the stroke log
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (submission_id) REFERENCES user_submissions(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE user_submissions DROP COLUMN palette_compliant;
+ALTER TABLE daily_prompts DROP COLUMN palette_mode;
@@ -0,0 +1,4 @@
+ALTER TABLE daily_prompts ADD COLUMN palette_mode TEXT NOT NULL DEFAULT 'flag' CHECK (palette_mode IN ('off', 'reward', 'flag', 'reject'));
+
+-- NULL when the submission image could not be analyzed
+ALTER TABLE user_submissions ADD COLUMN palette_compliant INTEGER;
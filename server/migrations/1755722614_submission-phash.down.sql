@@ -0,0 +1,3 @@
+ALTER TABLE user_submissions DROP COLUMN duplicate_distance;
+ALTER TABLE user_submissions DROP COLUMN duplicate_of;
+ALTER TABLE user_submissions DROP COLUMN phash;
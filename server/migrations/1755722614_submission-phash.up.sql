@@ -0,0 +1,6 @@
+-- Hex encoded perceptual hash of the submitted image, NULL when it could not be decoded
+ALTER TABLE user_submissions ADD COLUMN phash TEXT;
+
+-- Set when the submission looks like one of the user's recent drawings
+ALTER TABLE user_submissions ADD COLUMN duplicate_of TEXT REFERENCES user_submissions (id) ON DELETE SET NULL;
+ALTER TABLE user_submissions ADD COLUMN duplicate_distance INTEGER;
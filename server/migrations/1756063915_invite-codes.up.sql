@@ -0,0 +1,23 @@
+-- Shareable codes that befriend whoever redeems them with their creator
+CREATE TABLE IF NOT EXISTS invite_codes (
+    code TEXT PRIMARY KEY,
+    inviter_id TEXT NOT NULL,
+    expires_at TIMESTAMP, -- NULL never expires
+    max_uses INTEGER, -- NULL is unlimited
+    uses INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (inviter_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_invite_codes_inviter_id ON invite_codes (inviter_id);
+
+-- A user can only ever be referred once, by the first code they redeem
+CREATE TABLE IF NOT EXISTS referrals (
+    invitee_id TEXT PRIMARY KEY,
+    inviter_id TEXT NOT NULL,
+    code TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (invitee_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (inviter_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (code) REFERENCES invite_codes (code) ON DELETE SET NULL
+);
+CREATE INDEX idx_referrals_inviter_id ON referrals (inviter_id);
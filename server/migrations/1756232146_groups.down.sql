@@ -0,0 +1,4 @@
+ALTER TABLE user_submissions DROP COLUMN group_id;
+DROP TABLE IF EXISTS group_prompts;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS groups (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    invite_code TEXT NOT NULL UNIQUE,
+    created_by TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+    group_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('owner', 'member')),
+    joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, user_id),
+    FOREIGN KEY (group_id) REFERENCES groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_group_members_user_id ON group_members (user_id);
+
+-- Replaces the daily prompt for members who draw in the group's context
+CREATE TABLE IF NOT EXISTS group_prompts (
+    group_id TEXT NOT NULL,
+    day TEXT NOT NULL,
+    prompt TEXT NOT NULL,
+    colors TEXT NOT NULL,
+    palette_mode TEXT NOT NULL DEFAULT 'flag' CHECK (palette_mode IN ('off', 'reward', 'flag', 'reject')),
+    created_by TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, day),
+    FOREIGN KEY (group_id) REFERENCES groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+-- Set when the submission answered its group's prompt instead of the daily one
+ALTER TABLE user_submissions ADD COLUMN group_id TEXT REFERENCES groups (id) ON DELETE SET NULL;
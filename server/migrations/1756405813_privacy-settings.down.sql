@@ -0,0 +1,4 @@
+ALTER TABLE user_submissions DROP COLUMN visibility;
+ALTER TABLE users DROP COLUMN show_achievements;
+ALTER TABLE users DROP COLUMN show_streak;
+ALTER TABLE users DROP COLUMN profile_visibility;
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN profile_visibility TEXT NOT NULL DEFAULT 'everyone' CHECK (profile_visibility IN ('everyone', 'friends', 'nobody'));
+ALTER TABLE users ADD COLUMN show_streak INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN show_achievements INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE user_submissions ADD COLUMN visibility TEXT NOT NULL DEFAULT 'friends' CHECK (visibility IN ('private', 'friends', 'public'));
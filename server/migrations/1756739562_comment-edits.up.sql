@@ -0,0 +1,2 @@
+ALTER TABLE comments ADD COLUMN edited_at TIMESTAMP;
+ALTER TABLE comments ADD COLUMN deleted_at TIMESTAMP;
@@ -0,0 +1,21 @@
+-- Reactions users can pick from. Inactive ones can't be added anymore but
+-- stay on the content they were left on, and ones with a reward_id need
+-- that reward unlocked first.
+CREATE TABLE IF NOT EXISTS reaction_types (
+    id TEXT PRIMARY KEY,
+    emoji TEXT NOT NULL,
+    asset_url TEXT NOT NULL DEFAULT '',
+    applies_to TEXT NOT NULL DEFAULT 'both' CHECK (applies_to IN ('submission', 'comment', 'both')),
+    reward_id TEXT,
+    active INTEGER NOT NULL DEFAULT 1,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (reward_id) REFERENCES reward_unlocks (id) ON DELETE SET NULL
+);
+
+INSERT OR IGNORE INTO reaction_types (id, emoji, applies_to, sort_order) VALUES
+    ('heart', '❤️', 'both', 1),
+    ('cry-laugh', '😂', 'both', 2),
+    ('face-meh', '😐', 'both', 3),
+    ('fire', '🔥', 'both', 4),
+    ('thumbs-up', '👍', 'comment', 5);
@@ -0,0 +1,6 @@
+ALTER TABLE users DROP COLUMN suspended_until;
+ALTER TABLE users DROP COLUMN warning_count;
+ALTER TABLE prompt_suggestions DROP COLUMN hidden_at;
+ALTER TABLE comments DROP COLUMN hidden_at;
+ALTER TABLE user_submissions DROP COLUMN hidden_at;
+DROP TABLE IF EXISTS reports;
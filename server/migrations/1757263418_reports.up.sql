@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reporter_id TEXT NOT NULL,
+    content_type TEXT NOT NULL CHECK (content_type IN ('submission', 'comment', 'user', 'prompt_suggestion')),
+    content_id TEXT NOT NULL,
+    -- Who the reported content belongs to, warnings and suspensions go to them
+    content_owner_id TEXT,
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'inappropriate', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+    action TEXT CHECK (action IN ('dismiss', 'hide', 'delete', 'warn', 'suspend')),
+    resolved_by TEXT,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (reporter_id, content_type, content_id),
+    FOREIGN KEY (reporter_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (content_owner_id) REFERENCES users (id) ON DELETE SET NULL,
+    FOREIGN KEY (resolved_by) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX idx_reports_content ON reports (content_type, content_id);
+CREATE INDEX idx_reports_status ON reports (status);
+
+-- Hidden content is only shown to its author until a moderator looks at it
+ALTER TABLE user_submissions ADD COLUMN hidden_at TIMESTAMP;
+ALTER TABLE comments ADD COLUMN hidden_at TIMESTAMP;
+ALTER TABLE prompt_suggestions ADD COLUMN hidden_at TIMESTAMP;
+
+ALTER TABLE users ADD COLUMN warning_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN suspended_until TIMESTAMP;
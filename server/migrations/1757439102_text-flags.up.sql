@@ -0,0 +1,17 @@
+-- Text that matched the profanity filter while it's in flag mode, one row
+-- per piece of content so editing it again replaces the flag
+CREATE TABLE IF NOT EXISTS text_flags (
+    content_type TEXT NOT NULL CHECK (content_type IN ('comment', 'user', 'prompt_suggestion')),
+    content_id TEXT NOT NULL,
+    user_id TEXT,
+    text TEXT NOT NULL,
+    -- JSON array of the word list entries the text matched
+    terms TEXT NOT NULL,
+    reviewed_by TEXT,
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (content_type, content_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (reviewed_by) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX idx_text_flags_reviewed_at ON text_flags (reviewed_at);
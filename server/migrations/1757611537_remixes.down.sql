@@ -0,0 +1,31 @@
+PRAGMA foreign_keys=OFF;
+
+CREATE TABLE user_submissions_old (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    day TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    palette_compliant INTEGER,
+    phash TEXT,
+    duplicate_of TEXT REFERENCES user_submissions (id) ON DELETE SET NULL,
+    duplicate_distance INTEGER,
+    group_id TEXT REFERENCES groups (id) ON DELETE SET NULL,
+    visibility TEXT NOT NULL DEFAULT 'friends' CHECK (visibility IN ('private', 'friends', 'public')),
+    hidden_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(user_id, day)
+);
+
+-- Remixes can't be kept without the second slot per day
+INSERT INTO user_submissions_old (id, user_id, day, created_at, palette_compliant, phash, duplicate_of, duplicate_distance, group_id, visibility, hidden_at)
+SELECT id, user_id, day, created_at, palette_compliant, phash, duplicate_of, duplicate_distance, group_id, visibility, hidden_at
+FROM user_submissions
+WHERE kind = 'daily';
+
+DROP TABLE user_submissions;
+
+ALTER TABLE user_submissions_old RENAME TO user_submissions;
+
+CREATE INDEX idx_user_submissions_user_day ON user_submissions (user_id, day);
+
+PRAGMA foreign_keys=ON;
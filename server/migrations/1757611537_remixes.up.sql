@@ -0,0 +1,37 @@
+-- Remixes are drawings made in reply to another submission. They live next
+-- to daily drawings but don't take up the once-per-day slot, so the
+-- UNIQUE(user_id, day) constraint becomes a partial index over daily ones.
+PRAGMA foreign_keys=OFF;
+
+CREATE TABLE user_submissions_new (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    day TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    palette_compliant INTEGER,
+    phash TEXT,
+    duplicate_of TEXT REFERENCES user_submissions (id) ON DELETE SET NULL,
+    duplicate_distance INTEGER,
+    group_id TEXT REFERENCES groups (id) ON DELETE SET NULL,
+    visibility TEXT NOT NULL DEFAULT 'friends' CHECK (visibility IN ('private', 'friends', 'public')),
+    hidden_at TIMESTAMP,
+    kind TEXT NOT NULL DEFAULT 'daily' CHECK (kind IN ('daily', 'remix')),
+    -- Submission a remix answers. Cleared when that one is deleted, the remix
+    -- stays a remix.
+    remix_of TEXT REFERENCES user_submissions (id) ON DELETE SET NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO user_submissions_new (id, user_id, day, created_at, palette_compliant, phash, duplicate_of, duplicate_distance, group_id, visibility, hidden_at)
+SELECT id, user_id, day, created_at, palette_compliant, phash, duplicate_of, duplicate_distance, group_id, visibility, hidden_at
+FROM user_submissions;
+
+DROP TABLE user_submissions;
+
+ALTER TABLE user_submissions_new RENAME TO user_submissions;
+
+CREATE INDEX idx_user_submissions_user_day ON user_submissions (user_id, day);
+CREATE UNIQUE INDEX idx_user_submissions_daily ON user_submissions (user_id, day) WHERE kind = 'daily';
+CREATE INDEX idx_user_submissions_remix_of ON user_submissions (remix_of);
+
+PRAGMA foreign_keys=ON;
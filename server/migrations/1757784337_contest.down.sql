@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS contest_tallies;
+DROP TABLE IF EXISTS contest_wins;
+DROP TABLE IF EXISTS contest_votes;
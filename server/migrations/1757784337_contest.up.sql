@@ -0,0 +1,32 @@
+-- One vote per user per day for a friend's drawing of that day, casting
+-- another vote the same day moves it
+CREATE TABLE IF NOT EXISTS contest_votes (
+    voter_id TEXT NOT NULL,
+    day TEXT NOT NULL,
+    submission_id TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (voter_id, day),
+    FOREIGN KEY (voter_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (submission_id) REFERENCES user_submissions (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_contest_votes_submission_id ON contest_votes (submission_id);
+-- The most voted drawing of each friend circle per week, a circle being a
+-- user and their friends
+CREATE TABLE IF NOT EXISTS contest_wins (
+    week TEXT NOT NULL,
+    circle_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    submission_id TEXT NOT NULL,
+    votes INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (week, circle_id),
+    FOREIGN KEY (circle_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (submission_id) REFERENCES user_submissions (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_contest_wins_user_id ON contest_wins (user_id);
+-- Weeks whose votes have been tallied, so the job runs once per week
+CREATE TABLE IF NOT EXISTS contest_tallies (
+    week TEXT PRIMARY KEY,
+    tallied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,21 @@
+-- Named albums a user sorts their favorites into. A collection is shared
+-- publicly while it has a share code.
+CREATE TABLE IF NOT EXISTS favorite_collections (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    share_code TEXT UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_favorite_collections_user_id ON favorite_collections (user_id);
+CREATE TABLE IF NOT EXISTS favorite_collection_items (
+    collection_id TEXT NOT NULL,
+    favorite_id TEXT NOT NULL,
+    order_num INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (collection_id, favorite_id),
+    FOREIGN KEY (collection_id) REFERENCES favorite_collections (id) ON DELETE CASCADE,
+    FOREIGN KEY (favorite_id) REFERENCES user_favorite_submissions (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_favorite_collection_items_favorite_id ON favorite_collection_items (favorite_id);
@@ -0,0 +1,25 @@
+PRAGMA foreign_keys=OFF;
+
+CREATE TABLE text_flags_new (
+    content_type TEXT NOT NULL CHECK (content_type IN ('comment', 'user', 'prompt_suggestion')),
+    content_id TEXT NOT NULL,
+    user_id TEXT,
+    text TEXT NOT NULL,
+    terms TEXT NOT NULL,
+    reviewed_by TEXT,
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (content_type, content_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (reviewed_by) REFERENCES users (id) ON DELETE SET NULL
+);
+INSERT INTO text_flags_new SELECT content_type, content_id, user_id, text, terms, reviewed_by, reviewed_at, created_at FROM text_flags WHERE content_type != 'submission';
+DROP TABLE text_flags;
+ALTER TABLE text_flags_new RENAME TO text_flags;
+CREATE INDEX idx_text_flags_reviewed_at ON text_flags (reviewed_at);
+
+PRAGMA foreign_keys=ON;
+
+ALTER TABLE user_submissions DROP COLUMN alt_text;
+ALTER TABLE user_submissions DROP COLUMN caption;
+ALTER TABLE user_submissions DROP COLUMN title;
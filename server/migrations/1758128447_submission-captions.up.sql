@@ -0,0 +1,29 @@
+-- Optional text the artist adds to a drawing. Alt text describes the drawing
+-- for screen readers.
+ALTER TABLE user_submissions ADD COLUMN title TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_submissions ADD COLUMN caption TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_submissions ADD COLUMN alt_text TEXT NOT NULL DEFAULT '';
+
+-- Titles and captions go through the profanity filter too, so submissions
+-- can be flagged
+PRAGMA foreign_keys=OFF;
+
+CREATE TABLE text_flags_new (
+    content_type TEXT NOT NULL CHECK (content_type IN ('submission', 'comment', 'user', 'prompt_suggestion')),
+    content_id TEXT NOT NULL,
+    user_id TEXT,
+    text TEXT NOT NULL,
+    terms TEXT NOT NULL,
+    reviewed_by TEXT,
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (content_type, content_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (reviewed_by) REFERENCES users (id) ON DELETE SET NULL
+);
+INSERT INTO text_flags_new SELECT content_type, content_id, user_id, text, terms, reviewed_by, reviewed_at, created_at FROM text_flags;
+DROP TABLE text_flags;
+ALTER TABLE text_flags_new RENAME TO text_flags;
+CREATE INDEX idx_text_flags_reviewed_at ON text_flags (reviewed_at);
+
+PRAGMA foreign_keys=ON;
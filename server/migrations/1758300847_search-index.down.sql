@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS search_index_prompt_insert;
+DROP TRIGGER IF EXISTS search_index_prompt_update;
+DROP TRIGGER IF EXISTS search_index_prompt_delete;
+DROP TRIGGER IF EXISTS search_index_submission_insert;
+DROP TRIGGER IF EXISTS search_index_submission_update;
+DROP TRIGGER IF EXISTS search_index_submission_delete;
+DROP TRIGGER IF EXISTS search_index_comment_insert;
+DROP TRIGGER IF EXISTS search_index_comment_update;
+DROP TRIGGER IF EXISTS search_index_comment_delete;
+DROP TABLE IF EXISTS search_index;
+DROP TABLE IF EXISTS search_index_rows;
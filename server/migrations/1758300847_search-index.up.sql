@@ -0,0 +1,71 @@
+-- Full-text index over daily prompts, submission titles, captions and alt
+-- text, and comments. Each row of search_index has the rowid of its row in
+-- search_index_rows, which says what the text belongs to: content_id is the
+-- prompt's day, the submission's id or the comment's id. Triggers keep both
+-- in step with the indexed tables, finding index rows by rowid.
+CREATE TABLE search_index_rows (
+    id INTEGER PRIMARY KEY,
+    content_type TEXT NOT NULL,
+    content_id TEXT NOT NULL,
+    UNIQUE (content_type, content_id)
+);
+CREATE VIRTUAL TABLE search_index USING fts5 (
+    body,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO search_index_rows (content_type, content_id)
+SELECT 'prompt', day FROM daily_prompts;
+INSERT INTO search_index_rows (content_type, content_id)
+SELECT 'submission', id FROM user_submissions;
+INSERT INTO search_index_rows (content_type, content_id)
+SELECT 'comment', CAST(id AS TEXT) FROM comments;
+
+INSERT INTO search_index (rowid, body)
+SELECT r.id, dp.prompt FROM search_index_rows r
+JOIN daily_prompts dp ON r.content_type = 'prompt' AND dp.day = r.content_id;
+INSERT INTO search_index (rowid, body)
+SELECT r.id, us.title || char(10) || us.caption || char(10) || us.alt_text FROM search_index_rows r
+JOIN user_submissions us ON r.content_type = 'submission' AND us.id = r.content_id;
+INSERT INTO search_index (rowid, body)
+SELECT r.id, c.text FROM search_index_rows r
+JOIN comments c ON r.content_type = 'comment' AND CAST(c.id AS TEXT) = r.content_id;
+
+CREATE TRIGGER search_index_prompt_insert AFTER INSERT ON daily_prompts BEGIN
+    INSERT INTO search_index_rows (content_type, content_id) VALUES ('prompt', new.day);
+    INSERT INTO search_index (rowid, body) VALUES (last_insert_rowid(), new.prompt);
+END;
+CREATE TRIGGER search_index_prompt_update AFTER UPDATE OF prompt ON daily_prompts BEGIN
+    UPDATE search_index SET body = new.prompt
+    WHERE rowid = (SELECT id FROM search_index_rows WHERE content_type = 'prompt' AND content_id = old.day);
+END;
+CREATE TRIGGER search_index_prompt_delete AFTER DELETE ON daily_prompts BEGIN
+    DELETE FROM search_index WHERE rowid = (SELECT id FROM search_index_rows WHERE content_type = 'prompt' AND content_id = old.day);
+    DELETE FROM search_index_rows WHERE content_type = 'prompt' AND content_id = old.day;
+END;
+
+CREATE TRIGGER search_index_submission_insert AFTER INSERT ON user_submissions BEGIN
+    INSERT INTO search_index_rows (content_type, content_id) VALUES ('submission', new.id);
+    INSERT INTO search_index (rowid, body) VALUES (last_insert_rowid(), new.title || char(10) || new.caption || char(10) || new.alt_text);
+END;
+CREATE TRIGGER search_index_submission_update AFTER UPDATE OF title, caption, alt_text ON user_submissions BEGIN
+    UPDATE search_index SET body = new.title || char(10) || new.caption || char(10) || new.alt_text
+    WHERE rowid = (SELECT id FROM search_index_rows WHERE content_type = 'submission' AND content_id = old.id);
+END;
+CREATE TRIGGER search_index_submission_delete AFTER DELETE ON user_submissions BEGIN
+    DELETE FROM search_index WHERE rowid = (SELECT id FROM search_index_rows WHERE content_type = 'submission' AND content_id = old.id);
+    DELETE FROM search_index_rows WHERE content_type = 'submission' AND content_id = old.id;
+END;
+
+CREATE TRIGGER search_index_comment_insert AFTER INSERT ON comments BEGIN
+    INSERT INTO search_index_rows (content_type, content_id) VALUES ('comment', CAST(new.id AS TEXT));
+    INSERT INTO search_index (rowid, body) VALUES (last_insert_rowid(), new.text);
+END;
+CREATE TRIGGER search_index_comment_update AFTER UPDATE OF text ON comments BEGIN
+    UPDATE search_index SET body = new.text
+    WHERE rowid = (SELECT id FROM search_index_rows WHERE content_type = 'comment' AND content_id = CAST(old.id AS TEXT));
+END;
+CREATE TRIGGER search_index_comment_delete AFTER DELETE ON comments BEGIN
+    DELETE FROM search_index WHERE rowid = (SELECT id FROM search_index_rows WHERE content_type = 'comment' AND content_id = CAST(old.id AS TEXT));
+    DELETE FROM search_index_rows WHERE content_type = 'comment' AND content_id = CAST(old.id AS TEXT);
+END;
@@ -8,14 +8,13 @@ CREATE TABLE users (
     username TEXT UNIQUE NOT NULL,
     email TEXT UNIQUE NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-, role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin')), avatar_type VARCHAR (255) NOT NULL DEFAULT 'default', avatar_url VARCHAR (255) NOT NULL DEFAULT '');
+, role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin')), avatar_type VARCHAR (255) NOT NULL DEFAULT 'default', avatar_url VARCHAR (255) NOT NULL DEFAULT '', profile_visibility TEXT NOT NULL DEFAULT 'everyone' CHECK (profile_visibility IN ('everyone', 'friends', 'nobody')), show_streak INTEGER NOT NULL DEFAULT 1, show_achievements INTEGER NOT NULL DEFAULT 1, warning_count INTEGER NOT NULL DEFAULT 0, suspended_until TIMESTAMP);
 CREATE TABLE user_submissions (
     id TEXT PRIMARY KEY,
     user_id TEXT NOT NULL,
     day TEXT NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-    UNIQUE(user_id, day)
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, palette_compliant INTEGER, phash TEXT, duplicate_of TEXT REFERENCES user_submissions (id) ON DELETE SET NULL, duplicate_distance INTEGER, group_id TEXT REFERENCES groups (id) ON DELETE SET NULL, visibility TEXT NOT NULL DEFAULT 'friends' CHECK (visibility IN ('private', 'friends', 'public')), hidden_at TIMESTAMP, kind TEXT NOT NULL DEFAULT 'daily' CHECK (kind IN ('daily', 'remix')), remix_of TEXT REFERENCES user_submissions (id) ON DELETE SET NULL, title TEXT NOT NULL DEFAULT '', caption TEXT NOT NULL DEFAULT '', alt_text TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 CREATE TABLE verification_tokens (
     token TEXT PRIMARY KEY,
@@ -26,6 +25,8 @@ CREATE TABLE verification_tokens (
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 CREATE INDEX idx_user_submissions_user_day ON user_submissions (user_id, day);
+CREATE UNIQUE INDEX idx_user_submissions_daily ON user_submissions (user_id, day) WHERE kind = 'daily';
+CREATE INDEX idx_user_submissions_remix_of ON user_submissions (remix_of);
 CREATE INDEX idx_users_email ON users (email);
 CREATE INDEX idx_verification_tokens_user_id ON verification_tokens(user_id);
 CREATE INDEX idx_verification_tokens_email ON verification_tokens(email);
@@ -35,7 +36,7 @@ CREATE TABLE comments (
     submission_id TEXT NOT NULL,
     user_id TEXT NOT NULL,
     text TEXT NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, parent_id INTEGER REFERENCES comments (id) ON DELETE CASCADE, edited_at TIMESTAMP, deleted_at TIMESTAMP, hidden_at TIMESTAMP,
     FOREIGN KEY (submission_id) REFERENCES user_submissions(id) ON DELETE CASCADE,
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 );
@@ -88,6 +89,228 @@ CREATE INDEX idx_user_stat_calculations_user_id ON user_stat_calculations (user_
 CREATE INDEX idx_user_stat_calculations_stat_type ON user_stat_calculations (stat_type);
 CREATE INDEX idx_user_stat_updates_user_id ON user_stat_calculations (user_id);
 CREATE INDEX idx_user_stat_updates_stat_type ON user_stat_calculations (stat_type);
-CREATE TABLE "daily_prompts" (day TEXT PRIMARY KEY, colors TEXT NOT NULL, prompt TEXT NOT NULL, created_by TEXT, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE CASCADE);
+CREATE TABLE "daily_prompts" (day TEXT PRIMARY KEY, colors TEXT NOT NULL, prompt TEXT NOT NULL, created_by TEXT, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, palette_mode TEXT NOT NULL DEFAULT 'flag' CHECK (palette_mode IN ('off', 'reward', 'flag', 'reject')), FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE CASCADE);
 CREATE INDEX idx_daily_prompts_created_by ON daily_prompts (created_by);
-CREATE TABLE prompt_suggestions (id STRING PRIMARY KEY, prompt TEXT NOT NULL, user_id TEXT, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE);
+CREATE TABLE prompt_suggestions (id STRING PRIMARY KEY, prompt TEXT NOT NULL, user_id TEXT, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, hidden_at TIMESTAMP, FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE);
+CREATE TABLE submission_strokes (
+    submission_id TEXT PRIMARY KEY,
+    stroke_data TEXT NOT NULL, -- Store as JSON string containing the stroke log
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (submission_id) REFERENCES user_submissions(id) ON DELETE CASCADE
+);
+CREATE TABLE user_blocks (
+    blocker_id TEXT NOT NULL,
+    blocked_id TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (blocker_id, blocked_id),
+    FOREIGN KEY (blocker_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (blocked_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_user_blocks_blocked_id ON user_blocks (blocked_id);
+CREATE TABLE user_mutes (
+    muter_id TEXT NOT NULL,
+    muted_id TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (muter_id, muted_id),
+    FOREIGN KEY (muter_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (muted_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE TABLE invite_codes (
+    code TEXT PRIMARY KEY,
+    inviter_id TEXT NOT NULL,
+    expires_at TIMESTAMP, -- NULL never expires
+    max_uses INTEGER, -- NULL is unlimited
+    uses INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (inviter_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_invite_codes_inviter_id ON invite_codes (inviter_id);
+CREATE TABLE referrals (
+    invitee_id TEXT PRIMARY KEY,
+    inviter_id TEXT NOT NULL,
+    code TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (invitee_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (inviter_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (code) REFERENCES invite_codes (code) ON DELETE SET NULL
+);
+CREATE INDEX idx_referrals_inviter_id ON referrals (inviter_id);
+CREATE TABLE groups (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    invite_code TEXT NOT NULL UNIQUE,
+    created_by TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE TABLE group_members (
+    group_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('owner', 'member')),
+    joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, user_id),
+    FOREIGN KEY (group_id) REFERENCES groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_group_members_user_id ON group_members (user_id);
+CREATE TABLE group_prompts (
+    group_id TEXT NOT NULL,
+    day TEXT NOT NULL,
+    prompt TEXT NOT NULL,
+    colors TEXT NOT NULL,
+    palette_mode TEXT NOT NULL DEFAULT 'flag' CHECK (palette_mode IN ('off', 'reward', 'flag', 'reject')),
+    created_by TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, day),
+    FOREIGN KEY (group_id) REFERENCES groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX idx_comments_parent_id ON comments (parent_id);
+CREATE TABLE comment_mentions (
+    comment_id INTEGER NOT NULL,
+    user_id TEXT NOT NULL,
+    start INTEGER NOT NULL,
+    length INTEGER NOT NULL,
+    PRIMARY KEY (comment_id, start),
+    FOREIGN KEY (comment_id) REFERENCES comments (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_comment_mentions_user_id ON comment_mentions (user_id);
+CREATE TABLE reaction_types (
+    id TEXT PRIMARY KEY,
+    emoji TEXT NOT NULL,
+    asset_url TEXT NOT NULL DEFAULT '',
+    applies_to TEXT NOT NULL DEFAULT 'both' CHECK (applies_to IN ('submission', 'comment', 'both')),
+    reward_id TEXT,
+    active INTEGER NOT NULL DEFAULT 1,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (reward_id) REFERENCES reward_unlocks (id) ON DELETE SET NULL
+);
+CREATE TABLE reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reporter_id TEXT NOT NULL,
+    content_type TEXT NOT NULL CHECK (content_type IN ('submission', 'comment', 'user', 'prompt_suggestion')),
+    content_id TEXT NOT NULL,
+    content_owner_id TEXT,
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'inappropriate', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+    action TEXT CHECK (action IN ('dismiss', 'hide', 'delete', 'warn', 'suspend')),
+    resolved_by TEXT,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (reporter_id, content_type, content_id),
+    FOREIGN KEY (reporter_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (content_owner_id) REFERENCES users (id) ON DELETE SET NULL,
+    FOREIGN KEY (resolved_by) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX idx_reports_content ON reports (content_type, content_id);
+CREATE INDEX idx_reports_status ON reports (status);
+CREATE TABLE text_flags (
+    content_type TEXT NOT NULL CHECK (content_type IN ('submission', 'comment', 'user', 'prompt_suggestion')),
+    content_id TEXT NOT NULL,
+    user_id TEXT,
+    text TEXT NOT NULL,
+    terms TEXT NOT NULL,
+    reviewed_by TEXT,
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (content_type, content_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (reviewed_by) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX idx_text_flags_reviewed_at ON text_flags (reviewed_at);
+CREATE TABLE contest_votes (
+    voter_id TEXT NOT NULL,
+    day TEXT NOT NULL,
+    submission_id TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (voter_id, day),
+    FOREIGN KEY (voter_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (submission_id) REFERENCES user_submissions (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_contest_votes_submission_id ON contest_votes (submission_id);
+CREATE TABLE contest_wins (
+    week TEXT NOT NULL,
+    circle_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    submission_id TEXT NOT NULL,
+    votes INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (week, circle_id),
+    FOREIGN KEY (circle_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (submission_id) REFERENCES user_submissions (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_contest_wins_user_id ON contest_wins (user_id);
+CREATE TABLE contest_tallies (
+    week TEXT PRIMARY KEY,
+    tallied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE favorite_collections (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    share_code TEXT UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_favorite_collections_user_id ON favorite_collections (user_id);
+CREATE TABLE favorite_collection_items (
+    collection_id TEXT NOT NULL,
+    favorite_id TEXT NOT NULL,
+    order_num INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (collection_id, favorite_id),
+    FOREIGN KEY (collection_id) REFERENCES favorite_collections (id) ON DELETE CASCADE,
+    FOREIGN KEY (favorite_id) REFERENCES user_favorite_submissions (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_favorite_collection_items_favorite_id ON favorite_collection_items (favorite_id);
+CREATE TABLE search_index_rows (
+    id INTEGER PRIMARY KEY,
+    content_type TEXT NOT NULL,
+    content_id TEXT NOT NULL,
+    UNIQUE (content_type, content_id)
+);
+CREATE VIRTUAL TABLE search_index USING fts5 (
+    body,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+CREATE TRIGGER search_index_prompt_insert AFTER INSERT ON daily_prompts BEGIN
+    INSERT INTO search_index_rows (content_type, content_id) VALUES ('prompt', new.day);
+    INSERT INTO search_index (rowid, body) VALUES (last_insert_rowid(), new.prompt);
+END;
+CREATE TRIGGER search_index_prompt_update AFTER UPDATE OF prompt ON daily_prompts BEGIN
+    UPDATE search_index SET body = new.prompt
+    WHERE rowid = (SELECT id FROM search_index_rows WHERE content_type = 'prompt' AND content_id = old.day);
+END;
+CREATE TRIGGER search_index_prompt_delete AFTER DELETE ON daily_prompts BEGIN
+    DELETE FROM search_index WHERE rowid = (SELECT id FROM search_index_rows WHERE content_type = 'prompt' AND content_id = old.day);
+    DELETE FROM search_index_rows WHERE content_type = 'prompt' AND content_id = old.day;
+END;
+CREATE TRIGGER search_index_submission_insert AFTER INSERT ON user_submissions BEGIN
+    INSERT INTO search_index_rows (content_type, content_id) VALUES ('submission', new.id);
+    INSERT INTO search_index (rowid, body) VALUES (last_insert_rowid(), new.title || char(10) || new.caption || char(10) || new.alt_text);
+END;
+CREATE TRIGGER search_index_submission_update AFTER UPDATE OF title, caption, alt_text ON user_submissions BEGIN
+    UPDATE search_index SET body = new.title || char(10) || new.caption || char(10) || new.alt_text
+    WHERE rowid = (SELECT id FROM search_index_rows WHERE content_type = 'submission' AND content_id = old.id);
+END;
+CREATE TRIGGER search_index_submission_delete AFTER DELETE ON user_submissions BEGIN
+    DELETE FROM search_index WHERE rowid = (SELECT id FROM search_index_rows WHERE content_type = 'submission' AND content_id = old.id);
+    DELETE FROM search_index_rows WHERE content_type = 'submission' AND content_id = old.id;
+END;
+CREATE TRIGGER search_index_comment_insert AFTER INSERT ON comments BEGIN
+    INSERT INTO search_index_rows (content_type, content_id) VALUES ('comment', CAST(new.id AS TEXT));
+    INSERT INTO search_index (rowid, body) VALUES (last_insert_rowid(), new.text);
+END;
+CREATE TRIGGER search_index_comment_update AFTER UPDATE OF text ON comments BEGIN
+    UPDATE search_index SET body = new.text
+    WHERE rowid = (SELECT id FROM search_index_rows WHERE content_type = 'comment' AND content_id = CAST(old.id AS TEXT));
+END;
+CREATE TRIGGER search_index_comment_delete AFTER DELETE ON comments BEGIN
+    DELETE FROM search_index WHERE rowid = (SELECT id FROM search_index_rows WHERE content_type = 'comment' AND content_id = CAST(old.id AS TEXT));
+    DELETE FROM search_index_rows WHERE content_type = 'comment' AND content_id = CAST(old.id AS TEXT);
+END;
@@ -16,6 +16,7 @@ DROP TABLE IF EXISTS user_achievements;
 DROP TABLE IF EXISTS user_achievement_checks;
 DROP TABLE IF EXISTS user_stat_calculations;
 DROP TABLE IF EXISTS prompt_suggestions;
+DROP TABLE IF EXISTS submission_strokes;
 
 -- CREATE TABLE users (
 --     id TEXT PRIMARY KEY,
@@ -61,3 +62,21 @@ DROP TABLE IF EXISTS prompt_suggestions;
 -- CREATE INDEX idx_verification_tokens_user_id ON verification_tokens(user_id);
 -- CREATE INDEX idx_verification_tokens_email ON verification_tokens(email);
 -- CREATE TABLE schema_migrations (id VARCHAR(255) NOT NULL PRIMARY KEY);
+DROP TABLE IF EXISTS user_blocks;
+DROP TABLE IF EXISTS user_mutes;
+DROP TABLE IF EXISTS invite_codes;
+DROP TABLE IF EXISTS referrals;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS group_prompts;
+DROP TABLE IF EXISTS comment_mentions;
+DROP TABLE IF EXISTS reaction_types;
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS text_flags;
+DROP TABLE IF EXISTS contest_votes;
+DROP TABLE IF EXISTS contest_wins;
+DROP TABLE IF EXISTS contest_tallies;
+DROP TABLE IF EXISTS favorite_collection_items;
+DROP TABLE IF EXISTS favorite_collections;
+DROP TABLE IF EXISTS search_index;
+DROP TABLE IF EXISTS search_index_rows;